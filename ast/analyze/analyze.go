@@ -0,0 +1,154 @@
+// Package analyze finds rules, and parts of rules, which can never match or always match, and explains why.
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/sat"
+)
+
+// Kinds of Finding.
+const (
+	NeverMatches  = "never_matches"
+	AlwaysMatches = "always_matches"
+)
+
+// Finding reports a clause of a rule which can never match or always matches. The clause is the whole rule when the
+// rule itself is affected.
+type Finding struct {
+	Clause  string `json:"clause"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Analyze returns the clauses of the rule which never match or always match, outermost first. Clauses within a
+// reported clause are not reported. All the clauses share one limit of sat.DefaultLimit branches, and clauses which
+// cannot be decided within what is left of it are skipped.
+func Analyze(ast parse.AST) ([]Finding, error) {
+	var findings []Finding
+	err := analyze(sat.NewSolver(sat.DefaultLimit), ast, &findings)
+	return findings, err
+}
+
+func analyze(solver *sat.Solver, ast parse.AST, findings *[]Finding) error {
+	finding, err := check(solver, ast)
+	switch {
+	case err != nil && !errors.Is(err, sat.ErrLimit):
+		return err
+	case finding != nil:
+		*findings = append(*findings, *finding)
+		return nil
+	}
+
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			if err := analyze(solver, operand, findings); err != nil {
+				return err
+			}
+		}
+	case *bools.UnaryExpr:
+		return analyze(solver, ast.Expr, findings)
+	case *bools.AtLeastExpr, *bools.ExactlyExpr, *bools.PriorityExpr:
+		for _, child := range bools.Children(ast) {
+			if err := analyze(solver, child, findings); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check returns a finding if the clause never matches or always matches.
+func check(solver *sat.Solver, ast parse.AST) (*Finding, error) {
+	_, ok, err := solver.Solve(ast)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &Finding{Clause: fmt.Sprint(ast), Kind: NeverMatches, Message: explain(solver, ast, false)}, nil
+	}
+	_, valid, err := solver.Valid(ast)
+	if err != nil {
+		return nil, err
+	}
+	if valid {
+		return &Finding{Clause: fmt.Sprint(ast), Kind: AlwaysMatches, Message: explain(solver, ast, true)}, nil
+	}
+	return nil, nil
+}
+
+// explain describes why a clause never matches or, if always is set, always matches.
+func explain(solver *sat.Solver, ast parse.AST, always bool) string {
+	if _, ok := ast.(parse.Unparsed); ok {
+		return fmt.Sprintf("'%v' is not a comparison and always matches", ast)
+	}
+
+	if pred, ok := comp.AsPredicate(ast); ok && !always {
+		if _, isNum := pred.Number(); !isNum {
+			return fmt.Sprintf("never matches, since '%s' compares numbers and '%s' is not a number", pred.Op, pred.Value)
+		}
+		return fmt.Sprintf("never matches, since no number is %s %s", pred.Op, pred.Value)
+	}
+
+	bin, ok := ast.(*bools.BinExpr)
+	if !ok {
+		if always {
+			return "always matches"
+		}
+		return "never matches"
+	}
+
+	// a conjunction never matches when some of its clauses cannot hold together, and a disjunction always matches
+	// when some of its clauses cover every record
+	if (bin.Op == bools.OpAnd) == always {
+		if always {
+			return "always matches, since every clause always matches"
+		}
+		return "never matches, since no clause can match"
+	}
+	core := minimalCore(solver, bin.Op, bools.Flatten(bin, bin.Op), always)
+	if len(core) == 1 {
+		if always {
+			return fmt.Sprintf("always matches, since (%v) always matches", core[0])
+		}
+		return fmt.Sprintf("never matches, since (%v) never matches", core[0])
+	}
+	var clauses []string
+	for _, clause := range core {
+		clauses = append(clauses, fmt.Sprintf("(%v)", clause))
+	}
+	if always {
+		return fmt.Sprintf("always matches, since %s cover every record", strings.Join(clauses, " or "))
+	}
+	return fmt.Sprintf("never matches, since %s cannot all hold", strings.Join(clauses, " and "))
+}
+
+// minimalCore returns a minimal subset of the operands of a chain which still never matches or, if always is set,
+// always matches, by dropping operands one at a time.
+func minimalCore(solver *sat.Solver, op bools.Op, operands []parse.AST, always bool) []parse.AST {
+	core := append([]parse.AST{}, operands...)
+	for idx := 0; idx < len(core); {
+		candidate := append(append([]parse.AST{}, core[:idx]...), core[idx+1:]...)
+		if len(candidate) > 0 && decided(solver, bools.Chain(op, candidate...), always) {
+			core = candidate
+		} else {
+			idx++
+		}
+	}
+	return core
+}
+
+func decided(solver *sat.Solver, ast parse.AST, always bool) bool {
+	if always {
+		_, valid, err := solver.Valid(ast)
+		return err == nil && valid
+	}
+	_, ok, err := solver.Solve(ast)
+	return err == nil && !ok
+}
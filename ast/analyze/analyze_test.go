@@ -0,0 +1,114 @@
+package analyze
+
+import (
+	"fmt"
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+
+	testCases := []struct {
+		desc             string
+		ruleString       string
+		expectedFindings []Finding
+	}{
+		{
+			desc:       "rule without findings",
+			ruleString: "age > 30 AND (department == 'Sales' OR salary > 20000)",
+		},
+		{
+			desc:       "empty interval",
+			ruleString: "age > 40 AND department == 'Sales' AND age < 30",
+			expectedFindings: []Finding{{
+				Clause:  "age > 40 AND department == 'Sales' AND age < 30",
+				Kind:    NeverMatches,
+				Message: "never matches, since (age > 40) and (age < 30) cannot all hold",
+			}},
+		},
+		{
+			desc:       "conflicting equalities in a sub-clause",
+			ruleString: "age > 30 OR (dept == 'A' AND dept == 'B')",
+			expectedFindings: []Finding{{
+				Clause:  "dept == 'A' AND dept == 'B'",
+				Kind:    NeverMatches,
+				Message: "never matches, since (dept == 'A') and (dept == 'B') cannot all hold",
+			}},
+		},
+		{
+			desc:       "excluded middle",
+			ruleString: "dept == 'A' OR age > 1 OR NOT (dept == 'A')",
+			expectedFindings: []Finding{{
+				Clause:  "dept == 'A' OR age > 1 OR NOT (dept == 'A')",
+				Kind:    AlwaysMatches,
+				Message: "always matches, since (dept == 'A') or (NOT (dept == 'A')) cover every record",
+			}},
+		},
+		{
+			desc:       "comparison against a non-number",
+			ruleString: "age > 'thirty' OR dept == 'A'",
+			expectedFindings: []Finding{{
+				Clause:  "age > 'thirty'",
+				Kind:    NeverMatches,
+				Message: "never matches, since '>' compares numbers and 'thirty' is not a number",
+			}},
+		},
+		{
+			desc:       "comparison no number satisfies",
+			ruleString: "age > inf OR dept == 'A'",
+			expectedFindings: []Finding{{
+				Clause:  "age > inf",
+				Kind:    NeverMatches,
+				Message: "never matches, since no number is > inf",
+			}},
+		},
+		{
+			desc:       "bare term",
+			ruleString: "active AND age > 1",
+			expectedFindings: []Finding{{
+				Clause:  "active",
+				Kind:    AlwaysMatches,
+				Message: "'active' is not a comparison and always matches",
+			}},
+		},
+		{
+			desc:       "bounds do not cover missing attributes",
+			ruleString: "age > 30 OR age <= 30",
+		},
+		{
+			desc:       "negated contradiction",
+			ruleString: "NOT (age > 40 AND age < 30)",
+			expectedFindings: []Finding{{
+				Clause:  "NOT (age > 40 AND age < 30)",
+				Kind:    AlwaysMatches,
+				Message: "always matches",
+			}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			findings, err := Analyze(grammar.MustParse(tt.ruleString))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedFindings, findings)
+		})
+	}
+}
+
+func TestAnalyzeManyDisjunctions(t *testing.T) {
+
+	rule := "x > 5 AND x < 3"
+	for i := 0; i < 16; i++ {
+		rule += fmt.Sprintf(" AND (a%d > 1 OR b%d > 1)", i, i)
+	}
+	findings, err := Analyze(grammar.MustParse(rule))
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{{
+		Clause:  rule,
+		Kind:    NeverMatches,
+		Message: "never matches, since (x > 5) and (x < 3) cannot all hold",
+	}}, findings)
+}
@@ -0,0 +1,289 @@
+// Package codegen generates standalone JavaScript and Python functions from rule ASTs, so that rules can be evaluated
+// offline with the same semantics as the Go evaluator.
+//
+// The generated functions take a single object or dict mapping attribute names to string values. As in the Go
+// evaluator, a comparison on a missing attribute is false, equality compares strings exactly and ordinal comparisons
+// parse both sides as 32-bit floats, failing when the value is not a number. Hexadecimal floats, which Go also
+// accepts, are treated as non-numeric by the generated code.
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrUnsupported is returned for rule constructs which cannot be generated.
+var ErrUnsupported = errors.New("unsupported construct")
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// numberPattern matches the decimal numbers accepted by strconv.ParseFloat, except for the special values.
+const numberPattern = `^[+-]?([0-9](_?[0-9])*(\.([0-9](_?[0-9])*)?)?|\.[0-9](_?[0-9])*)([eE][+-]?[0-9](_?[0-9])*)?$`
+
+// language holds the syntax needed to render an expression in one target language. count and ifElse are format
+// strings: count takes a comma-separated list of expressions and gives the number which are true, and ifElse takes a
+// condition and the expressions to use when it holds and when it does not.
+type language struct {
+	and, or, not string
+	true, false  string
+	inf          string
+	count        string
+	ifElse       string
+}
+
+var (
+	javaScript = language{
+		and: " && ", or: " || ", not: "!", true: "true", false: "false", inf: "Infinity",
+		count: "[%s].filter(Boolean).length", ifElse: "(%[1]s ? %[2]s : %[3]s)",
+	}
+	python = language{
+		and: " and ", or: " or ", not: "not ", true: "True", false: "False", inf: "math.inf",
+		count: "sum([%s])", ifElse: "(%[2]s if %[1]s else %[3]s)",
+	}
+)
+
+const javaScriptTemplate = `// Code generated by RuleEngineAST. DO NOT EDIT.
+// Rule: %[1]s
+function %[2]s(data) {
+  "use strict";
+  function has(k) {
+    return data !== null && data !== undefined && Object.prototype.hasOwnProperty.call(data, k);
+  }
+  function num(k) {
+    var s = String(data[k]);
+    if (/^[+-]?(inf|infinity)$/i.test(s)) {
+      return s[0] === "-" ? -Infinity : Infinity;
+    }
+    if (/^nan$/i.test(s)) {
+      return NaN;
+    }
+    if (!/%[3]s/.test(s)) {
+      return null;
+    }
+    var f = Math.fround(Number(s.replace(/_/g, "")));
+    return isFinite(f) ? f : null;
+  }
+  function eq(k, v) { return has(k) && String(data[k]) === v; }
+  function ne(k, v) { return has(k) && String(data[k]) !== v; }
+  function gt(k, v) { var f = has(k) ? num(k) : null; return f !== null && f > v; }
+  function ge(k, v) { var f = has(k) ? num(k) : null; return f !== null && f >= v; }
+  function lt(k, v) { var f = has(k) ? num(k) : null; return f !== null && f < v; }
+  function le(k, v) { var f = has(k) ? num(k) : null; return f !== null && f <= v; }
+  return %[4]s;
+}
+`
+
+const pythonTemplate = `# Code generated by RuleEngineAST. DO NOT EDIT.
+# Rule: %[1]s
+def %[2]s(data):
+    import math
+    import re
+    import struct
+
+    def has(k):
+        return data is not None and k in data
+
+    def num(k):
+        s = str(data[k])
+        t = s.lower()
+        if t in ("inf", "+inf", "-inf", "infinity", "+infinity", "-infinity"):
+            return -math.inf if t.startswith("-") else math.inf
+        if t == "nan":
+            return math.nan
+        if not re.fullmatch(r"%[3]s", s):
+            return None
+        try:
+            f = struct.unpack("f", struct.pack("f", float(s.replace("_", ""))))[0]
+        except OverflowError:
+            return None
+        return None if math.isinf(f) else f
+
+    def eq(k, v):
+        return has(k) and str(data[k]) == v
+
+    def ne(k, v):
+        return has(k) and str(data[k]) != v
+
+    def ordinal(k, test):
+        f = num(k) if has(k) else None
+        return f is not None and test(f)
+
+    def gt(k, v):
+        return ordinal(k, lambda f: f > v)
+
+    def ge(k, v):
+        return ordinal(k, lambda f: f >= v)
+
+    def lt(k, v):
+        return ordinal(k, lambda f: f < v)
+
+    def le(k, v):
+        return ordinal(k, lambda f: f <= v)
+
+    return %[4]s
+`
+
+var helpers = map[comp.Op]string{
+	comp.OpEqual:          "eq",
+	comp.OpNotEqual:       "ne",
+	comp.OpGreater:        "gt",
+	comp.OpGreaterOrEqual: "ge",
+	comp.OpLess:           "lt",
+	comp.OpLessOrEqual:    "le",
+}
+
+// JavaScript returns the source of a self-contained JavaScript function with the provided name, which returns whether
+// the rule matches its data argument.
+func JavaScript(ast parse.AST, name string) (string, error) {
+	return generate(javaScript, javaScriptTemplate, ast, name)
+}
+
+// Python returns the source of a self-contained Python function with the provided name, which returns whether the
+// rule matches its data argument.
+func Python(ast parse.AST, name string) (string, error) {
+	return generate(python, pythonTemplate, ast, name)
+}
+
+func generate(lang language, template string, ast parse.AST, name string) (string, error) {
+	if !identifier.MatchString(name) {
+		return "", fmt.Errorf("%w: function name '%s'", ErrUnsupported, name)
+	}
+	expr, err := lang.expr(ast)
+	if err != nil {
+		return "", err
+	}
+	// the rule is only used in a comment, so it must stay on one line
+	rule := strings.Join(strings.Fields(fmt.Sprint(ast)), " ")
+	return fmt.Sprintf(template, rule, name, numberPattern, expr), nil
+}
+
+func (l language) expr(ast parse.AST) (string, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		var op string
+		switch ast.Op {
+		case bools.OpAnd:
+			op = l.and
+		case bools.OpOr:
+			op = l.or
+		default:
+			return "", fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		var operands []string
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			expr, err := l.expr(operand)
+			if err != nil {
+				return "", err
+			}
+			operands = append(operands, expr)
+		}
+		return "(" + strings.Join(operands, op) + ")", nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return "", fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		expr, err := l.expr(ast.Expr)
+		if err != nil {
+			return "", err
+		}
+		return "(" + l.not + expr + ")", nil
+	case *bools.AtLeastExpr:
+		count, err := l.countTrue(ast.Children)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s >= %d)", count, ast.K), nil
+	case *bools.ExactlyExpr:
+		count, err := l.countTrue(ast.Children)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s == %d)", count, ast.K), nil
+	case *bools.PriorityExpr:
+		return l.priority(ast.Children)
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return "", fmt.Errorf("%w: comparison '%v' must have an attribute on the left and a literal on the right", ErrUnsupported, ast)
+		}
+		return l.predicate(pred), nil
+	case parse.Unparsed:
+		// the Go evaluator matches bare terms unconditionally
+		return l.true, nil
+	}
+	return "", fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func (l language) countTrue(children []parse.AST) (string, error) {
+	var exprs []string
+	for _, child := range children {
+		expr, err := l.expr(child)
+		if err != nil {
+			return "", err
+		}
+		exprs = append(exprs, expr)
+	}
+	return fmt.Sprintf(l.count, strings.Join(exprs, ", ")), nil
+}
+
+// priority renders the children of a PRIORITY rule as nested conditionals, each testing that the data has every
+// attribute compared by a child before using it.
+func (l language) priority(children []parse.AST) (string, error) {
+	if len(children) == 0 {
+		return l.false, nil
+	}
+	expr, err := l.expr(children[0])
+	if err != nil {
+		return "", err
+	}
+	attrs := comp.Attributes(children[0])
+	if len(attrs) == 0 {
+		return expr, nil
+	}
+	var tests []string
+	for _, attr := range attrs {
+		tests = append(tests, fmt.Sprintf("has(%s)", quote(attr)))
+	}
+	rest, err := l.priority(children[1:])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(l.ifElse, strings.Join(tests, l.and), expr, rest), nil
+}
+
+func (l language) predicate(pred comp.Predicate) string {
+	var value string
+	switch pred.Op {
+	case comp.OpEqual, comp.OpNotEqual:
+		value = quote(pred.Value)
+	default:
+		// literals are converted once here, and comparisons against non-numbers never match
+		n, ok := pred.Number()
+		switch {
+		case !ok || math.IsNaN(float64(n)):
+			return l.false
+		case math.IsInf(float64(n), 1):
+			value = l.inf
+		case math.IsInf(float64(n), -1):
+			value = "-" + l.inf
+		default:
+			value = strconv.FormatFloat(float64(n), 'g', -1, 64)
+		}
+	}
+	return fmt.Sprintf("%s(%s, %s)", helpers[pred.Op], quote(pred.Attr), value)
+}
+
+// quote returns a string literal valid in both JavaScript and Python.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,122 @@
+package codegen
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rules and records form the corpus on which generated code must agree with rules compiled by ast/compile.
+var rules = []string{
+	"age > 30",
+	"age >= 30.5 AND age <= 40",
+	"age < 18 OR age > 65",
+	"department == 'Marketing'",
+	"department != 'Sales'",
+	"department == 'Human Resources' OR department == 'Sales'",
+	"NOT (department == 'Sales')",
+	"NOT (age > 30 AND salary >= 20000)",
+	"((age > 30 AND department == 'Marketing')) AND (salary > 20000 OR experience > 5)",
+	"age > 'thirty'",
+	"age > inf OR age < -Infinity",
+	"score > 30.0000001",
+	"active AND age > 1",
+	"ATLEAST(2, age > 30, department == 'Marketing', salary > 20000)",
+	"MAJORITY(age > 30, department == 'Sales', experience > 5) AND NOT (salary > 50000)",
+	"XOR(department == 'Sales', age < 18, age > 65)",
+	"EXACTLY(2, age > 30, department == 'Sales') OR ATLEAST(2, age > 1, salary > 1)",
+	"PRIORITY(department == 'Sales' AND salary > 20000, experience > 5, age > 30)",
+	"PRIORITY(score > 30, active, age > 30)",
+}
+
+var records = []map[string]string{
+	{},
+	{"age": "31", "department": "Marketing", "salary": "51000", "experience": "6"},
+	{"age": "30", "department": "Sales", "salary": "20000", "experience": "5"},
+	{"age": "17", "department": "Human Resources"},
+	{"age": "66", "department": "sales"},
+	{"age": "30.5", "salary": "1e5"},
+	{"age": "+31", "department": ""},
+	{"age": ".5e2", "department": "Marketing", "salary": "20_001", "experience": "5."},
+	{"age": "thirty", "salary": "NaN"},
+	{"age": " 31", "salary": "Inf", "experience": "-infinity"},
+	{"age": "1e39", "salary": "3.4028235e38"},
+	{"age": "-Inf", "score": "30.0000001"},
+	{"age": "1_0", "score": "30.000002"},
+	{"age": "1e-50", "score": "30"},
+	{"age": "40", "department": "Marketing", "salary": "19999.99", "experience": "6"},
+}
+
+func TestGeneratedCodeMatchesCompile(t *testing.T) {
+
+	runners := []struct {
+		desc     string
+		binary   string
+		generate func(parse.AST, string) (string, error)
+		name     string
+		driver   string
+	}{
+		{
+			desc:     "javascript",
+			binary:   "node",
+			generate: JavaScript,
+			name:     "ruleMatch",
+			driver:   "\nconst records = JSON.parse(require('fs').readFileSync(0, 'utf8'));\nprocess.stdout.write(JSON.stringify(records.map(ruleMatch)));\n",
+		},
+		{
+			desc:     "python",
+			binary:   "python3",
+			generate: Python,
+			name:     "rule_match",
+			driver:   "\nimport json, sys\nprint(json.dumps([rule_match(r) for r in json.load(sys.stdin)]))\n",
+		},
+	}
+
+	input, err := json.Marshal(records)
+	assert.Nil(t, err)
+
+	for _, runner := range runners {
+		t.Run(runner.desc, func(t *testing.T) {
+			binary, err := exec.LookPath(runner.binary)
+			if err != nil {
+				t.Skipf("%s is not installed", runner.binary)
+			}
+
+			for _, rule := range rules {
+				ast := grammar.MustParse(rule)
+				match, err := compile.Compile(ast)
+				assert.Nil(t, err)
+
+				code, err := runner.generate(ast, runner.name)
+				assert.Nil(t, err)
+
+				script := filepath.Join(t.TempDir(), "rule")
+				assert.Nil(t, os.WriteFile(script, []byte(code+runner.driver), 0644))
+
+				cmd := exec.Command(binary, script)
+				cmd.Stdin = strings.NewReader(string(input))
+				output, err := cmd.CombinedOutput()
+				assert.Nil(t, err, string(output))
+
+				var results []bool
+				assert.Nil(t, json.Unmarshal(output, &results), string(output))
+
+				for idx, record := range records {
+					expected := match(record)
+					if assert.Less(t, idx, len(results)) {
+						assert.Equal(t, expected, results[idx], "rule %q, record %v", rule, record)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package compile turns parsed rule ASTs into trees of pre-bound Go closures, so that evaluating a rule against a
+// record does no parsing, no type switches and no allocation.
+package compile
+
+import (
+	"fmt"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// Rule reports whether a record matches a compiled rule. Rules hold no mutable state and are safe for concurrent use.
+type Rule func(data map[string]string) bool
+
+func always(map[string]string) bool { return true }
+
+func never(map[string]string) bool { return false }
+
+// Compile compiles a parsed rule. The compiled rule gives the same results as the rule engine's tree-walking
+// evaluator: comparisons on missing attributes are false, equality compares strings exactly, ordinal comparisons
+// parse both sides as 32-bit floats and bare terms always match. Literals are converted and attribute names resolved
+// once, here, rather than on every evaluation.
+func Compile(ast parse.AST) (Rule, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		lhs, err := Compile(ast.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := Compile(ast.RHS)
+		if err != nil {
+			return nil, err
+		}
+		switch ast.Op {
+		case bools.OpAnd:
+			return func(data map[string]string) bool { return lhs(data) && rhs(data) }, nil
+		case bools.OpOr:
+			return func(data map[string]string) bool { return lhs(data) || rhs(data) }, nil
+		}
+		return never, nil
+
+	case *bools.UnaryExpr:
+		expr, err := Compile(ast.Expr)
+		if err != nil {
+			return nil, err
+		}
+		if ast.Op != bools.OpNot {
+			return never, nil
+		}
+		return func(data map[string]string) bool { return !expr(data) }, nil
+
+	case *bools.AtLeastExpr:
+		children, err := compileAll(ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		k := ast.K
+		return func(data map[string]string) bool {
+			// stop as soon as the count is reached, or can no longer be
+			count := 0
+			for idx, child := range children {
+				if count >= k || count+len(children)-idx < k {
+					break
+				}
+				if child(data) {
+					count++
+				}
+			}
+			return count >= k
+		}, nil
+
+	case *bools.ExactlyExpr:
+		children, err := compileAll(ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		k := ast.K
+		return func(data map[string]string) bool {
+			count := 0
+			for _, child := range children {
+				if child(data) {
+					count++
+					if count > k {
+						return false
+					}
+				}
+			}
+			return count == k
+		}, nil
+
+	case *bools.PriorityExpr:
+		children, err := compileAll(ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		attrs := make([][]string, len(ast.Children))
+		for idx, child := range ast.Children {
+			attrs[idx] = comp.Attributes(child)
+		}
+		return func(data map[string]string) bool {
+			for idx, child := range children {
+				if hasAll(data, attrs[idx]) {
+					return child(data)
+				}
+			}
+			return false
+		}, nil
+
+	case *comp.EqualExpr:
+		key, literal := operand(ast.LHS), operand(ast.RHS)
+		switch ast.Op {
+		case comp.OpEqual:
+			return func(data map[string]string) bool {
+				val, ok := data[key]
+				return ok && val == literal
+			}, nil
+		case comp.OpNotEqual:
+			return func(data map[string]string) bool {
+				val, ok := data[key]
+				return ok && val != literal
+			}, nil
+		}
+		return never, nil
+
+	case *comp.OrdinalExpr:
+		key := operand(ast.LHS)
+		literal, ok := comp.Number(operand(ast.RHS))
+		if !ok {
+			return never, nil
+		}
+		var test func(float32) bool
+		switch ast.Op {
+		case comp.OpGreater:
+			test = func(val float32) bool { return val > literal }
+		case comp.OpGreaterOrEqual:
+			test = func(val float32) bool { return val >= literal }
+		case comp.OpLess:
+			test = func(val float32) bool { return val < literal }
+		case comp.OpLessOrEqual:
+			test = func(val float32) bool { return val <= literal }
+		default:
+			return never, nil
+		}
+		return func(data map[string]string) bool {
+			raw, ok := data[key]
+			if !ok {
+				return false
+			}
+			val, ok := comp.Number(raw)
+			return ok && test(val)
+		}, nil
+
+	case parse.Unparsed:
+		return always, nil
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func compileAll(asts []parse.AST) ([]Rule, error) {
+	var result []Rule
+	for _, ast := range asts {
+		rule, err := Compile(ast)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+// hasAll reports whether the record has every one of the provided attributes.
+func hasAll(data map[string]string, attrs []string) bool {
+	for _, attr := range attrs {
+		if _, ok := data[attr]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// operand returns the attribute name or literal held by one side of a comparison. Like the tree-walking evaluator,
+// anything other than an unparsed node resolves to the empty string.
+func operand(ast parse.AST) string {
+	unparsed, ok := ast.(parse.Unparsed)
+	if !ok {
+		return ""
+	}
+	return strings.ReplaceAll(strings.Join(unparsed.Contents, " "), "'", "")
+}
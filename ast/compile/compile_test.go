@@ -0,0 +1,87 @@
+package compile
+
+import (
+	"errors"
+	"testing"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		rule          string
+		data          map[string]string
+		expectedMatch bool
+	}{
+		{desc: "equal", rule: "department == 'Marketing'", data: map[string]string{"department": "Marketing"}, expectedMatch: true},
+		{desc: "equal compares strings exactly", rule: "age == 30", data: map[string]string{"age": "30.0"}, expectedMatch: false},
+		{desc: "equal on missing attribute", rule: "department == ''", data: map[string]string{}, expectedMatch: false},
+		{desc: "not equal", rule: "department != 'Sales'", data: map[string]string{"department": "Marketing"}, expectedMatch: true},
+		{desc: "not equal on same value", rule: "department != 'Sales'", data: map[string]string{"department": "Sales"}, expectedMatch: false},
+		{desc: "not equal on missing attribute", rule: "department != 'Sales'", data: map[string]string{}, expectedMatch: false},
+		{desc: "quoted multi-word literal", rule: "department == 'Human Resources'", data: map[string]string{"department": "Human Resources"}, expectedMatch: true},
+		{desc: "greater", rule: "age > 30", data: map[string]string{"age": "31"}, expectedMatch: true},
+		{desc: "greater at bound", rule: "age > 30", data: map[string]string{"age": "30"}, expectedMatch: false},
+		{desc: "greater or equal", rule: "age >= 30", data: map[string]string{"age": "30"}, expectedMatch: true},
+		{desc: "less", rule: "age < 30", data: map[string]string{"age": "29.5"}, expectedMatch: true},
+		{desc: "less or equal", rule: "age <= 30", data: map[string]string{"age": "30.5"}, expectedMatch: false},
+		{desc: "ordinal compares numbers", rule: "salary > 9", data: map[string]string{"salary": "1e5"}, expectedMatch: true},
+		{desc: "ordinal compares 32-bit floats", rule: "score > 30", data: map[string]string{"score": "30.0000001"}, expectedMatch: false},
+		{desc: "ordinal on missing attribute", rule: "age <= 30", data: map[string]string{}, expectedMatch: false},
+		{desc: "ordinal on non-numeric value", rule: "age < 30", data: map[string]string{"age": "young"}, expectedMatch: false},
+		{desc: "ordinal against non-numeric literal", rule: "age > 'thirty'", data: map[string]string{"age": "thirty"}, expectedMatch: false},
+		{desc: "negated ordinal against non-numeric literal", rule: "NOT (age > 'thirty')", data: map[string]string{"age": "31"}, expectedMatch: true},
+		{desc: "bare term", rule: "active", data: map[string]string{}, expectedMatch: true},
+		{desc: "and", rule: "age > 30 AND department == 'Sales'", data: map[string]string{"age": "31", "department": "Sales"}, expectedMatch: true},
+		{desc: "and with one side false", rule: "age > 30 AND department == 'Sales'", data: map[string]string{"age": "31", "department": "HR"}, expectedMatch: false},
+		{desc: "or", rule: "age > 30 OR department == 'Sales'", data: map[string]string{"department": "Sales"}, expectedMatch: true},
+		{desc: "or with both sides false", rule: "age > 30 OR department == 'Sales'", data: map[string]string{"age": "20"}, expectedMatch: false},
+		{desc: "not", rule: "NOT (department == 'Sales')", data: map[string]string{"department": "HR"}, expectedMatch: true},
+		{desc: "not on missing attribute", rule: "NOT (age > 30)", data: map[string]string{}, expectedMatch: true},
+		{
+			desc:          "nested",
+			rule:          "((age > 30 AND department == 'Marketing')) AND (salary > 20000 OR experience > 5)",
+			data:          map[string]string{"age": "31", "department": "Marketing", "experience": "6"},
+			expectedMatch: true,
+		},
+		{desc: "at least reached", rule: "ATLEAST(2, a == 1, b == 1, c == 1)", data: map[string]string{"a": "1", "c": "1"}, expectedMatch: true},
+		{desc: "at least missed", rule: "ATLEAST(2, a == 1, b == 1, c == 1)", data: map[string]string{"b": "1"}, expectedMatch: false},
+		{desc: "at least of all", rule: "ATLEAST(3, a == 1, b == 1, c == 1)", data: map[string]string{"a": "1", "b": "1", "c": "1"}, expectedMatch: true},
+		{desc: "majority", rule: "MAJORITY(a == 1, b == 1, c == 1)", data: map[string]string{"a": "1", "b": "1"}, expectedMatch: true},
+		{desc: "majority missed", rule: "MAJORITY(a == 1, b == 1, c == 1, d == 1)", data: map[string]string{"a": "1", "b": "1"}, expectedMatch: false},
+		{desc: "exactly", rule: "EXACTLY(2, a == 1, b == 1, c == 1)", data: map[string]string{"a": "1", "c": "1"}, expectedMatch: true},
+		{desc: "exactly exceeded", rule: "EXACTLY(2, a == 1, b == 1, c == 1)", data: map[string]string{"a": "1", "b": "1", "c": "1"}, expectedMatch: false},
+		{desc: "exactly missed", rule: "EXACTLY(2, a == 1, b == 1, c == 1)", data: map[string]string{"a": "1"}, expectedMatch: false},
+		{desc: "xor", rule: "XOR(a == 1, b == 1)", data: map[string]string{"b": "1"}, expectedMatch: true},
+		{desc: "xor of both", rule: "XOR(a == 1, b == 1)", data: map[string]string{"a": "1", "b": "1"}, expectedMatch: false},
+		{desc: "priority decided by first child", rule: "PRIORITY(vip == 'true', age > 30)", data: map[string]string{"vip": "false", "age": "31"}, expectedMatch: false},
+		{desc: "priority falls through", rule: "PRIORITY(vip == 'true', age > 30)", data: map[string]string{"age": "31"}, expectedMatch: true},
+		{desc: "priority needs every attribute", rule: "PRIORITY(vip == 'true' AND region == 'EU', age > 30)", data: map[string]string{"vip": "true", "age": "20"}, expectedMatch: false},
+		{desc: "priority without opinion", rule: "PRIORITY(vip == 'true', age > 30)", data: map[string]string{}, expectedMatch: false},
+		{desc: "priority with bare term", rule: "PRIORITY(vip == 'true', active)", data: map[string]string{}, expectedMatch: true},
+		{desc: "negated list", rule: "NOT (XOR(a == 1, b == 1)) AND ATLEAST(1, c > 1, d > 1)", data: map[string]string{"d": "2"}, expectedMatch: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			match, err := Compile(grammar.MustParse(tt.rule))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedMatch, match(tt.data))
+		})
+	}
+}
+
+func TestCompileUnknownAST(t *testing.T) {
+	_, err := Compile(nil)
+	assert.True(t, errors.Is(err, parse.ErrUnknownAST), "unexpected error: %v", err)
+
+	// an unknown node below a known one is reported too
+	_, err = Compile(&bools.UnaryExpr{Op: bools.OpNot, Expr: nil})
+	assert.True(t, errors.Is(err, parse.ErrUnknownAST), "unexpected error: %v", err)
+}
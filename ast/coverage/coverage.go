@@ -0,0 +1,231 @@
+// Package coverage measures how thoroughly a set of records exercises a rule.
+//
+// Every node of the rule is evaluated against every record. A node decides the outcome for a record when forcing the
+// node to the opposite value, leaving the rest of the rule as it is, changes whether the rule matches the record. A
+// comparison is covered in the MC/DC sense when the records include one for which it is true and decides the outcome,
+// and one for which it is false and decides the outcome, showing that it affects the rule on its own.
+package coverage
+
+import (
+	"fmt"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/tree"
+)
+
+// Node is the coverage of one node of the rule. Paths list the indices of the children leading to the node in the
+// tree rendering of the rule, where AND and OR chains are flattened; the root is /. True and False count the records
+// for which the node was true and false, and DecidedTrue and DecidedFalse those for which it also decided the outcome.
+type Node struct {
+	Path         string `json:"path"`
+	Type         string `json:"type"`
+	Rule         string `json:"rule"`
+	True         int    `json:"true"`
+	False        int    `json:"false"`
+	DecidedTrue  int    `json:"decided_true"`
+	DecidedFalse int    `json:"decided_false"`
+}
+
+// Covered reports whether the node decided the outcome both when true and when false.
+func (n Node) Covered() bool {
+	return n.DecidedTrue > 0 && n.DecidedFalse > 0
+}
+
+// Report is the coverage of a rule by a set of records. Nodes lists every node of the rule, parents before their
+// children. ConditionCoverage is the share of comparisons which were both true and false, and MCDCCoverage the share
+// of comparisons which are covered; both are 1 for a rule without comparisons. Unexercised lists the paths of the
+// nodes which never decided the outcome.
+type Report struct {
+	Records           int      `json:"records"`
+	Matched           int      `json:"matched"`
+	Nodes             []Node   `json:"nodes"`
+	ConditionCoverage float64  `json:"condition_coverage"`
+	MCDCCoverage      float64  `json:"mcdc_coverage"`
+	Unexercised       []string `json:"unexercised"`
+}
+
+// node is a node of the rule being measured.
+type node struct {
+	kind     string
+	k        int
+	attrs    []string // attrs are the attributes compared by the node, for the children of a PRIORITY node
+	children []*node
+	leaf     compile.Rule
+	report   *Node
+}
+
+// Measure evaluates the rule against the records and reports its coverage.
+func Measure(ast parse.AST, records []map[string]string) (*Report, error) {
+	var nodes []*node
+	root, err := build(ast, nil, &nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Records: len(records), Nodes: []Node{}, Unexercised: []string{}}
+	for _, record := range records {
+		values := map[*node]bool{}
+		outcome := root.eval(record, values, nil)
+		if outcome {
+			report.Matched++
+		}
+		for _, n := range nodes {
+			value := values[n]
+			decided := root.eval(record, nil, map[*node]bool{n: !value}) != outcome
+			switch {
+			case value && decided:
+				n.report.True++
+				n.report.DecidedTrue++
+			case value:
+				n.report.True++
+			case decided:
+				n.report.False++
+				n.report.DecidedFalse++
+			default:
+				n.report.False++
+			}
+		}
+	}
+
+	comparisons, bothWays, covered := 0, 0, 0
+	for _, n := range nodes {
+		report.Nodes = append(report.Nodes, *n.report)
+		if n.report.DecidedTrue+n.report.DecidedFalse == 0 {
+			report.Unexercised = append(report.Unexercised, n.report.Path)
+		}
+		if n.kind != tree.Comparison {
+			continue
+		}
+		comparisons++
+		if n.report.True > 0 && n.report.False > 0 {
+			bothWays++
+		}
+		if n.report.Covered() {
+			covered++
+		}
+	}
+	report.ConditionCoverage, report.MCDCCoverage = 1, 1
+	if comparisons > 0 {
+		report.ConditionCoverage = float64(bothWays) / float64(comparisons)
+		report.MCDCCoverage = float64(covered) / float64(comparisons)
+	}
+	return report, nil
+}
+
+// build converts the rule into nodes shaped like its tree rendering, appending every node to nodes in pre-order.
+func build(ast parse.AST, path []int, nodes *[]*node) (*node, error) {
+	n := &node{report: &Node{Path: tree.Path(path), Rule: fmt.Sprint(ast)}}
+	*nodes = append(*nodes, n)
+
+	var children []parse.AST
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		switch ast.Op {
+		case bools.OpAnd:
+			n.kind = tree.And
+		case bools.OpOr:
+			n.kind = tree.Or
+		default:
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		children = bools.Flatten(ast, ast.Op)
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		n.kind, children = tree.Not, []parse.AST{ast.Expr}
+	case *bools.AtLeastExpr:
+		n.kind, n.k, children = tree.AtLeast, ast.K, ast.Children
+	case *bools.ExactlyExpr:
+		n.kind, n.k, children = tree.Exactly, ast.K, ast.Children
+	case *bools.PriorityExpr:
+		n.kind, children = tree.Priority, ast.Children
+	case *comp.EqualExpr, *comp.OrdinalExpr, parse.Unparsed:
+		n.kind = tree.Comparison
+		if _, ok := ast.(parse.Unparsed); ok {
+			n.kind = tree.Term
+		}
+		leaf, err := compile.Compile(ast)
+		if err != nil {
+			return nil, err
+		}
+		n.leaf = leaf
+	default:
+		return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+	}
+	n.report.Type = n.kind
+
+	for idx, child := range children {
+		built, err := build(child, append(append([]int{}, path...), idx), nodes)
+		if err != nil {
+			return nil, err
+		}
+		if n.kind == tree.Priority {
+			built.attrs = comp.Attributes(child)
+		}
+		n.children = append(n.children, built)
+	}
+	return n, nil
+}
+
+// eval evaluates the node for the record. Every node is evaluated, without short-circuiting, so that its value can be
+// stored in values when that is not nil. Nodes in override take the provided value instead of being evaluated.
+func (n *node) eval(record map[string]string, values, override map[*node]bool) bool {
+	var result bool
+	if forced, ok := override[n]; ok {
+		result = forced
+	} else {
+		result = n.evalChildren(record, values, override)
+	}
+	if values != nil {
+		values[n] = result
+	}
+	return result
+}
+
+func (n *node) evalChildren(record map[string]string, values, override map[*node]bool) bool {
+	if n.leaf != nil {
+		return n.leaf(record)
+	}
+	var results []bool
+	count := 0
+	for _, child := range n.children {
+		result := child.eval(record, values, override)
+		results = append(results, result)
+		if result {
+			count++
+		}
+	}
+
+	switch n.kind {
+	case tree.And:
+		return count == len(results)
+	case tree.Or:
+		return count > 0
+	case tree.Not:
+		return !results[0]
+	case tree.AtLeast:
+		return count >= n.k
+	case tree.Exactly:
+		return count == n.k
+	case tree.Priority:
+		for idx, child := range n.children {
+			if hasAll(record, child.attrs) {
+				return results[idx]
+			}
+		}
+	}
+	return false
+}
+
+func hasAll(record map[string]string, attrs []string) bool {
+	for _, attr := range attrs {
+		if _, ok := record[attr]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package coverage
+
+import (
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasure(t *testing.T) {
+
+	testCases := []struct {
+		desc                string
+		ruleString          string
+		records             []map[string]string
+		expectedMatched     int
+		expectedCondition   float64
+		expectedMCDC        float64
+		expectedUnexercised []string
+	}{
+		{
+			desc:       "full coverage of an AND",
+			ruleString: "age > 30 AND dept == 'A'",
+			records: []map[string]string{
+				{"age": "31", "dept": "A"},
+				{"age": "30", "dept": "A"},
+				{"age": "31", "dept": "B"},
+			},
+			expectedMatched:     1,
+			expectedCondition:   1,
+			expectedMCDC:        1,
+			expectedUnexercised: []string{},
+		},
+		{
+			desc:       "both false never decides",
+			ruleString: "age > 30 AND dept == 'A'",
+			records: []map[string]string{
+				{"age": "31", "dept": "A"},
+				{"age": "30", "dept": "B"},
+			},
+			expectedMatched:     1,
+			expectedCondition:   1,
+			expectedMCDC:        0,
+			expectedUnexercised: []string{},
+		},
+		{
+			desc:       "masked branch",
+			ruleString: "age > 30 OR (dept == 'A' AND salary > 100)",
+			records: []map[string]string{
+				{"age": "31", "dept": "A", "salary": "200"},
+				{"age": "20", "dept": "B", "salary": "200"},
+			},
+			expectedMatched:     1,
+			expectedCondition:   2.0 / 3,
+			expectedMCDC:        0,
+			expectedUnexercised: []string{"/1/1"},
+		},
+		{
+			desc:       "priority child without an opinion",
+			ruleString: "PRIORITY(vip == 'yes', age > 30)",
+			records: []map[string]string{
+				{"age": "31"},
+				{"age": "20"},
+			},
+			expectedMatched:     1,
+			expectedCondition:   0.5,
+			expectedMCDC:        0.5,
+			expectedUnexercised: []string{"/0"},
+		},
+		{
+			desc:                "rule without comparisons",
+			ruleString:          "active",
+			records:             []map[string]string{{}},
+			expectedMatched:     1,
+			expectedCondition:   1,
+			expectedMCDC:        1,
+			expectedUnexercised: []string{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			report, err := Measure(grammar.MustParse(tt.ruleString), tt.records)
+			assert.Nil(t, err)
+			assert.Equal(t, len(tt.records), report.Records)
+			assert.Equal(t, tt.expectedMatched, report.Matched)
+			assert.Equal(t, tt.expectedCondition, report.ConditionCoverage)
+			assert.Equal(t, tt.expectedMCDC, report.MCDCCoverage)
+			assert.Equal(t, tt.expectedUnexercised, report.Unexercised)
+		})
+	}
+}
+
+func TestMeasureNodes(t *testing.T) {
+	ast := grammar.MustParse("age > 30 AND (dept == 'A' OR dept == 'B')")
+	records := []map[string]string{
+		{"age": "31", "dept": "A"},
+		{"age": "31", "dept": "B"},
+		{"age": "31", "dept": "C"},
+		{"age": "20", "dept": "A"},
+	}
+	report, err := Measure(ast, records)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Node{
+		{Path: "/", Type: "and", Rule: report.Nodes[0].Rule, True: 2, False: 2, DecidedTrue: 2, DecidedFalse: 2},
+		{Path: "/0", Type: "comparison", Rule: report.Nodes[1].Rule, True: 3, False: 1, DecidedTrue: 2, DecidedFalse: 1},
+		{Path: "/1", Type: "or", Rule: report.Nodes[2].Rule, True: 3, False: 1, DecidedTrue: 2, DecidedFalse: 1},
+		{Path: "/1/0", Type: "comparison", Rule: report.Nodes[3].Rule, True: 2, False: 2, DecidedTrue: 1, DecidedFalse: 1},
+		{Path: "/1/1", Type: "comparison", Rule: report.Nodes[4].Rule, True: 1, False: 3, DecidedTrue: 1, DecidedFalse: 1},
+	}, report.Nodes)
+
+	// the root follows the rule engine
+	match, err := compile.Compile(ast)
+	assert.Nil(t, err)
+	matched := 0
+	for _, record := range records {
+		if match(record) {
+			matched++
+		}
+	}
+	assert.Equal(t, matched, report.Matched)
+}
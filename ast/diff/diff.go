@@ -0,0 +1,275 @@
+// Package diff compares the structure of two rules and lists the clauses which were added, removed or changed between
+// them.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/tree"
+)
+
+// Kind is the kind of a Change.
+type Kind string
+
+// Kinds of Change.
+const (
+	Added    Kind = "added"
+	Removed  Kind = "removed"
+	Replaced Kind = "replaced"
+	Operator Kind = "operator"
+	Literal  Kind = "literal"
+)
+
+// Change is a difference between two rules. Added clauses only have a NewPath and an After, removed clauses only an
+// OldPath and a Before. Operator is an AND becoming an OR, a count changing, or a comparison operator changing, and
+// Literal is the literal of a comparison changing; both give the whole clause before and after the change.
+//
+// Paths list the indices of the children leading to the clause in the tree rendering of the rule, where AND and OR
+// chains are flattened, so /1/0 is the first operand of the second operand of the root. The root is /.
+type Change struct {
+	Kind    Kind   `json:"kind"`
+	OldPath string `json:"old_path,omitempty"`
+	NewPath string `json:"new_path,omitempty"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+// node is a rule node shaped like its tree rendering.
+type node struct {
+	kind     string
+	label    string // label is the operator of a comparison, or the count of ATLEAST and EXACTLY
+	ast      parse.AST
+	pred     *comp.Predicate
+	children []*node
+	key      string
+}
+
+// Diff lists the changes turning the first rule into the second. Formatting is ignored, and so is the order of the
+// operands of AND, OR, ATLEAST and EXACTLY, which do not change what the rule matches. Changes are listed in the order
+// of the clauses in the first rule, followed by the clauses added in the second.
+func Diff(first, second parse.AST) ([]Change, error) {
+	a, err := build(first)
+	if err != nil {
+		return nil, err
+	}
+	b, err := build(second)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	compare(a, b, nil, nil, &changes)
+	return changes, nil
+}
+
+func build(ast parse.AST) (*node, error) {
+	n := &node{ast: ast}
+	var children []parse.AST
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		switch ast.Op {
+		case bools.OpAnd:
+			n.kind = tree.And
+		case bools.OpOr:
+			n.kind = tree.Or
+		default:
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		children = bools.Flatten(ast, ast.Op)
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		n.kind, children = tree.Not, []parse.AST{ast.Expr}
+	case *bools.AtLeastExpr:
+		n.kind, n.label, children = tree.AtLeast, strconv.Itoa(ast.K), ast.Children
+	case *bools.ExactlyExpr:
+		n.kind, n.label, children = tree.Exactly, strconv.Itoa(ast.K), ast.Children
+	case *bools.PriorityExpr:
+		n.kind, children = tree.Priority, ast.Children
+	case *comp.EqualExpr:
+		n.kind, n.label = tree.Comparison, ast.Op.String()
+		children = comparisonOperands(ast, ast.LHS, ast.RHS, n)
+	case *comp.OrdinalExpr:
+		n.kind, n.label = tree.Comparison, ast.Op.String()
+		children = comparisonOperands(ast, ast.LHS, ast.RHS, n)
+	case parse.Unparsed:
+		n.kind, n.label = tree.Term, ast.String()
+	default:
+		return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+	}
+
+	for _, child := range children {
+		built, err := build(child)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, built)
+	}
+	n.key = key(n)
+	return n, nil
+}
+
+// comparisonOperands sets the predicate of a comparison between an attribute and a literal, and returns the operands
+// of any other comparison.
+func comparisonOperands(ast, lhs, rhs parse.AST, n *node) []parse.AST {
+	if pred, ok := comp.AsPredicate(ast); ok {
+		n.pred = &pred
+		return nil
+	}
+	return []parse.AST{lhs, rhs}
+}
+
+// commutative reports whether the order of the children of the node does not matter.
+func (n *node) commutative() bool {
+	switch n.kind {
+	case tree.And, tree.Or, tree.AtLeast, tree.Exactly:
+		return true
+	}
+	return false
+}
+
+// key returns a string identifying the node up to formatting and the order of commutative operands.
+func key(n *node) string {
+	if n.pred != nil {
+		return fmt.Sprintf("%s %s %q %t", n.pred.Attr, n.label, n.pred.Value, n.pred.Quoted)
+	}
+	var children []string
+	for _, child := range n.children {
+		children = append(children, child.key)
+	}
+	if n.commutative() {
+		sort.Strings(children)
+	}
+	return n.kind + " " + n.label + "(" + strings.Join(children, ", ") + ")"
+}
+
+// group reports whether the kind is one of the nodes joining any number of children, which can be changed into one
+// another by changing their operator.
+func group(kind string) bool {
+	return kind == tree.And || kind == tree.Or || kind == tree.AtLeast || kind == tree.Exactly
+}
+
+func compare(a, b *node, oldPath, newPath []int, changes *[]Change) {
+	if a.key == b.key {
+		return
+	}
+	change := Change{OldPath: tree.Path(oldPath), NewPath: tree.Path(newPath), Before: fmt.Sprint(a.ast), After: fmt.Sprint(b.ast)}
+
+	switch {
+	case a.pred != nil && b.pred != nil && a.pred.Attr == b.pred.Attr:
+		if a.label != b.label {
+			change.Kind = Operator
+			*changes = append(*changes, change)
+		}
+		if a.pred.Value != b.pred.Value || a.pred.Quoted != b.pred.Quoted {
+			change.Kind = Literal
+			*changes = append(*changes, change)
+		}
+	case group(a.kind) && group(b.kind):
+		if a.kind != b.kind || a.label != b.label {
+			change.Kind = Operator
+			*changes = append(*changes, change)
+		}
+		if a.commutative() && b.commutative() {
+			matchChildren(a, b, oldPath, newPath, changes)
+		} else {
+			positionalChildren(a, b, oldPath, newPath, changes)
+		}
+	case a.kind == b.kind && a.kind != tree.Term && a.pred == nil && b.pred == nil:
+		if a.label != b.label {
+			change.Kind = Operator
+			*changes = append(*changes, change)
+		}
+		positionalChildren(a, b, oldPath, newPath, changes)
+	default:
+		change.Kind = Replaced
+		*changes = append(*changes, change)
+	}
+}
+
+// matchChildren compares the children of two commutative nodes: identical children are paired first, then children
+// of the same kind, comparisons being only paired when they are on the same attribute.
+func matchChildren(a, b *node, oldPath, newPath []int, changes *[]Change) {
+	pairs := make([]int, len(a.children))
+	paired := make([]bool, len(b.children))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	pair := func(similar func(x, y *node) bool) {
+		for i, x := range a.children {
+			for j, y := range b.children {
+				if pairs[i] < 0 && !paired[j] && similar(x, y) {
+					pairs[i], paired[j] = j, true
+				}
+			}
+		}
+	}
+	pair(func(x, y *node) bool { return x.key == y.key })
+	pair(func(x, y *node) bool {
+		if x.pred != nil || y.pred != nil {
+			return x.pred != nil && y.pred != nil && x.pred.Attr == y.pred.Attr
+		}
+		return x.kind == y.kind || group(x.kind) && group(y.kind)
+	})
+
+	for i, x := range a.children {
+		if j := pairs[i]; j >= 0 {
+			compare(x, b.children[j], child(oldPath, i), child(newPath, j), changes)
+		} else {
+			*changes = append(*changes, Change{Kind: Removed, OldPath: tree.Path(child(oldPath, i)), Before: fmt.Sprint(x.ast)})
+		}
+	}
+	for j, y := range b.children {
+		if !paired[j] {
+			*changes = append(*changes, Change{Kind: Added, NewPath: tree.Path(child(newPath, j)), After: fmt.Sprint(y.ast)})
+		}
+	}
+}
+
+// positionalChildren compares the children of two nodes in which order matters, one position at a time.
+func positionalChildren(a, b *node, oldPath, newPath []int, changes *[]Change) {
+	for i, x := range a.children {
+		if i < len(b.children) {
+			compare(x, b.children[i], child(oldPath, i), child(newPath, i), changes)
+		} else {
+			*changes = append(*changes, Change{Kind: Removed, OldPath: tree.Path(child(oldPath, i)), Before: fmt.Sprint(x.ast)})
+		}
+	}
+	for j := len(a.children); j < len(b.children); j++ {
+		*changes = append(*changes, Change{Kind: Added, NewPath: tree.Path(child(newPath, j)), After: fmt.Sprint(b.children[j].ast)})
+	}
+}
+
+func child(p []int, idx int) []int {
+	return append(append([]int{}, p...), idx)
+}
+
+// Unified renders the changes as text in the style of a unified diff: each change starts with a header giving its kind
+// and paths, followed by the clause before the change prefixed with - and the clause after it prefixed with +.
+func Unified(changes []Change) string {
+	var result strings.Builder
+	result.WriteString("--- first\n+++ second\n")
+	for _, change := range changes {
+		fmt.Fprintf(&result, "@@ %s", change.Kind)
+		if change.OldPath != "" {
+			fmt.Fprintf(&result, " -%s", change.OldPath)
+		}
+		if change.NewPath != "" {
+			fmt.Fprintf(&result, " +%s", change.NewPath)
+		}
+		result.WriteString(" @@\n")
+		if change.Before != "" {
+			fmt.Fprintf(&result, "-%s\n", change.Before)
+		}
+		if change.After != "" {
+			fmt.Fprintf(&result, "+%s\n", change.After)
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+
+	testCases := []struct {
+		desc            string
+		firstRule       string
+		secondRule      string
+		expectedChanges []Change
+	}{
+		{
+			desc:       "formatting and reordering",
+			firstRule:  "age > 30 AND (department == 'Sales' OR salary > 20000)",
+			secondRule: "((salary > 20000  OR  department == 'Sales')) AND (age > 30)",
+		},
+		{
+			desc:       "changed literal",
+			firstRule:  "age > 30 AND department == 'Sales'",
+			secondRule: "department == 'Marketing' AND age > 30",
+			expectedChanges: []Change{
+				{Kind: Literal, OldPath: "/1", NewPath: "/0", Before: "department == 'Sales'", After: "department == 'Marketing'"},
+			},
+		},
+		{
+			desc:       "changed comparison operator",
+			firstRule:  "age > 30",
+			secondRule: "age >= 30",
+			expectedChanges: []Change{
+				{Kind: Operator, OldPath: "/", NewPath: "/", Before: "age > 30", After: "age >= 30"},
+			},
+		},
+		{
+			desc:       "quoted literal",
+			firstRule:  "grade == 3",
+			secondRule: "grade == '3'",
+			expectedChanges: []Change{
+				{Kind: Literal, OldPath: "/", NewPath: "/", Before: "grade == 3", After: "grade == '3'"},
+			},
+		},
+		{
+			desc:       "added and removed clauses",
+			firstRule:  "age > 30 AND department == 'Sales' AND active",
+			secondRule: "age > 30 AND salary > 20000 AND active AND experience > 5",
+			expectedChanges: []Change{
+				{Kind: Removed, OldPath: "/1", Before: "department == 'Sales'"},
+				{Kind: Added, NewPath: "/1", After: "salary > 20000"},
+				{Kind: Added, NewPath: "/3", After: "experience > 5"},
+			},
+		},
+		{
+			desc:       "changed chain operator",
+			firstRule:  "age > 30 AND department == 'Sales'",
+			secondRule: "age > 31 OR department == 'Sales'",
+			expectedChanges: []Change{
+				{Kind: Operator, OldPath: "/", NewPath: "/", Before: "age > 30 AND department == 'Sales'", After: "age > 31 OR department == 'Sales'"},
+				{Kind: Literal, OldPath: "/0", NewPath: "/0", Before: "age > 30", After: "age > 31"},
+			},
+		},
+		{
+			desc:       "changed count",
+			firstRule:  "ATLEAST(2, a == 1, b == 2, c == 3)",
+			secondRule: "ATLEAST(1, c == 3, b == 2, a == 1)",
+			expectedChanges: []Change{
+				{Kind: Operator, OldPath: "/", NewPath: "/", Before: "ATLEAST(2, a == 1, b == 2, c == 3)", After: "ATLEAST(1, c == 3, b == 2, a == 1)"},
+			},
+		},
+		{
+			desc:       "priority order matters",
+			firstRule:  "PRIORITY(a == 1, b == 2)",
+			secondRule: "PRIORITY(b == 2, a == 1)",
+			expectedChanges: []Change{
+				{Kind: Replaced, OldPath: "/0", NewPath: "/0", Before: "a == 1", After: "b == 2"},
+				{Kind: Replaced, OldPath: "/1", NewPath: "/1", Before: "b == 2", After: "a == 1"},
+			},
+		},
+		{
+			desc:       "nested change",
+			firstRule:  "age > 30 AND NOT (department == 'Sales' OR department == 'HR')",
+			secondRule: "age > 30 AND NOT (department == 'Sales')",
+			expectedChanges: []Change{
+				{Kind: Replaced, OldPath: "/1/0", NewPath: "/1/0", Before: "department == 'Sales' OR department == 'HR'", After: "department == 'Sales'"},
+			},
+		},
+		{
+			desc:       "replaced clause",
+			firstRule:  "age > 30 AND NOT (active)",
+			secondRule: "age > 30 AND experience > 5",
+			expectedChanges: []Change{
+				{Kind: Removed, OldPath: "/1", Before: "NOT (active)"},
+				{Kind: Added, NewPath: "/1", After: "experience > 5"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			changes, err := Diff(grammar.MustParse(tt.firstRule), grammar.MustParse(tt.secondRule))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedChanges, changes)
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	changes, err := Diff(
+		grammar.MustParse("age > 30 AND department == 'Sales' AND active"),
+		grammar.MustParse("age >= 30 AND active AND experience > 5"),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "--- first\n+++ second\n"+
+		"@@ operator -/0 +/0 @@\n-age > 30\n+age >= 30\n"+
+		"@@ removed -/1 @@\n-department == 'Sales'\n"+
+		"@@ added +/2 @@\n+experience > 5\n", Unified(changes))
+}
@@ -0,0 +1,187 @@
+// Package elastic translates rule ASTs into Elasticsearch bool queries.
+package elastic
+
+import (
+	"errors"
+	"fmt"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrUnsupported is returned for rule constructs which have no Elasticsearch equivalent.
+var ErrUnsupported = errors.New("unsupported construct")
+
+var operators = map[comp.Op]string{
+	comp.OpGreater:        "gt",
+	comp.OpGreaterOrEqual: "gte",
+	comp.OpLess:           "lt",
+	comp.OpLessOrEqual:    "lte",
+}
+
+// Search returns a search request body whose query matches the documents matched by the provided rule.
+func Search(ast parse.AST) (map[string]any, error) {
+	query, err := Query(ast)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"query": query}, nil
+}
+
+// Query translates a parsed rule into an Elasticsearch query clause made of maps and slices. AND becomes a filter
+// context bool query so that rules do not affect scoring, a chain of OR-ed equality checks on one field becomes a
+// terms query, and since the engine never matches a comparison on a missing attribute, != also requires the field to
+// exist.
+// ATLEAST uses minimum_should_match, EXACTLY also excludes the documents matching one more child, and PRIORITY checks
+// the fields of its children with exists queries.
+func Query(ast parse.AST) (map[string]any, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		operands := bools.Flatten(ast, ast.Op)
+		var clause map[string]any
+		switch ast.Op {
+		case bools.OpAnd:
+			queries, err := queries(operands)
+			if err != nil {
+				return nil, err
+			}
+			clause = map[string]any{"filter": queries}
+		case bools.OpOr:
+			if field, values, ok := termsList(operands); ok {
+				return map[string]any{"terms": map[string]any{field: values}}, nil
+			}
+			queries, err := queries(operands)
+			if err != nil {
+				return nil, err
+			}
+			clause = map[string]any{"should": queries, "minimum_should_match": 1}
+		default:
+			return nil, fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		return map[string]any{"bool": clause}, nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		query, err := Query(ast.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"bool": map[string]any{"must_not": []any{query}}}, nil
+	case *bools.AtLeastExpr:
+		return atLeast(ast.K, ast.Children)
+	case *bools.ExactlyExpr:
+		atLeastK, err := atLeast(ast.K, ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		moreThanK, err := atLeast(ast.K+1, ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"bool": map[string]any{"filter": []any{atLeastK}, "must_not": []any{moreThanK}}}, nil
+	case *bools.PriorityExpr:
+		return priorityQuery(ast)
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return nil, fmt.Errorf("%w: comparison '%v' must have an attribute on the left and a literal on the right", ErrUnsupported, ast)
+		}
+		return predicateQuery(pred)
+	case parse.Unparsed:
+		return nil, fmt.Errorf("%w: '%v' is not a comparison", ErrUnsupported, ast)
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func queries(operands []parse.AST) ([]any, error) {
+	var result []any
+	for _, operand := range operands {
+		query, err := Query(operand)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, query)
+	}
+	return result, nil
+}
+
+func atLeast(k int, children []parse.AST) (map[string]any, error) {
+	queries, err := queries(children)
+	if err != nil {
+		return nil, err
+	}
+	if k <= 0 {
+		return map[string]any{"match_all": map[string]any{}}, nil
+	}
+	if k > len(children) {
+		return map[string]any{"match_none": map[string]any{}}, nil
+	}
+	return map[string]any{"bool": map[string]any{"should": queries, "minimum_should_match": k}}, nil
+}
+
+// priorityQuery matches the documents matched by a child when they have all its fields and miss one of the fields of
+// each earlier child.
+func priorityQuery(ast *bools.PriorityExpr) (map[string]any, error) {
+	var clauses, earlier []any
+	for _, child := range ast.Children {
+		query, err := Query(child)
+		if err != nil {
+			return nil, err
+		}
+		filter := []any{query}
+		fields := comp.Attributes(child)
+		for _, field := range fields {
+			filter = append(filter, map[string]any{"exists": map[string]any{"field": field}})
+		}
+		clause := map[string]any{"filter": filter}
+		if len(earlier) > 0 {
+			clause["must_not"] = append([]any{}, earlier...)
+		}
+		clauses = append(clauses, map[string]any{"bool": clause})
+		if len(fields) == 0 {
+			break
+		}
+		earlier = append(earlier, map[string]any{"bool": map[string]any{"filter": filter[1:]}})
+	}
+	return map[string]any{"bool": map[string]any{"should": clauses, "minimum_should_match": 1}}, nil
+}
+
+func predicateQuery(pred comp.Predicate) (map[string]any, error) {
+	switch pred.Op {
+	case comp.OpEqual:
+		return term(pred), nil
+	case comp.OpNotEqual:
+		return map[string]any{"bool": map[string]any{
+			"filter":   []any{map[string]any{"exists": map[string]any{"field": pred.Attr}}},
+			"must_not": []any{term(pred)},
+		}}, nil
+	case comp.OpGreater, comp.OpGreaterOrEqual, comp.OpLess, comp.OpLessOrEqual:
+		n, ok := pred.NumericLiteral()
+		if !ok {
+			return nil, fmt.Errorf("%w: ordinal comparison '%v' against non-numeric literal", ErrUnsupported, pred)
+		}
+		return map[string]any{"range": map[string]any{pred.Attr: map[string]any{operators[pred.Op]: n}}}, nil
+	}
+	return nil, fmt.Errorf("%w: comparison operator %v", ErrUnsupported, pred.Op)
+}
+
+func term(pred comp.Predicate) map[string]any {
+	return map[string]any{"term": map[string]any{pred.Attr: pred.Literal()}}
+}
+
+// termsList reports whether the operands are all equality checks on one field, returning the field and values.
+func termsList(operands []parse.AST) (string, []any, bool) {
+	var field string
+	var values []any
+	for _, operand := range operands {
+		pred, ok := comp.AsPredicate(operand)
+		if !ok || pred.Op != comp.OpEqual || (field != "" && pred.Attr != field) {
+			return "", nil, false
+		}
+		field = pred.Attr
+		values = append(values, pred.Literal())
+	}
+	return field, values, true
+}
@@ -0,0 +1,68 @@
+package elastic
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGolden(t *testing.T) {
+
+	testCases := []struct {
+		name       string
+		ruleString string
+	}{
+		{name: "comparisons", ruleString: "age > 30 AND age <= 60 AND salary >= 20000.5 AND experience < 5"},
+		{name: "equality", ruleString: "department == 'Marketing' AND grade != 3 AND active == true"},
+		{name: "readme", ruleString: "((age > 30 AND department == 'Marketing')) AND (salary > 20000 OR experience > 5)"},
+		{name: "in", ruleString: "department == 'Sales' OR department == 'Marketing' OR department == 'Human Resources'"},
+		{name: "not", ruleString: "NOT (department == 'Sales' OR age < 18)"},
+		{name: "xor", ruleString: "XOR(department == 'Sales', age < 18)"},
+		{name: "priority", ruleString: "PRIORITY(override == 'allow', age > 30 AND salary > 20000)"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Search(grammar.MustParse(tt.ruleString))
+			assert.Nil(t, err)
+			actual, err := json.MarshalIndent(doc, "", "  ")
+			assert.Nil(t, err)
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				assert.Nil(t, os.WriteFile(golden, append(actual, '\n'), 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.Nil(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+
+	testCases := []struct {
+		desc string
+		ast  parse.AST
+	}{
+		{desc: "bare term", ast: parse.Unparsed{Contents: []string{"active"}}},
+		{desc: "ordinal comparison against a string", ast: grammar.MustParse("age > 'thirty'")},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := Search(tt.ast)
+			assert.True(t, errors.Is(err, ErrUnsupported), "unexpected error: %v", err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package equiv compares two rules by the records they match.
+package equiv
+
+import (
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/sat"
+)
+
+// Relation is how the records matched by a first rule relate to the records matched by a second one.
+type Relation string
+
+const (
+	// Equivalent rules match the same records.
+	Equivalent Relation = "equivalent"
+	// Implies means every record matched by the first rule is matched by the second, which matches more records.
+	Implies Relation = "implies"
+	// ImpliedBy means every record matched by the second rule is matched by the first, which matches more records.
+	ImpliedBy Relation = "implied_by"
+	// Incomparable rules each match records the other does not.
+	Incomparable Relation = "incomparable"
+)
+
+// Result is the outcome of Compare. Unless the rules are equivalent it holds witness records on which they disagree.
+type Result struct {
+	Relation Relation `json:"relation"`
+	// OnlyFirst is a record matched by the first rule and not the second, if there is one.
+	OnlyFirst map[string]string `json:"only_first"`
+	// OnlySecond is a record matched by the second rule and not the first, if there is one.
+	OnlySecond map[string]string `json:"only_second"`
+}
+
+// Compare decides how the records matched by the first rule relate to those matched by the second. It looks for a
+// record matched by one rule and not the other, in both directions, with the solver in ast/sat, so the result follows
+// the engine's semantics exactly, including for missing attributes and values which are not numbers.
+func Compare(first, second parse.AST) (Result, error) {
+	onlyFirst, firstBroader, err := sat.Solve(difference(first, second))
+	if err != nil {
+		return Result{}, err
+	}
+	onlySecond, secondBroader, err := sat.Solve(difference(second, first))
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{OnlyFirst: onlyFirst, OnlySecond: onlySecond}
+	switch {
+	case firstBroader && secondBroader:
+		result.Relation = Incomparable
+	case firstBroader:
+		result.Relation = ImpliedBy
+	case secondBroader:
+		result.Relation = Implies
+	default:
+		result.Relation = Equivalent
+	}
+	return result, nil
+}
+
+// difference returns a rule matching the records matched by a and not by b.
+func difference(a, b parse.AST) parse.AST {
+	return &bools.BinExpr{LHS: a, RHS: &bools.UnaryExpr{Op: bools.OpNot, Expr: b}, Op: bools.OpAnd}
+}
@@ -0,0 +1,174 @@
+package equiv
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+	"RuleEngineAST/ast/simplify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertWitnesses checks that the witnesses of a result are matched by exactly the rule they are reported for.
+func assertWitnesses(t *testing.T, first, second parse.AST, result Result) {
+	matchFirst, err := compile.Compile(first)
+	assert.Nil(t, err)
+	matchSecond, err := compile.Compile(second)
+	assert.Nil(t, err)
+
+	if result.OnlyFirst != nil {
+		assert.True(t, matchFirst(result.OnlyFirst), "first rule must match %v", result.OnlyFirst)
+		assert.False(t, matchSecond(result.OnlyFirst), "second rule must not match %v", result.OnlyFirst)
+	}
+	if result.OnlySecond != nil {
+		assert.False(t, matchFirst(result.OnlySecond), "first rule must not match %v", result.OnlySecond)
+		assert.True(t, matchSecond(result.OnlySecond), "second rule must match %v", result.OnlySecond)
+	}
+}
+
+func TestCompare(t *testing.T) {
+
+	testCases := []struct {
+		desc             string
+		firstRule        string
+		secondRule       string
+		expectedRelation Relation
+	}{
+		{
+			desc:             "same rule",
+			firstRule:        "age > 30 AND department == 'Sales'",
+			secondRule:       "age > 30 AND department == 'Sales'",
+			expectedRelation: Equivalent,
+		},
+		{
+			desc:             "reordered and reformatted",
+			firstRule:        "age > 30 AND (department == 'Sales' OR salary > 20000)",
+			secondRule:       "((salary > 20000 OR department == 'Sales')) AND age > 30.0",
+			expectedRelation: Equivalent,
+		},
+		{
+			desc:             "subsumed bound",
+			firstRule:        "age > 30 AND age > 20",
+			secondRule:       "age > 30",
+			expectedRelation: Equivalent,
+		},
+		{
+			desc:             "tighter bound is narrower",
+			firstRule:        "age > 40",
+			secondRule:       "age > 30",
+			expectedRelation: Implies,
+		},
+		{
+			desc:             "extra clause in a disjunction is broader",
+			firstRule:        "age > 30 OR department == 'Sales'",
+			secondRule:       "age > 30",
+			expectedRelation: ImpliedBy,
+		},
+		{
+			desc:             "overlapping ranges",
+			firstRule:        "age > 30 AND age < 50",
+			secondRule:       "age > 40",
+			expectedRelation: Incomparable,
+		},
+		{
+			desc:             "negated comparison is not the opposite bound",
+			firstRule:        "NOT (age > 30)",
+			secondRule:       "age <= 30",
+			expectedRelation: ImpliedBy,
+		},
+		{
+			desc:             "bare terms always match",
+			firstRule:        "active",
+			secondRule:       "NOT (age > 30 AND age < 20)",
+			expectedRelation: Equivalent,
+		},
+		{
+			desc:             "float32 precision",
+			firstRule:        "age > 30",
+			secondRule:       "age > 30.0000001",
+			expectedRelation: Equivalent,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			first := grammar.MustParse(tt.firstRule)
+			second := grammar.MustParse(tt.secondRule)
+
+			result, err := Compare(first, second)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRelation, result.Relation)
+			assert.Equal(t, tt.expectedRelation == Equivalent || tt.expectedRelation == Implies, result.OnlyFirst == nil)
+			assert.Equal(t, tt.expectedRelation == Equivalent || tt.expectedRelation == ImpliedBy, result.OnlySecond == nil)
+			assertWitnesses(t, first, second, result)
+		})
+	}
+}
+
+// TestCompareRandomRules compares random rules with their simplified form, which must be equivalent, and with other
+// random rules, checking the relation against the records both rules match.
+func TestCompareRandomRules(t *testing.T) {
+
+	atoms := []string{
+		"age > 30", "age >= 30", "age < 30", "age <= 20", "age == 30", "age != 30",
+		"dept == 'A'", "dept == 'B'", "dept != 'A'", "flag",
+	}
+	var records []map[string]string
+	for _, age := range []string{"", "19", "20", "20.0", "25", "30", "30.0", "31", "NaN", "thirty"} {
+		for _, dept := range []string{"", "A", "B", "C"} {
+			record := map[string]string{}
+			if age != "" {
+				record["age"] = age
+			}
+			if dept != "" {
+				record["dept"] = dept
+			}
+			records = append(records, record)
+		}
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	var build func(depth int) string
+	build = func(depth int) string {
+		switch n := rnd.Intn(6); {
+		case depth == 0 || n < 2:
+			return atoms[rnd.Intn(len(atoms))]
+		case n == 2:
+			return "NOT (" + build(depth-1) + ")"
+		case n == 3:
+			return "(" + build(depth-1) + ") OR (" + build(depth-1) + ")"
+		default:
+			return "(" + build(depth-1) + ") AND (" + build(depth-1) + ")"
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+		first := grammar.MustParse(build(3))
+		simplified, err := simplify.Simplify(first)
+		assert.Nil(t, err)
+		result, err := Compare(first, simplified)
+		assert.Nil(t, err)
+		assert.Equal(t, Equivalent, result.Relation, "rule %q simplified to %q", first, simplified)
+
+		second := grammar.MustParse(build(3))
+		result, err = Compare(first, second)
+		assert.Nil(t, err)
+		assertWitnesses(t, first, second, result)
+
+		// the records cover every case the atoms tell apart, so they must agree with the solver
+		matchFirst, _ := compile.Compile(first)
+		matchSecond, _ := compile.Compile(second)
+		onlyFirst, onlySecond := false, false
+		for _, record := range records {
+			onlyFirst = onlyFirst || matchFirst(record) && !matchSecond(record)
+			onlySecond = onlySecond || !matchFirst(record) && matchSecond(record)
+		}
+		description := fmt.Sprintf("comparing %q with %q", first, second)
+		assert.Equal(t, onlyFirst, result.OnlyFirst != nil, description)
+		assert.Equal(t, onlySecond, result.OnlySecond != nil, description)
+	}
+}
@@ -0,0 +1,153 @@
+// Package expand rewrites the ATLEAST, EXACTLY and PRIORITY nodes of a rule into AND, OR and NOT, for translators and
+// analyses which only handle those.
+package expand
+
+import (
+	"errors"
+	"fmt"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrTooLarge is returned when a count node would expand into more than MaxClauses clauses.
+var ErrTooLarge = errors.New("expansion too large")
+
+// MaxClauses is the largest number of clauses a single ATLEAST or EXACTLY node may expand into.
+const MaxClauses = 1024
+
+// Expand returns a copy of the rule made only of AND, OR and NOT nodes and the nodes they join; the provided AST is not
+// modified. ATLEAST and EXACTLY become a disjunction with one clause per combination of matching children, and
+// PRIORITY becomes a disjunction with one clause per child, matching when the child matches and the record has its
+// attributes but not those of any earlier child.
+func Expand(ast parse.AST) (parse.AST, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		lhs, err := Expand(ast.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := Expand(ast.RHS)
+		if err != nil {
+			return nil, err
+		}
+		return &bools.BinExpr{LHS: lhs, RHS: rhs, Op: ast.Op}, nil
+	case *bools.UnaryExpr:
+		expr, err := Expand(ast.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &bools.UnaryExpr{Op: ast.Op, Expr: expr}, nil
+	case *bools.AtLeastExpr:
+		children, err := expandAll(ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		return combinations(ast.K, children, false)
+	case *bools.ExactlyExpr:
+		children, err := expandAll(ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		return combinations(ast.K, children, true)
+	case *bools.PriorityExpr:
+		children, err := expandAll(ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		return priority(ast.Children, children), nil
+	}
+	return ast, nil
+}
+
+func expandAll(children []parse.AST) ([]parse.AST, error) {
+	var result []parse.AST
+	for _, child := range children {
+		expanded, err := Expand(child)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded)
+	}
+	return result, nil
+}
+
+// combinations returns a disjunction of the conjunctions of every k of the children, in which the other children must
+// not match if exact is set.
+func combinations(k int, children []parse.AST, exact bool) (parse.AST, error) {
+	n := len(children)
+	if k <= 0 && !exact {
+		return parse.Unparsed{Contents: []string{"TRUE"}}, nil
+	}
+	if k < 0 || k > n {
+		return &bools.UnaryExpr{Op: bools.OpNot, Expr: parse.Unparsed{Contents: []string{"TRUE"}}}, nil
+	}
+	if count := binomial(n, k); count > MaxClauses {
+		return nil, fmt.Errorf("%w: choosing %d of %d rules gives more than %d clauses", ErrTooLarge, k, n, MaxClauses)
+	}
+
+	var clauses []parse.AST
+	var choose func(start int, chosen []bool, left int)
+	choose = func(start int, chosen []bool, left int) {
+		if left == 0 {
+			var clause []parse.AST
+			for idx, child := range children {
+				if chosen[idx] {
+					clause = append(clause, child)
+				} else if exact {
+					clause = append(clause, &bools.UnaryExpr{Op: bools.OpNot, Expr: child})
+				}
+			}
+			if len(clause) == 0 {
+				clause = append(clause, parse.Unparsed{Contents: []string{"TRUE"}})
+			}
+			clauses = append(clauses, bools.Chain(bools.OpAnd, clause...))
+			return
+		}
+		for idx := start; idx <= n-left; idx++ {
+			chosen[idx] = true
+			choose(idx+1, chosen, left-1)
+			chosen[idx] = false
+		}
+	}
+	choose(0, make([]bool, n), k)
+	return bools.Chain(bools.OpOr, clauses...), nil
+}
+
+// binomial returns n choose k, or MaxClauses+1 if it is larger than MaxClauses.
+func binomial(n, k int) int {
+	result := 1
+	for i := 1; i <= k; i++ {
+		result = result * (n - k + i) / i
+		if result > MaxClauses {
+			return MaxClauses + 1
+		}
+	}
+	return result
+}
+
+// Priority rewrites a PRIORITY node into AND, OR and NOT, leaving its children as they are.
+func Priority(ast *bools.PriorityExpr) parse.AST {
+	return priority(ast.Children, ast.Children)
+}
+
+// priority expands a PRIORITY node given its original and expanded children. The attributes are taken from the
+// original children, which compare the same attributes as the expanded ones.
+func priority(original, children []parse.AST) parse.AST {
+	var clauses, earlier []parse.AST
+	for idx, child := range children {
+		attrs := comp.Attributes(original[idx])
+		clause := append(append([]parse.AST{}, earlier...), child)
+		if len(attrs) > 0 {
+			clause = append(clause, comp.Present(attrs...))
+		}
+		clauses = append(clauses, bools.Chain(bools.OpAnd, clause...))
+		if len(attrs) == 0 {
+			// a child comparing no attribute always has an opinion, so no later child is ever used
+			break
+		}
+		earlier = append(earlier, &bools.UnaryExpr{Op: bools.OpNot, Expr: comp.Present(attrs...)})
+	}
+	return bools.Chain(bools.OpOr, clauses...)
+}
@@ -0,0 +1,117 @@
+package expand
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+
+	var records []map[string]string
+	for _, age := range []string{"", "20", "31"} {
+		for _, dept := range []string{"", "A", "B"} {
+			for _, grade := range []string{"", "1", "2"} {
+				record := map[string]string{}
+				for attr, val := range map[string]string{"age": age, "dept": dept, "grade": grade} {
+					if val != "" {
+						record[attr] = val
+					}
+				}
+				records = append(records, record)
+			}
+		}
+	}
+
+	testCases := []struct {
+		desc         string
+		ruleString   string
+		ast          parse.AST // ast is expanded instead of ruleString, for counts the parser rejects
+		expectedRule string
+	}{
+		{
+			desc:         "at least",
+			ruleString:   "ATLEAST(2, age > 30, dept == 'A', grade == 1)",
+			expectedRule: "(age > 30 AND dept == 'A') OR (age > 30 AND grade == 1) OR (dept == 'A' AND grade == 1)",
+		},
+		{
+			desc:         "xor",
+			ruleString:   "XOR(age > 30, dept == 'A')",
+			expectedRule: "(age > 30 AND NOT (dept == 'A')) OR (NOT (age > 30) AND dept == 'A')",
+		},
+		{
+			desc:         "majority",
+			ruleString:   "MAJORITY(age > 30, dept == 'A')",
+			expectedRule: "age > 30 AND dept == 'A'",
+		},
+		{
+			desc:         "at least none",
+			ast:          &bools.AtLeastExpr{K: 0, Children: []parse.AST{grammar.MustParse("age > 30")}},
+			expectedRule: "TRUE",
+		},
+		{
+			desc:         "exactly more than the children",
+			ast:          &bools.ExactlyExpr{K: 3, Children: []parse.AST{grammar.MustParse("age > 30"), grammar.MustParse("dept == 'A'")}},
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:       "priority",
+			ruleString: "PRIORITY(grade == 2, age > 30 AND dept == 'A')",
+			expectedRule: "(grade == 2 AND (grade == '' OR grade != '')) OR " +
+				"(NOT (grade == '' OR grade != '') AND (age > 30 AND dept == 'A') AND " +
+				"(age == '' OR age != '') AND (dept == '' OR dept != ''))",
+		},
+		{
+			desc:         "priority ends at a child without attributes",
+			ruleString:   "PRIORITY(grade == 2, TRUE, age > 30)",
+			expectedRule: "(grade == 2 AND (grade == '' OR grade != '')) OR (NOT (grade == '' OR grade != '') AND TRUE)",
+		},
+		{
+			desc:         "nested",
+			ruleString:   "NOT (XOR(PRIORITY(age > 30, dept == 'B'), grade == 1))",
+			expectedRule: "",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := tt.ast
+			if ast == nil {
+				ast = grammar.MustParse(tt.ruleString)
+			}
+			original := fmt.Sprint(ast)
+
+			expanded, err := Expand(ast)
+			assert.Nil(t, err)
+			if tt.expectedRule != "" {
+				assert.Equal(t, tt.expectedRule, fmt.Sprint(expanded))
+			}
+			assert.Equal(t, original, fmt.Sprint(ast), "the input must not be modified")
+
+			before, err := compile.Compile(ast)
+			assert.Nil(t, err)
+			after, err := compile.Compile(expanded)
+			assert.Nil(t, err)
+			for _, record := range records {
+				assert.Equal(t, before(record), after(record), "record %v", record)
+			}
+		})
+	}
+}
+
+func TestExpandTooLarge(t *testing.T) {
+	var children []string
+	for i := 0; i < 20; i++ {
+		children = append(children, fmt.Sprintf("a%d == 1", i))
+	}
+	_, err := Expand(grammar.MustParse("MAJORITY(" + strings.Join(children, ", ") + ")"))
+	assert.True(t, errors.Is(err, ErrTooLarge))
+}
@@ -0,0 +1,413 @@
+// Package jsonlogic converts between JSONLogic documents (https://jsonlogic.com) and rule ASTs built from the bools and
+// comp grammars.
+package jsonlogic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/expand"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/parse/grammar"
+)
+
+// ErrUnsupported is returned for constructs which exist on one side of the conversion but cannot be expressed on the
+// other.
+var ErrUnsupported = errors.New("unsupported construct")
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+var compOps = map[string]comp.Op{
+	"==":  comp.OpEqual,
+	"===": comp.OpEqual,
+	"!=":  comp.OpNotEqual,
+	"!==": comp.OpNotEqual,
+	">":   comp.OpGreater,
+	">=":  comp.OpGreaterOrEqual,
+	"<":   comp.OpLess,
+	"<=":  comp.OpLessOrEqual,
+}
+
+// flipped maps each comparison operator to the one which gives the same result with its operands swapped.
+var flipped = map[comp.Op]comp.Op{
+	comp.OpEqual:          comp.OpEqual,
+	comp.OpNotEqual:       comp.OpNotEqual,
+	comp.OpGreater:        comp.OpLess,
+	comp.OpGreaterOrEqual: comp.OpLessOrEqual,
+	comp.OpLess:           comp.OpGreater,
+	comp.OpLessOrEqual:    comp.OpGreaterOrEqual,
+}
+
+// Encode converts a parsed rule into a JSONLogic document made of maps, slices and scalars, ready to be passed to
+// json.Marshal. Unquoted numeric literals are encoded as JSON numbers, unquoted true and false as JSON booleans, and
+// every other literal as a string. ATLEAST and EXACTLY are expanded into "and" and "or", and PRIORITY becomes an "if"
+// which checks that the attributes of each child are not null.
+func Encode(ast parse.AST) (any, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		var name string
+		switch ast.Op {
+		case bools.OpAnd:
+			name = "and"
+		case bools.OpOr:
+			name = "or"
+		default:
+			return nil, fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		var args []any
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			arg, err := Encode(operand)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return map[string]any{name: args}, nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		arg, err := Encode(ast.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"!": []any{arg}}, nil
+	case *bools.AtLeastExpr, *bools.ExactlyExpr:
+		expanded, err := expand.Expand(ast)
+		if err != nil {
+			return nil, err
+		}
+		return Encode(expanded)
+	case *bools.PriorityExpr:
+		return encodePriority(ast)
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return nil, fmt.Errorf("%w: comparison '%v' must have an attribute on the left and a literal on the right", ErrUnsupported, ast)
+		}
+		return map[string]any{pred.Op.String(): []any{map[string]any{"var": pred.Attr}, encodeLiteral(pred)}}, nil
+	case parse.Unparsed:
+		return nil, fmt.Errorf("%w: '%v' is not a comparison", ErrUnsupported, ast)
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func encodePriority(ast *bools.PriorityExpr) (any, error) {
+	var args []any
+	for _, child := range ast.Children {
+		arg, err := Encode(child)
+		if err != nil {
+			return nil, err
+		}
+		attrs := comp.Attributes(child)
+		if len(attrs) == 0 {
+			// a child comparing no attribute always has an opinion, so it ends the chain
+			return map[string]any{"if": append(args, arg)}, nil
+		}
+		args = append(args, encodePresent(attrs), arg)
+	}
+	return map[string]any{"if": append(args, false)}, nil
+}
+
+// encodePresent checks that a record has all the provided attributes. Unlike "missing", which also reports attributes
+// set to an empty string, it compares each attribute with null, so that an empty value counts as present as it does for
+// the rule engine.
+func encodePresent(attrs []string) any {
+	var checks []any
+	for _, attr := range attrs {
+		checks = append(checks, map[string]any{"!==": []any{map[string]any{"var": attr}, nil}})
+	}
+	if len(checks) == 1 {
+		return checks[0]
+	}
+	return map[string]any{"and": checks}
+}
+
+func encodeLiteral(pred comp.Predicate) any {
+	if pred.Quoted {
+		return pred.Value
+	}
+	switch {
+	case pred.Value == "true":
+		return true
+	case pred.Value == "false":
+		return false
+	case jsonNumber.MatchString(pred.Value):
+		return json.Number(pred.Value)
+	}
+	return pred.Value
+}
+
+// Parse decodes a JSON encoded JSONLogic document into a rule AST. Numbers keep the exact text they were written with.
+func Parse(data []byte) (parse.AST, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", parse.ErrParse, err)
+	}
+	return Decode(doc)
+}
+
+// Decode converts a JSONLogic document, as produced by json.Unmarshal, into a rule AST. Besides the operators produced
+// by Encode it accepts "===" and "!==", "!!", three-argument "<" and "<=" between checks, comparisons with the
+// attribute on the right, and "in" against a list of literals, all of which are rewritten into the engine's grammar.
+// "if" is only accepted in the form Encode gives PRIORITY.
+// Comparisons whose rule text would not be read back as the same comparison are rejected with ErrUnsupported.
+func Decode(doc any) (parse.AST, error) {
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: constant rule %v; rules must be operations", ErrUnsupported, doc)
+	}
+	if len(obj) != 1 {
+		return nil, fmt.Errorf("%w: operations must have exactly one key, found %d", parse.ErrParse, len(obj))
+	}
+	for name, raw := range obj {
+		args := arguments(raw)
+		switch name {
+		case "and", "or":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%w: '%s' needs at least one argument", parse.ErrParse, name)
+			}
+			operands := make([]parse.AST, 0, len(args))
+			for _, arg := range args {
+				operand, err := Decode(arg)
+				if err != nil {
+					return nil, err
+				}
+				operands = append(operands, operand)
+			}
+			op := bools.OpAnd
+			if name == "or" {
+				op = bools.OpOr
+			}
+			return bools.Chain(op, operands...), nil
+		case "!", "!!":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%w: '%s' takes exactly one argument, found %d", parse.ErrParse, name, len(args))
+			}
+			expr, err := Decode(args[0])
+			if err != nil {
+				return nil, err
+			}
+			expr = &bools.UnaryExpr{Op: bools.OpNot, Expr: expr}
+			if name == "!!" {
+				expr = &bools.UnaryExpr{Op: bools.OpNot, Expr: expr}
+			}
+			return expr, nil
+		case "in":
+			return decodeIn(args)
+		case "if":
+			return decodePriority(args)
+		}
+		op, ok := compOps[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: operator '%s'", ErrUnsupported, name)
+		}
+		switch {
+		case len(args) == 2:
+			return decodeComparison(op, args[0], args[1])
+		case len(args) == 3 && (op == comp.OpLess || op == comp.OpLessOrEqual):
+			lower, err := decodeComparison(op, args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			upper, err := decodeComparison(op, args[1], args[2])
+			if err != nil {
+				return nil, err
+			}
+			return bools.Chain(bools.OpAnd, lower, upper), nil
+		}
+		return nil, fmt.Errorf("%w: '%s' with %d arguments", ErrUnsupported, name, len(args))
+	}
+	// unreachable
+	return nil, fmt.Errorf("%w: empty operation", parse.ErrParse)
+}
+
+// arguments returns the argument list of an operation; JSONLogic allows a single argument to be passed without the
+// surrounding array.
+func arguments(raw any) []any {
+	if args, ok := raw.([]any); ok {
+		return args
+	}
+	return []any{raw}
+}
+
+func decodeComparison(op comp.Op, lhs, rhs any) (parse.AST, error) {
+	lAttr, lIsVar, err := decodeVar(lhs)
+	if err != nil {
+		return nil, err
+	}
+	rAttr, rIsVar, err := decodeVar(rhs)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case lIsVar && rIsVar:
+		return nil, fmt.Errorf("%w: comparison between attributes '%s' and '%s'", ErrUnsupported, lAttr, rAttr)
+	case !lIsVar && !rIsVar:
+		return nil, fmt.Errorf("%w: comparison between literals %v and %v", ErrUnsupported, lhs, rhs)
+	case rIsVar:
+		// the engine expects the attribute on the left-hand side
+		lAttr, rhs, op = rAttr, lhs, flipped[op]
+	}
+	pred, err := decodeLiteral(rhs)
+	if err != nil {
+		return nil, err
+	}
+	pred.Attr, pred.Op = lAttr, op
+	return checkPredicate(pred)
+}
+
+func decodeIn(args []any) (parse.AST, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%w: 'in' takes exactly two arguments, found %d", parse.ErrParse, len(args))
+	}
+	attr, isVar, err := decodeVar(args[0])
+	if err != nil {
+		return nil, err
+	}
+	values, isList := args[1].([]any)
+	if !isVar || !isList {
+		return nil, fmt.Errorf("%w: 'in' is only supported for an attribute and a list of literals", ErrUnsupported)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: 'in' with an empty list", ErrUnsupported)
+	}
+	operands := make([]parse.AST, 0, len(values))
+	for _, value := range values {
+		pred, err := decodeLiteral(value)
+		if err != nil {
+			return nil, err
+		}
+		pred.Attr, pred.Op = attr, comp.OpEqual
+		operand, err := checkPredicate(pred)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	return bools.Chain(bools.OpOr, operands...), nil
+}
+
+// decodePriority decodes the "if" produced by Encode for a PRIORITY rule: pairs of a check that the attributes of a
+// child are present and the child, followed by false.
+func decodePriority(args []any) (parse.AST, error) {
+	if len(args) < 3 || len(args)%2 == 0 || args[len(args)-1] != false {
+		return nil, fmt.Errorf("%w: 'if' other than the form of PRIORITY", ErrUnsupported)
+	}
+	var children []parse.AST
+	for idx := 0; idx < len(args)-1; idx += 2 {
+		child, err := Decode(args[idx+1])
+		if err != nil {
+			return nil, err
+		}
+		attrs, err := decodePresent(args[idx])
+		if err != nil {
+			return nil, err
+		}
+		if fmt.Sprint(attrs) != fmt.Sprint(comp.Attributes(child)) {
+			return nil, fmt.Errorf("%w: 'if' condition checks %v rather than the attributes of '%v'", ErrUnsupported, attrs, child)
+		}
+		children = append(children, child)
+	}
+	return &bools.PriorityExpr{Children: children}, nil
+}
+
+// decodePresent returns the attributes checked by a condition produced by encodePresent.
+func decodePresent(cond any) ([]string, error) {
+	checks := []any{cond}
+	if obj, ok := cond.(map[string]any); ok && len(obj) == 1 && obj["and"] != nil {
+		checks = arguments(obj["and"])
+	}
+	var attrs []string
+	for _, check := range checks {
+		obj, ok := check.(map[string]any)
+		if !ok || len(obj) != 1 {
+			return nil, fmt.Errorf("%w: 'if' condition %v", ErrUnsupported, check)
+		}
+		args := arguments(obj["!=="])
+		if len(args) != 2 || args[1] != nil {
+			return nil, fmt.Errorf("%w: 'if' condition %v", ErrUnsupported, check)
+		}
+		attr, isVar, err := decodeVar(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if !isVar {
+			return nil, fmt.Errorf("%w: 'if' condition %v", ErrUnsupported, check)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// decodeVar returns the attribute name if arg is a "var" operation. The second result is false for any other value.
+func decodeVar(arg any) (string, bool, error) {
+	obj, ok := arg.(map[string]any)
+	if !ok {
+		return "", false, nil
+	}
+	raw, ok := obj["var"]
+	if !ok || len(obj) != 1 {
+		for name := range obj {
+			return "", false, fmt.Errorf("%w: operator '%s' used as an operand", ErrUnsupported, name)
+		}
+		return "", false, fmt.Errorf("%w: empty operation used as an operand", parse.ErrParse)
+	}
+	args := arguments(raw)
+	if len(args) != 1 {
+		return "", false, fmt.Errorf("%w: 'var' with a default value", ErrUnsupported)
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return "", false, fmt.Errorf("%w: 'var' with non-string name %v", ErrUnsupported, args[0])
+	}
+	if name == "" || strings.ContainsAny(name, "'()=!<> \t\r\n") {
+		return "", false, fmt.Errorf("%w: attribute name '%s'", ErrUnsupported, name)
+	}
+	return name, true, nil
+}
+
+func decodeLiteral(arg any) (comp.Predicate, error) {
+	switch arg := arg.(type) {
+	case string:
+		if strings.Contains(arg, "'") {
+			return comp.Predicate{}, fmt.Errorf("%w: string literal containing a quote: %s", ErrUnsupported, arg)
+		}
+		return comp.Predicate{Value: arg, Quoted: true}, nil
+	case json.Number:
+		return comp.Predicate{Value: arg.String()}, nil
+	case float64:
+		return comp.Predicate{Value: strconv.FormatFloat(arg, 'f', -1, 64)}, nil
+	case int:
+		return comp.Predicate{Value: strconv.Itoa(arg)}, nil
+	case bool:
+		return comp.Predicate{Value: strconv.FormatBool(arg)}, nil
+	case nil:
+		return comp.Predicate{}, fmt.Errorf("%w: null literal", ErrUnsupported)
+	}
+	return comp.Predicate{}, fmt.Errorf("%w: literal %v", ErrUnsupported, arg)
+}
+
+// checkPredicate returns the comparison node for a decoded predicate, provided its rule text parses back to the same
+// predicate. Literals containing a keyword as a word of its own, such as 'x == y', or runs of whitespace would
+// otherwise produce rules which fail to parse or compare a different value.
+func checkPredicate(pred comp.Predicate) (parse.AST, error) {
+	text := pred.String()
+	ast, err := grammar.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: comparison '%s' cannot be read back: %v", ErrUnsupported, text, err)
+	}
+	if parsed, ok := comp.AsPredicate(ast); !ok || parsed != pred {
+		return nil, fmt.Errorf("%w: comparison of '%s' with %q cannot be written as a rule", ErrUnsupported, pred.Attr, pred.Value)
+	}
+	return ast, nil
+}
@@ -0,0 +1,219 @@
+package jsonlogic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		ruleString   string
+		expectedJSON string
+	}{
+		{
+			desc:         "equal",
+			ruleString:   "department == 'Marketing'",
+			expectedJSON: `{"==":[{"var":"department"},"Marketing"]}`,
+		},
+		{
+			desc:         "not equal",
+			ruleString:   "department != 'Sales'",
+			expectedJSON: `{"!=":[{"var":"department"},"Sales"]}`,
+		},
+		{
+			desc:         "greater",
+			ruleString:   "age > 30",
+			expectedJSON: `{">":[{"var":"age"},30]}`,
+		},
+		{
+			desc:         "greater or equal",
+			ruleString:   "salary >= 20000.5",
+			expectedJSON: `{">=":[{"var":"salary"},20000.5]}`,
+		},
+		{
+			desc:         "less",
+			ruleString:   "experience < 5",
+			expectedJSON: `{"<":[{"var":"experience"},5]}`,
+		},
+		{
+			desc:         "less or equal",
+			ruleString:   "experience <= -1",
+			expectedJSON: `{"<=":[{"var":"experience"},-1]}`,
+		},
+		{
+			desc:         "and chain",
+			ruleString:   "age > 30 AND department == 'Marketing' AND salary > 20000",
+			expectedJSON: `{"and":[{">":[{"var":"age"},30]},{"==":[{"var":"department"},"Marketing"]},{">":[{"var":"salary"},20000]}]}`,
+		},
+		{
+			desc:         "or chain",
+			ruleString:   "salary > 20000 OR experience > 5",
+			expectedJSON: `{"or":[{">":[{"var":"salary"},20000]},{">":[{"var":"experience"},5]}]}`,
+		},
+		{
+			desc:         "not",
+			ruleString:   "NOT (department == 'Sales')",
+			expectedJSON: `{"!":[{"==":[{"var":"department"},"Sales"]}]}`,
+		},
+		{
+			desc:         "nested",
+			ruleString:   "(age > 30 AND department == 'Marketing') AND (salary > 20000 OR NOT (experience < 5))",
+			expectedJSON: `{"and":[{">":[{"var":"age"},30]},{"==":[{"var":"department"},"Marketing"]},{"or":[{">":[{"var":"salary"},20000]},{"!":[{"<":[{"var":"experience"},5]}]}]}]}`,
+		},
+		{
+			desc:         "string with spaces and boolean literal",
+			ruleString:   "department == 'Human Resources' AND active == true",
+			expectedJSON: `{"and":[{"==":[{"var":"department"},"Human Resources"]},{"==":[{"var":"active"},true]}]}`,
+		},
+		{
+			desc:         "expanded xor",
+			ruleString:   "XOR(age > 30, department == 'Sales')",
+			expectedJSON: `{"or":[{"and":[{">":[{"var":"age"},30]},{"!":[{"==":[{"var":"department"},"Sales"]}]}]},{"and":[{"!":[{">":[{"var":"age"},30]}]},{"==":[{"var":"department"},"Sales"]}]}]}`,
+		},
+		{
+			desc:       "priority",
+			ruleString: "PRIORITY(override == 'allow', age > 30 AND salary > 20000)",
+			expectedJSON: `{"if":[
+				{"!==":[{"var":"override"},null]},{"==":[{"var":"override"},"allow"]},
+				{"and":[{"!==":[{"var":"age"},null]},{"!==":[{"var":"salary"},null]}]},{"and":[{">":[{"var":"age"},30]},{">":[{"var":"salary"},20000]}]},
+				false
+			]}`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := grammar.MustParse(tt.ruleString)
+
+			doc, err := Encode(ast)
+			assert.Nil(t, err)
+			data, err := json.Marshal(doc)
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.expectedJSON, string(data))
+
+			// nested chains of one operator are flattened, so compare the documents rather than the trees
+			decoded, err := Parse(data)
+			assert.Nil(t, err)
+			redoc, err := Encode(decoded)
+			assert.Nil(t, err)
+			redata, err := json.Marshal(redoc)
+			assert.Nil(t, err)
+			assert.JSONEq(t, string(data), string(redata))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		jsonLogic    string
+		expectedRule string
+	}{
+		{
+			desc:         "strict equality",
+			jsonLogic:    `{"===":[{"var":"department"},"Sales"]}`,
+			expectedRule: "department == 'Sales'",
+		},
+		{
+			desc:         "attribute on the right",
+			jsonLogic:    `{"<":[30,{"var":"age"}]}`,
+			expectedRule: "age > 30",
+		},
+		{
+			desc:         "between",
+			jsonLogic:    `{"<=":[18,{"var":"age"},65]}`,
+			expectedRule: "age >= 18 AND age <= 65",
+		},
+		{
+			desc:         "in",
+			jsonLogic:    `{"in":[{"var":"department"},["Sales","Marketing"]]}`,
+			expectedRule: "department == 'Sales' OR department == 'Marketing'",
+		},
+		{
+			desc:         "double negation with unwrapped argument",
+			jsonLogic:    `{"!!":{">":[{"var":["age"]},30]}}`,
+			expectedRule: "NOT (NOT (age > 30))",
+		},
+		{
+			desc:         "single operand and",
+			jsonLogic:    `{"and":[{"!=":[{"var":"age"},"30"]}]}`,
+			expectedRule: "age != '30'",
+		},
+		{
+			desc:         "keyword in literal",
+			jsonLogic:    `{"==":[{"var":"dept"},"NOTE"]}`,
+			expectedRule: "dept == 'NOTE'",
+		},
+		{
+			desc:         "list keyword and parentheses in literal",
+			jsonLogic:    `{"==":[{"var":"a"},"BAND(1)"]}`,
+			expectedRule: "a == 'BAND(1)'",
+		},
+		{
+			desc:         "unbalanced parenthesis in literal",
+			jsonLogic:    `{"==":[{"var":"a"},"(x"]}`,
+			expectedRule: "a == '(x'",
+		},
+		{
+			desc:         "keyword in listed literal",
+			jsonLogic:    `{"in":[{"var":"dept"},["Sales","NOTE"]]}`,
+			expectedRule: "dept == 'Sales' OR dept == 'NOTE'",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast, err := Parse([]byte(tt.jsonLogic))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(ast))
+		})
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+
+	testCases := []struct {
+		desc      string
+		jsonLogic string
+	}{
+		{desc: "constant", jsonLogic: `true`},
+		{desc: "arithmetic", jsonLogic: `{">":[{"+":[{"var":"a"},1]},3]}`},
+		{desc: "if", jsonLogic: `{"if":[{"var":"a"},1,2]}`},
+		{desc: "if without false", jsonLogic: `{"if":[{"!==":[{"var":"a"},null]},{"==":[{"var":"a"},"x"]},true]}`},
+		{desc: "if with missing", jsonLogic: `{"if":[{"!":[{"missing":["a"]}]},{"==":[{"var":"a"},"x"]},false]}`},
+		{desc: "if checking other attributes", jsonLogic: `{"if":[{"!==":[{"var":"b"},null]},{"==":[{"var":"a"},"x"]},false]}`},
+		{desc: "bare var", jsonLogic: `{"!!":{"var":"a"}}`},
+		{desc: "two attributes", jsonLogic: `{"==":[{"var":"a"},{"var":"b"}]}`},
+		{desc: "two literals", jsonLogic: `{"==":[1,1]}`},
+		{desc: "var default", jsonLogic: `{"==":[{"var":["a","x"]},"y"]}`},
+		{desc: "null literal", jsonLogic: `{"==":[{"var":"a"},null]}`},
+		{desc: "quote in literal", jsonLogic: `{"==":[{"var":"a"},"O'Brien"]}`},
+		{desc: "substring in", jsonLogic: `{"in":[{"var":"a"},"abc"]}`},
+		{desc: "empty in", jsonLogic: `{"in":[{"var":"a"},[]]}`},
+		{desc: "operator in literal", jsonLogic: `{"==":[{"var":"a"},"x == y"]}`},
+		{desc: "whitespace run in literal", jsonLogic: `{"==":[{"var":"a"},"a  b"]}`},
+		{desc: "tab in literal", jsonLogic: `{"!=":[{"var":"a"},"a\tb"]}`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := Parse([]byte(tt.jsonLogic))
+			assert.True(t, errors.Is(err, ErrUnsupported), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	_, err := Encode(parse.Unparsed{Contents: []string{"active"}})
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
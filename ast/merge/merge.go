@@ -0,0 +1,129 @@
+// Package merge combines several parsed rules into one.
+package merge
+
+import (
+	"fmt"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/simplify"
+)
+
+// Merge joins the rules with op, which must be AND or OR, and simplifies the result. Clauses shared by several of the
+// merged rules are factored out, so merging age > 30 AND dept == 'A' with age > 30 AND dept == 'B' using OR gives
+// age > 30 AND (dept == 'A' OR dept == 'B'). The provided ASTs are not modified.
+func Merge(op bools.Op, rules ...parse.AST) (parse.AST, error) {
+	if op != bools.OpAnd && op != bools.OpOr {
+		return nil, fmt.Errorf("%w: cannot merge rules with %v", parse.ErrConfig, op)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%w: no rules to merge", parse.ErrConfig)
+	}
+
+	merged, err := simplify.Simplify(bools.Chain(op, rules...))
+	if err != nil {
+		return nil, err
+	}
+	return simplify.Simplify(factor(merged))
+}
+
+// factor returns a copy of the rule in which clauses shared by operands of the same chain are factored out.
+func factor(ast parse.AST) parse.AST {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		var operands []parse.AST
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			operands = append(operands, factor(operand))
+		}
+		return factorChain(ast.Op, operands)
+	case *bools.UnaryExpr:
+		return &bools.UnaryExpr{Op: ast.Op, Expr: factor(ast.Expr)}
+	case *bools.AtLeastExpr:
+		return &bools.AtLeastExpr{K: ast.K, Children: factorAll(ast.Children)}
+	case *bools.ExactlyExpr:
+		return &bools.ExactlyExpr{K: ast.K, Children: factorAll(ast.Children)}
+	}
+	return ast
+}
+
+func factorAll(children []parse.AST) []parse.AST {
+	var result []parse.AST
+	for _, child := range children {
+		result = append(result, factor(child))
+	}
+	return result
+}
+
+// factorChain joins the operands with op, after repeatedly grouping the operands which share the clause found in the
+// most operands: (c AND x) OR (c AND y) becomes c AND (x OR y), and (c OR x) AND (c OR y) becomes c OR (x AND y).
+func factorChain(op bools.Op, operands []parse.AST) parse.AST {
+	dual := bools.OpAnd
+	if op == bools.OpAnd {
+		dual = bools.OpOr
+	}
+
+	clauses := make([][]parse.AST, len(operands))
+	counts := map[string]int{}
+	var order []string
+	for idx, operand := range operands {
+		clauses[idx] = bools.Flatten(operand, dual)
+		seen := map[string]bool{}
+		for _, clause := range clauses[idx] {
+			key := fmt.Sprint(clause)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		}
+	}
+
+	shared := ""
+	for _, key := range order {
+		if counts[key] >= 2 && (shared == "" || counts[key] > counts[shared]) {
+			shared = key
+		}
+	}
+	if shared == "" {
+		return bools.Chain(op, operands...)
+	}
+
+	// group the operands containing the shared clause in place of the first of them
+	var result, rests []parse.AST
+	var common parse.AST
+	grouped, absorbed := -1, false
+	for idx, operand := range operands {
+		var rest []parse.AST
+		found := false
+		for _, clause := range clauses[idx] {
+			if !found && fmt.Sprint(clause) == shared {
+				common, found = clause, true
+			} else {
+				rest = append(rest, clause)
+			}
+		}
+		if !found {
+			result = append(result, operand)
+			continue
+		}
+		if grouped < 0 {
+			grouped = len(result)
+			result = append(result, nil)
+		}
+		if len(rest) == 0 {
+			// the shared clause on its own absorbs the other operands containing it
+			absorbed = true
+		}
+		rests = append(rests, bools.Chain(dual, rest...))
+	}
+
+	if absorbed {
+		result[grouped] = common
+	} else {
+		result[grouped] = bools.Chain(dual, common, factorChain(op, rests))
+	}
+	return factorChain(op, result)
+}
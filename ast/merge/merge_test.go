@@ -0,0 +1,140 @@
+package merge
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"RuleEngineAST/ast/equiv"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		rules        []string
+		op           bools.Op
+		expectedRule string
+	}{
+		{
+			desc:         "two rules",
+			rules:        []string{"age > 30", "department == 'ENGINEERING'"},
+			op:           bools.OpAnd,
+			expectedRule: "age > 30 AND department == 'ENGINEERING'",
+		},
+		{
+			desc:         "single rule",
+			rules:        []string{"(age > 30)"},
+			op:           bools.OpOr,
+			expectedRule: "age > 30",
+		},
+		{
+			desc:         "duplicate clauses",
+			rules:        []string{"age > 30 AND x == 1", "(age > 30)", "x == 1 AND salary > 20000"},
+			op:           bools.OpAnd,
+			expectedRule: "age > 30 AND x == 1 AND salary > 20000",
+		},
+		{
+			desc:         "shared clause factored out of a disjunction",
+			rules:        []string{"age > 30 AND dept == 'A'", "age > 30 AND dept == 'B'", "salary > 20000"},
+			op:           bools.OpOr,
+			expectedRule: "(age > 30 AND (dept == 'A' OR dept == 'B')) OR salary > 20000",
+		},
+		{
+			desc:         "shared clauses factored out of a conjunction",
+			rules:        []string{"active OR dept == 'A' OR age > 30", "dept == 'B' OR age > 30 OR active"},
+			op:           bools.OpAnd,
+			expectedRule: "TRUE",
+		},
+		{
+			desc:         "several shared clauses",
+			rules:        []string{"dept == 'A' OR age > 30 OR x == 1", "dept == 'B' OR age > 30 OR x == 1", "y == 2"},
+			op:           bools.OpAnd,
+			expectedRule: "(age > 30 OR x == 1) AND y == 2",
+		},
+		{
+			desc:         "absorbed rule",
+			rules:        []string{"age > 30 AND dept == 'A'", "age > 30", "age > 30 AND dept == 'B'"},
+			op:           bools.OpOr,
+			expectedRule: "age > 30",
+		},
+		{
+			desc:         "bounds are merged",
+			rules:        []string{"age > 30 AND dept == 'A'", "age > 40"},
+			op:           bools.OpAnd,
+			expectedRule: "dept == 'A' AND age > 40",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			var rules []parse.AST
+			for _, rule := range tt.rules {
+				rules = append(rules, grammar.MustParse(rule))
+			}
+
+			merged, err := Merge(tt.op, rules...)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(merged))
+
+			result, err := equiv.Compare(grammar.MustParse(fmt.Sprint(merged)), bools.Chain(tt.op, rules...))
+			assert.Nil(t, err)
+			assert.Equal(t, equiv.Equivalent, result.Relation)
+		})
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	_, err := Merge(bools.OpAnd)
+	assert.ErrorIs(t, err, parse.ErrConfig)
+
+	_, err = Merge(bools.OpNot, grammar.MustParse("age > 30"))
+	assert.ErrorIs(t, err, parse.ErrConfig)
+}
+
+// TestMergeRandomRules checks that merging random rules gives a rule matching the same records as joining them.
+func TestMergeRandomRules(t *testing.T) {
+
+	atoms := []string{
+		"age > 30", "age >= 30", "age < 30", "age == 25", "dept == 'A'", "dept == 'B'", "dept != 'A'", "flag",
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	var build func(depth int) string
+	build = func(depth int) string {
+		switch n := rnd.Intn(6); {
+		case depth == 0 || n < 2:
+			return atoms[rnd.Intn(len(atoms))]
+		case n == 2:
+			return "NOT (" + build(depth-1) + ")"
+		case n == 3:
+			return "(" + build(depth-1) + ") OR (" + build(depth-1) + ")"
+		default:
+			return "(" + build(depth-1) + ") AND (" + build(depth-1) + ")"
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+		op := bools.OpAnd
+		if rnd.Intn(2) == 0 {
+			op = bools.OpOr
+		}
+		var rules []parse.AST
+		for n := 1 + rnd.Intn(4); n > 0; n-- {
+			rules = append(rules, grammar.MustParse(build(2)))
+		}
+
+		merged, err := Merge(op, rules...)
+		assert.Nil(t, err)
+		result, err := equiv.Compare(grammar.MustParse(fmt.Sprint(merged)), bools.Chain(op, rules...))
+		assert.Nil(t, err)
+		if !assert.Equal(t, equiv.Equivalent, result.Relation, "merging %v with %v gave %q", rules, op, merged) {
+			return
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package mongo translates rule ASTs into MongoDB query filter documents.
+package mongo
+
+import (
+	"errors"
+	"fmt"
+
+	"RuleEngineAST/ast/expand"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrUnsupported is returned for rule constructs which have no MongoDB equivalent.
+var ErrUnsupported = errors.New("unsupported construct")
+
+var operators = map[comp.Op]string{
+	comp.OpEqual:          "$eq",
+	comp.OpNotEqual:       "$ne",
+	comp.OpGreater:        "$gt",
+	comp.OpGreaterOrEqual: "$gte",
+	comp.OpLess:           "$lt",
+	comp.OpLessOrEqual:    "$lte",
+}
+
+// Filter translates a parsed rule into a MongoDB filter document made of maps and slices, ready to be marshalled to
+// JSON or BSON. A chain of OR-ed equality checks on one attribute becomes a single $in, and since the engine never
+// matches a comparison on a missing attribute, != also requires the field to exist. ATLEAST and EXACTLY have no filter
+// operator and are expanded into $and and $or, and PRIORITY checks the attributes of its children with $exists.
+func Filter(ast parse.AST) (map[string]any, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		var name string
+		switch ast.Op {
+		case bools.OpAnd:
+			name = "$and"
+		case bools.OpOr:
+			name = "$or"
+		default:
+			return nil, fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		operands := bools.Flatten(ast, ast.Op)
+		if ast.Op == bools.OpOr {
+			if attr, values, ok := inList(operands); ok {
+				return map[string]any{attr: map[string]any{"$in": values}}, nil
+			}
+		}
+		var filters []any
+		for _, operand := range operands {
+			filter, err := Filter(operand)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+		return map[string]any{name: filters}, nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		filter, err := Filter(ast.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"$nor": []any{filter}}, nil
+	case *bools.PriorityExpr:
+		return priorityFilter(ast)
+	case *bools.AtLeastExpr, *bools.ExactlyExpr:
+		expanded, err := expand.Expand(ast)
+		if err != nil {
+			return nil, err
+		}
+		return Filter(expanded)
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return nil, fmt.Errorf("%w: comparison '%v' must have an attribute on the left and a literal on the right", ErrUnsupported, ast)
+		}
+		return predicateFilter(pred)
+	case parse.Unparsed:
+		return nil, fmt.Errorf("%w: '%v' is not a comparison", ErrUnsupported, ast)
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+// priorityFilter matches the records matched by a child when they have all its attributes and miss one of the
+// attributes of each earlier child.
+func priorityFilter(ast *bools.PriorityExpr) (map[string]any, error) {
+	var clauses, earlier []any
+	for _, child := range ast.Children {
+		filter, err := Filter(child)
+		if err != nil {
+			return nil, err
+		}
+		attrs := comp.Attributes(child)
+		clause := append(append([]any{}, earlier...), filter)
+		if len(attrs) == 0 {
+			clauses = append(clauses, map[string]any{"$and": clause})
+			break
+		}
+		clauses = append(clauses, map[string]any{"$and": append(clause, exists(attrs))})
+		earlier = append(earlier, map[string]any{"$nor": []any{exists(attrs)}})
+	}
+	return map[string]any{"$or": clauses}, nil
+}
+
+func exists(attrs []string) map[string]any {
+	var filters []any
+	for _, attr := range attrs {
+		filters = append(filters, map[string]any{attr: map[string]any{"$exists": true}})
+	}
+	return map[string]any{"$and": filters}
+}
+
+func predicateFilter(pred comp.Predicate) (map[string]any, error) {
+	cond := map[string]any{}
+	switch pred.Op {
+	case comp.OpEqual:
+		cond["$eq"] = pred.Literal()
+	case comp.OpNotEqual:
+		cond["$ne"] = pred.Literal()
+		cond["$exists"] = true
+	case comp.OpGreater, comp.OpGreaterOrEqual, comp.OpLess, comp.OpLessOrEqual:
+		n, ok := pred.NumericLiteral()
+		if !ok {
+			return nil, fmt.Errorf("%w: ordinal comparison '%v' against non-numeric literal", ErrUnsupported, pred)
+		}
+		cond[operators[pred.Op]] = n
+	default:
+		return nil, fmt.Errorf("%w: comparison operator %v", ErrUnsupported, pred.Op)
+	}
+	return map[string]any{pred.Attr: cond}, nil
+}
+
+// inList reports whether the operands are all equality checks on one attribute, returning the attribute and values.
+func inList(operands []parse.AST) (string, []any, bool) {
+	var attr string
+	var values []any
+	for _, operand := range operands {
+		pred, ok := comp.AsPredicate(operand)
+		if !ok || pred.Op != comp.OpEqual || (attr != "" && pred.Attr != attr) {
+			return "", nil, false
+		}
+		attr = pred.Attr
+		values = append(values, pred.Literal())
+	}
+	return attr, values, true
+}
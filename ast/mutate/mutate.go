@@ -0,0 +1,262 @@
+// Package mutate judges the test cases of a rule by mutation testing.
+//
+// Mutants are copies of the rule with one small fault introduced: a comparison operator moved across its boundary or
+// negated, AND and OR swapped, a clause dropped, a negation removed, a numeric literal nudged by one, the threshold of
+// a count changed by one or two children of a PRIORITY rule swapped. A mutant is killed when one of the test cases
+// which pass on the rule fails on the mutant. Surviving mutants point at behaviour the test cases do not pin down,
+// although some may be equivalent to the rule and impossible to kill.
+package mutate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/tree"
+)
+
+// Kind is the kind of fault a mutant introduces.
+type Kind string
+
+const (
+	Boundary   Kind = "boundary"   // Boundary moves a comparison operator across its boundary, as in > to >=.
+	Negate     Kind = "negate"     // Negate replaces a comparison operator with its negation, as in > to <= or == to !=.
+	Connective Kind = "connective" // Connective swaps AND and OR for a whole chain.
+	Drop       Kind = "drop"       // Drop removes a clause from an AND or OR chain.
+	Unnegate   Kind = "unnegate"   // Unnegate removes a NOT.
+	Literal    Kind = "literal"    // Literal adds or subtracts one from a numeric literal.
+	Threshold  Kind = "threshold"  // Threshold adds or subtracts one from the count of an ATLEAST or EXACTLY rule.
+	Order      Kind = "order"      // Order swaps two neighbouring children of a PRIORITY rule.
+)
+
+// Mutant is a copy of a rule with one fault. Path locates the mutated node as in the tree rendering of the rule, with
+// AND and OR chains flattened; the root is /.
+type Mutant struct {
+	Kind        Kind      `json:"kind"`
+	Path        string    `json:"path"`
+	Description string    `json:"description"`
+	Rule        string    `json:"rule"`
+	AST         parse.AST `json:"-"`
+}
+
+// Case is a test case: a record and whether the rule is expected to match it.
+type Case struct {
+	Data     map[string]string `json:"data"`
+	Expected bool              `json:"expected"`
+}
+
+// Report is the result of mutation testing. Score is the share of mutants killed, and is 1 when there are no
+// mutants. Failing lists the indices of the test cases which already fail on the rule; they are left out when judging
+// the mutants.
+type Report struct {
+	Mutants   int      `json:"mutants"`
+	Killed    int      `json:"killed"`
+	Score     float64  `json:"score"`
+	Survivors []Mutant `json:"survivors"`
+	Failing   []int    `json:"failing"`
+}
+
+// Mutants returns the mutants of the rule, leaving out duplicates and mutants which render the same as the rule.
+func Mutants(ast parse.AST) []Mutant {
+	var result []Mutant
+	seen := map[string]bool{fmt.Sprint(ast): true}
+	add := func(m Mutant) {
+		m.Rule = fmt.Sprint(m.AST)
+		if seen[m.Rule] {
+			return
+		}
+		seen[m.Rule] = true
+		result = append(result, m)
+	}
+	generate(ast, nil, func(ast parse.AST) parse.AST { return ast }, add)
+	return result
+}
+
+// Run runs the test cases against every mutant of the rule.
+func Run(ast parse.AST, cases []Case) (*Report, error) {
+	match, err := compile.Compile(ast)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Survivors: []Mutant{}, Failing: []int{}}
+	var passing []Case
+	for idx, c := range cases {
+		if match(c.Data) == c.Expected {
+			passing = append(passing, c)
+		} else {
+			report.Failing = append(report.Failing, idx)
+		}
+	}
+
+	for _, mutant := range Mutants(ast) {
+		match, err := compile.Compile(mutant.AST)
+		if err != nil {
+			return nil, err
+		}
+		report.Mutants++
+		killed := false
+		for _, c := range passing {
+			if match(c.Data) != c.Expected {
+				killed = true
+				break
+			}
+		}
+		if killed {
+			report.Killed++
+		} else {
+			report.Survivors = append(report.Survivors, mutant)
+		}
+	}
+
+	report.Score = 1
+	if report.Mutants > 0 {
+		report.Score = float64(report.Killed) / float64(report.Mutants)
+	}
+	return report, nil
+}
+
+// generate adds the mutants of the node at the provided path, and of its descendants. rebuild returns the whole rule
+// with the node replaced by its argument.
+func generate(ast parse.AST, path []int, rebuild func(parse.AST) parse.AST, add func(Mutant)) {
+	at := tree.Path(path)
+	mutant := func(kind Kind, replacement parse.AST, format string, args ...any) {
+		add(Mutant{Kind: kind, Path: at, Description: fmt.Sprintf(format, args...), AST: rebuild(replacement)})
+	}
+
+	var children []parse.AST
+	var replace func(idx int, child parse.AST) parse.AST
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		other := bools.OpOr
+		if ast.Op == bools.OpOr {
+			other = bools.OpAnd
+		}
+		children = bools.Flatten(ast, ast.Op)
+		mutant(Connective, bools.Chain(other, children...), "%v replaced with %v", ast.Op, other)
+		for idx, child := range children {
+			mutant(Drop, bools.Chain(ast.Op, without(children, idx)...), "%v dropped", child)
+		}
+		replace = func(idx int, child parse.AST) parse.AST {
+			return bools.Chain(ast.Op, with(children, idx, child)...)
+		}
+	case *bools.UnaryExpr:
+		children = []parse.AST{ast.Expr}
+		mutant(Unnegate, ast.Expr, "%v removed", ast.Op)
+		replace = func(_ int, child parse.AST) parse.AST {
+			return &bools.UnaryExpr{Op: ast.Op, Expr: child}
+		}
+	case *bools.AtLeastExpr:
+		children = ast.Children
+		for _, k := range []int{ast.K - 1, ast.K + 1} {
+			if bools.ValidCount(k, len(children)) {
+				mutant(Threshold, &bools.AtLeastExpr{K: k, Children: children}, "count %d replaced with %d", ast.K, k)
+			}
+		}
+		replace = func(idx int, child parse.AST) parse.AST {
+			return &bools.AtLeastExpr{K: ast.K, Children: with(children, idx, child)}
+		}
+	case *bools.ExactlyExpr:
+		children = ast.Children
+		for _, k := range []int{ast.K - 1, ast.K + 1} {
+			if bools.ValidCount(k, len(children)) {
+				mutant(Threshold, &bools.ExactlyExpr{K: k, Children: children}, "count %d replaced with %d", ast.K, k)
+			}
+		}
+		replace = func(idx int, child parse.AST) parse.AST {
+			return &bools.ExactlyExpr{K: ast.K, Children: with(children, idx, child)}
+		}
+	case *bools.PriorityExpr:
+		children = ast.Children
+		for idx := 0; idx+1 < len(children); idx++ {
+			swapped := with(with(children, idx, children[idx+1]), idx+1, children[idx])
+			mutant(Order, &bools.PriorityExpr{Children: swapped}, "%v and %v swapped", children[idx], children[idx+1])
+		}
+		replace = func(idx int, child parse.AST) parse.AST {
+			return &bools.PriorityExpr{Children: with(children, idx, child)}
+		}
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		comparison(ast, func(kind Kind, replacement parse.AST, description string) {
+			mutant(kind, replacement, "%s", description)
+		})
+	}
+
+	for idx, child := range children {
+		idx := idx
+		generate(child, append(append([]int{}, path...), idx), func(replacement parse.AST) parse.AST {
+			return rebuild(replace(idx, replacement))
+		}, add)
+	}
+}
+
+// boundaries and negations map each comparison operator to the operators its mutants use.
+var (
+	boundaries = map[comp.Op]comp.Op{
+		comp.OpGreater:        comp.OpGreaterOrEqual,
+		comp.OpGreaterOrEqual: comp.OpGreater,
+		comp.OpLess:           comp.OpLessOrEqual,
+		comp.OpLessOrEqual:    comp.OpLess,
+	}
+	negations = map[comp.Op]comp.Op{
+		comp.OpEqual:          comp.OpNotEqual,
+		comp.OpNotEqual:       comp.OpEqual,
+		comp.OpGreater:        comp.OpLessOrEqual,
+		comp.OpGreaterOrEqual: comp.OpLess,
+		comp.OpLess:           comp.OpGreaterOrEqual,
+		comp.OpLessOrEqual:    comp.OpGreater,
+	}
+)
+
+// comparison adds the mutants of a comparison node.
+func comparison(ast parse.AST, add func(kind Kind, replacement parse.AST, description string)) {
+	var lhs, rhs parse.AST
+	var op comp.Op
+	switch ast := ast.(type) {
+	case *comp.EqualExpr:
+		lhs, rhs, op = ast.LHS, ast.RHS, ast.Op
+	case *comp.OrdinalExpr:
+		lhs, rhs, op = ast.LHS, ast.RHS, ast.Op
+	}
+	withOp := func(op comp.Op) parse.AST {
+		if op == comp.OpEqual || op == comp.OpNotEqual {
+			return &comp.EqualExpr{LHS: lhs, RHS: rhs, Op: op}
+		}
+		return &comp.OrdinalExpr{LHS: lhs, RHS: rhs, Op: op}
+	}
+	if to, ok := boundaries[op]; ok {
+		add(Boundary, withOp(to), fmt.Sprintf("%v replaced with %v", op, to))
+	}
+	if to, ok := negations[op]; ok {
+		add(Negate, withOp(to), fmt.Sprintf("%v replaced with %v", op, to))
+	}
+
+	pred, ok := comp.AsPredicate(ast)
+	if !ok || strings.ContainsAny(pred.Value, " \t\n") {
+		return
+	}
+	num, ok := pred.Number()
+	if !ok {
+		return
+	}
+	for _, delta := range []float32{-1, 1} {
+		nudged := pred
+		nudged.Value = strconv.FormatFloat(float64(num+delta), 'g', -1, 32)
+		add(Literal, nudged.AST(), fmt.Sprintf("%s replaced with %s", pred.Value, nudged.Value))
+	}
+}
+
+// with returns a copy of the nodes in which the node at idx is replaced.
+func with(nodes []parse.AST, idx int, node parse.AST) []parse.AST {
+	result := append([]parse.AST{}, nodes...)
+	result[idx] = node
+	return result
+}
+
+// without returns a copy of the nodes without the node at idx.
+func without(nodes []parse.AST, idx int) []parse.AST {
+	result := append([]parse.AST{}, nodes[:idx]...)
+	return append(result, nodes[idx+1:]...)
+}
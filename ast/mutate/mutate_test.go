@@ -0,0 +1,122 @@
+package mutate
+
+import (
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutants(t *testing.T) {
+
+	testCases := []struct {
+		desc          string
+		ruleString    string
+		expectedRules []string
+	}{
+		{
+			desc:          "ordinal comparison",
+			ruleString:    "age > 30",
+			expectedRules: []string{"age >= 30", "age <= 30", "age > 29", "age > 31"},
+		},
+		{
+			desc:          "quoted equality",
+			ruleString:    "dept == 'A'",
+			expectedRules: []string{"dept != 'A'"},
+		},
+		{
+			desc:       "chain",
+			ruleString: "a == 'x' AND b != 'y'",
+			expectedRules: []string{
+				"a == 'x' OR b != 'y'", "b != 'y'", "a == 'x'", "a != 'x' AND b != 'y'", "a == 'x' AND b == 'y'",
+			},
+		},
+		{
+			desc:          "negation",
+			ruleString:    "NOT (flag)",
+			expectedRules: []string{"flag"},
+		},
+		{
+			desc:       "count",
+			ruleString: "ATLEAST(2, a == 'x', b == 'x', c == 'x')",
+			expectedRules: []string{
+				"ATLEAST(1, a == 'x', b == 'x', c == 'x')", "ATLEAST(3, a == 'x', b == 'x', c == 'x')",
+				"ATLEAST(2, a != 'x', b == 'x', c == 'x')", "ATLEAST(2, a == 'x', b != 'x', c == 'x')",
+				"ATLEAST(2, a == 'x', b == 'x', c != 'x')",
+			},
+		},
+		{
+			desc:          "priority",
+			ruleString:    "PRIORITY(a == 'x', b == 'x')",
+			expectedRules: []string{"PRIORITY(b == 'x', a == 'x')", "PRIORITY(a != 'x', b == 'x')", "PRIORITY(a == 'x', b != 'x')"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			rules := []string{}
+			for _, mutant := range Mutants(grammar.MustParse(tt.ruleString)) {
+				rules = append(rules, mutant.Rule)
+			}
+			assert.Equal(t, tt.expectedRules, rules)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	rule := "age > 30 AND dept == 'A'"
+
+	testCases := []struct {
+		desc              string
+		cases             []Case
+		expectedKilled    int
+		expectedSurvivors []string
+		expectedFailing   []int
+	}{
+		{
+			desc:              "no test cases",
+			cases:             nil,
+			expectedKilled:    0,
+			expectedSurvivors: []string{"age > 30 OR dept == 'A'", "dept == 'A'", "age > 30", "age >= 30 AND dept == 'A'", "age <= 30 AND dept == 'A'", "age > 29 AND dept == 'A'", "age > 31 AND dept == 'A'", "age > 30 AND dept != 'A'"},
+			expectedFailing:   []int{},
+		},
+		{
+			desc: "cases missing the boundary",
+			cases: []Case{
+				{Data: map[string]string{"age": "31", "dept": "A"}, Expected: true},
+				{Data: map[string]string{"age": "29", "dept": "A"}, Expected: false},
+				{Data: map[string]string{"age": "31", "dept": "B"}, Expected: false},
+			},
+			expectedKilled:    6,
+			expectedSurvivors: []string{"age >= 30 AND dept == 'A'", "age > 29 AND dept == 'A'"},
+			expectedFailing:   []int{},
+		},
+		{
+			desc: "failing case is ignored",
+			cases: []Case{
+				{Data: map[string]string{"age": "31", "dept": "A"}, Expected: false},
+				{Data: map[string]string{"age": "31", "dept": "B"}, Expected: false},
+			},
+			expectedKilled:    3,
+			expectedSurvivors: []string{"dept == 'A'", "age >= 30 AND dept == 'A'", "age <= 30 AND dept == 'A'", "age > 29 AND dept == 'A'", "age > 31 AND dept == 'A'"},
+			expectedFailing:   []int{0},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			report, err := Run(grammar.MustParse(rule), tt.cases)
+			assert.Nil(t, err)
+			survivors := []string{}
+			for _, mutant := range report.Survivors {
+				survivors = append(survivors, mutant.Rule)
+			}
+			assert.Equal(t, len(tt.expectedSurvivors)+tt.expectedKilled, report.Mutants)
+			assert.Equal(t, tt.expectedKilled, report.Killed)
+			assert.Equal(t, float64(tt.expectedKilled)/float64(report.Mutants), report.Score)
+			assert.Equal(t, tt.expectedSurvivors, survivors)
+			assert.Equal(t, tt.expectedFailing, report.Failing)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Package normal converts rule ASTs into conjunctive and disjunctive normal form.
+//
+// Negations are pushed inward until they only apply to comparisons. They are never pushed into comparisons, since a
+// comparison on a missing attribute is false and NOT (age > 30) is therefore not age <= 30. Bare terms always match,
+// so they are folded as the constant TRUE.
+package normal
+
+import (
+	"errors"
+	"fmt"
+
+	"RuleEngineAST/ast/expand"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrTooLarge is returned when the normal form of a rule would have more clauses than the limit.
+var ErrTooLarge = errors.New("normal form too large")
+
+// DefaultLimit is the largest number of clauses Convert produces.
+const DefaultLimit = 1024
+
+// Form is a normal form.
+type Form string
+
+// Normal forms.
+const (
+	CNF Form = "cnf" // CNF is an AND of ORs of comparisons and negated comparisons.
+	DNF Form = "dnf" // DNF is an OR of ANDs of comparisons and negated comparisons.
+)
+
+// Convert returns a copy of the rule in the provided normal form; the provided AST is not modified. Duplicate
+// comparisons are removed from each clause, as are clauses holding a comparison and its negation, which are decided,
+// and clauses holding every comparison of another clause, which are absorbed by it. A rule which always matches converts to TRUE and one which never matches to NOT (TRUE).
+func Convert(ast parse.AST, form Form) (parse.AST, error) {
+	return ConvertLimit(ast, form, DefaultLimit)
+}
+
+// ConvertLimit is Convert with a custom limit on the number of clauses.
+func ConvertLimit(ast parse.AST, form Form, limit int) (parse.AST, error) {
+	if form != CNF && form != DNF {
+		return nil, fmt.Errorf("%w: unknown normal form '%s'", parse.ErrConfig, form)
+	}
+	expanded, err := expand.Expand(ast)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTooLarge, err)
+	}
+
+	// the outer operator joins the clauses and the inner one the literals of each clause
+	outer, inner := bools.OpOr, bools.OpAnd
+	if form == CNF {
+		outer, inner = bools.OpAnd, bools.OpOr
+	}
+	c := &converter{outer: outer, limit: limit}
+	clauses, err := c.convert(expanded, false)
+	if err != nil {
+		return nil, err
+	}
+	clauses = absorb(clauses)
+
+	if len(clauses) == 0 {
+		// no clause is an empty OR for DNF, which never matches, and an empty AND for CNF, which always does
+		return constant(form == CNF), nil
+	}
+	var chains []parse.AST
+	for _, clause := range clauses {
+		if len(clause) == 0 {
+			return constant(form == DNF), nil
+		}
+		chains = append(chains, bools.Chain(inner, clause...))
+	}
+	return bools.Chain(outer, chains...), nil
+}
+
+func constant(value bool) parse.AST {
+	if value {
+		return parse.Unparsed{Contents: []string{"TRUE"}}
+	}
+	return &bools.UnaryExpr{Op: bools.OpNot, Expr: parse.Unparsed{Contents: []string{"TRUE"}}}
+}
+
+// clause is a conjunction of literals for DNF, and a disjunction for CNF.
+type clause []parse.AST
+
+type converter struct {
+	outer bools.Op
+	limit int
+}
+
+// convert returns the clauses of the rule, negated if negate is set.
+func (c *converter) convert(ast parse.AST, negate bool) ([]clause, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		op := ast.Op
+		if op != bools.OpAnd && op != bools.OpOr {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, op)
+		}
+		if negate && op == bools.OpAnd {
+			op = bools.OpOr
+		} else if negate {
+			op = bools.OpAnd
+		}
+
+		var operands [][]clause
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			clauses, err := c.convert(operand, negate)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, clauses)
+		}
+		if op == c.outer {
+			return c.union(operands)
+		}
+		return c.product(operands)
+
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		return c.convert(ast.Expr, !negate)
+
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		var lit parse.AST = ast
+		if negate {
+			lit = &bools.UnaryExpr{Op: bools.OpNot, Expr: ast}
+		}
+		return []clause{{lit}}, nil
+
+	case parse.Unparsed:
+		// TRUE is a single empty clause for DNF and no clause for CNF, and FALSE the other way round
+		if negate == (c.outer == bools.OpOr) {
+			return nil, nil
+		}
+		return []clause{{}}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+// union joins the clauses of operands combined with the outer operator.
+func (c *converter) union(operands [][]clause) ([]clause, error) {
+	var result []clause
+	seen := map[string]bool{}
+	for _, clauses := range operands {
+		for _, cl := range clauses {
+			key := fmt.Sprint(cl)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, cl)
+			if len(result) > c.limit {
+				return nil, fmt.Errorf("%w: more than %d clauses", ErrTooLarge, c.limit)
+			}
+		}
+	}
+	return result, nil
+}
+
+// product distributes operands combined with the inner operator over their clauses, giving one clause for every way
+// of picking a clause from each operand.
+func (c *converter) product(operands [][]clause) ([]clause, error) {
+	result := []clause{{}}
+	for _, clauses := range operands {
+		var next []clause
+		seen := map[string]bool{}
+		for _, left := range result {
+			for _, right := range clauses {
+				merged, ok := join(left, right)
+				if !ok {
+					continue
+				}
+				key := fmt.Sprint(merged)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				next = append(next, merged)
+				if len(next) > c.limit {
+					return nil, fmt.Errorf("%w: more than %d clauses", ErrTooLarge, c.limit)
+				}
+			}
+		}
+		result = next
+	}
+	return result, nil
+}
+
+// join returns the literals of both clauses without duplicates. It returns false if the clause would hold a literal
+// and its negation, which makes a DNF clause never match and a CNF clause always match, so it can be dropped.
+func join(left, right clause) (clause, bool) {
+	result := append(clause{}, left...)
+	seen := map[string]bool{}
+	for _, lit := range left {
+		seen[fmt.Sprint(lit)] = true
+	}
+	for _, lit := range right {
+		key := fmt.Sprint(lit)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, lit)
+	}
+	for _, lit := range result {
+		if not, ok := lit.(*bools.UnaryExpr); ok && seen[fmt.Sprint(not.Expr)] {
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+// absorb drops the clauses which hold all the literals of another clause, keeping the first of identical clauses.
+func absorb(clauses []clause) []clause {
+	sets := make([]map[string]bool, len(clauses))
+	for idx, cl := range clauses {
+		sets[idx] = map[string]bool{}
+		for _, lit := range cl {
+			sets[idx][fmt.Sprint(lit)] = true
+		}
+	}
+	subset := func(small, large map[string]bool) bool {
+		for key := range small {
+			if !large[key] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var result []clause
+	for idx, cl := range clauses {
+		absorbed := false
+		for other := range clauses {
+			if other != idx && subset(sets[other], sets[idx]) && (len(sets[other]) < len(sets[idx]) || other < idx) {
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			result = append(result, cl)
+		}
+	}
+	return result
+}
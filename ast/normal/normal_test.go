@@ -0,0 +1,170 @@
+package normal
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var records = func() []map[string]string {
+	var result []map[string]string
+	for _, age := range []string{"", "20", "25", "30", "31", "thirty"} {
+		for _, dept := range []string{"", "A", "B", "C"} {
+			record := map[string]string{}
+			if age != "" {
+				record["age"] = age
+			}
+			if dept != "" {
+				record["dept"] = dept
+			}
+			result = append(result, record)
+		}
+	}
+	return result
+}()
+
+// assertSameMatches checks that the converted rule round trips and matches the same records as the original one.
+func assertSameMatches(t *testing.T, original, converted parse.AST) bool {
+	before, err := compile.Compile(original)
+	assert.Nil(t, err)
+	after, err := compile.Compile(grammar.MustParse(fmt.Sprint(converted)))
+	assert.Nil(t, err)
+	for _, record := range records {
+		if !assert.Equal(t, before(record), after(record), "rule %q converted to %q, record %v", original, converted, record) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestConvert(t *testing.T) {
+
+	testCases := []struct {
+		desc        string
+		ruleString  string
+		expectedDNF string
+		expectedCNF string
+	}{
+		{
+			desc:        "comparison",
+			ruleString:  "age > 30",
+			expectedDNF: "age > 30",
+			expectedCNF: "age > 30",
+		},
+		{
+			desc:        "distribution",
+			ruleString:  "age > 30 AND (dept == 'A' OR dept == 'B')",
+			expectedDNF: "(age > 30 AND dept == 'A') OR (age > 30 AND dept == 'B')",
+			expectedCNF: "age > 30 AND (dept == 'A' OR dept == 'B')",
+		},
+		{
+			desc:        "negation pushed to comparisons",
+			ruleString:  "NOT (age > 30 OR NOT (dept == 'A' OR dept == 'B'))",
+			expectedDNF: "(NOT (age > 30) AND dept == 'A') OR (NOT (age > 30) AND dept == 'B')",
+			expectedCNF: "NOT (age > 30) AND (dept == 'A' OR dept == 'B')",
+		},
+		{
+			desc:        "complementary and absorbed clauses are dropped",
+			ruleString:  "(age > 30 OR dept == 'A') AND (NOT (age > 30) OR dept == 'A')",
+			expectedDNF: "dept == 'A'",
+			expectedCNF: "(age > 30 OR dept == 'A') AND (NOT (age > 30) OR dept == 'A')",
+		},
+		{
+			desc:        "never matches",
+			ruleString:  "age > 30 AND NOT (age > 30)",
+			expectedDNF: "NOT (TRUE)",
+			expectedCNF: "age > 30 AND NOT (age > 30)",
+		},
+		{
+			desc:        "always matches",
+			ruleString:  "active OR age > 30",
+			expectedDNF: "TRUE",
+			expectedCNF: "TRUE",
+		},
+		{
+			desc:        "xor",
+			ruleString:  "XOR(age > 30, dept == 'A')",
+			expectedDNF: "(age > 30 AND NOT (dept == 'A')) OR (NOT (age > 30) AND dept == 'A')",
+			expectedCNF: "(age > 30 OR dept == 'A') AND (NOT (dept == 'A') OR NOT (age > 30))",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := grammar.MustParse(tt.ruleString)
+			original := fmt.Sprint(ast)
+
+			dnf, err := Convert(ast, DNF)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedDNF, fmt.Sprint(dnf))
+			assertSameMatches(t, ast, dnf)
+
+			cnf, err := Convert(ast, CNF)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedCNF, fmt.Sprint(cnf))
+			assertSameMatches(t, ast, cnf)
+
+			assert.Equal(t, original, fmt.Sprint(ast), "the input must not be modified")
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	_, err := Convert(grammar.MustParse("age > 30"), Form("nnf"))
+	assert.True(t, errors.Is(err, parse.ErrConfig))
+
+	// a conjunction of n disjunctions of two comparisons has 2^n clauses in DNF
+	var operands []string
+	for i := 0; i < 11; i++ {
+		operands = append(operands, fmt.Sprintf("(a%d == 1 OR b%d == 1)", i, i))
+	}
+	rule := grammar.MustParse(strings.Join(operands, " AND "))
+	_, err = Convert(rule, DNF)
+	assert.True(t, errors.Is(err, ErrTooLarge))
+	_, err = ConvertLimit(rule, DNF, 4096)
+	assert.Nil(t, err)
+	_, err = Convert(rule, CNF)
+	assert.Nil(t, err)
+}
+
+func TestConvertRandomRules(t *testing.T) {
+
+	atoms := []string{
+		"age > 30", "age >= 30", "age < 30", "age <= 20", "age == 30", "age != 30",
+		"dept == 'A'", "dept == 'B'", "dept != 'A'", "flag",
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	var build func(depth int) string
+	build = func(depth int) string {
+		switch n := rnd.Intn(6); {
+		case depth == 0 || n < 2:
+			return atoms[rnd.Intn(len(atoms))]
+		case n == 2:
+			return "NOT (" + build(depth-1) + ")"
+		case n == 3:
+			return "(" + build(depth-1) + ") OR (" + build(depth-1) + ")"
+		default:
+			return "(" + build(depth-1) + ") AND (" + build(depth-1) + ")"
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+		ast := grammar.MustParse(build(4))
+		for _, form := range []Form{CNF, DNF} {
+			converted, err := Convert(ast, form)
+			assert.Nil(t, err)
+			if !assertSameMatches(t, ast, converted) {
+				return
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package parse
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"unicode"
 )
 
@@ -26,6 +27,11 @@ func (u Unparsed) Parse(p Parser) error {
 	return fmt.Errorf("%w: attempted to parse Unparsed node", ErrParse)
 }
 
+// String joins the unparsed tokens with single spaces.
+func (u Unparsed) String() string {
+	return strings.Join(u.Contents, " ")
+}
+
 type Parser interface {
 	Parse(tokens []string) (AST, error)
 }
@@ -42,6 +48,9 @@ func (i Interpreter[T]) WithFallback(b Interpreter[T]) Interpreter[T] {
 	}
 }
 
+// Tokenize splits str into words at whitespace, and around the open and close parentheses and every keyword matched
+// by keywordMatcher. Between single quotes only whitespace splits words, so that a quoted literal may contain
+// parentheses and keywords joined to other characters, as in 'MAJORITY_X'.
 func Tokenize(str string, open, close rune, keywordMatcher *KeywordTrie) []string {
 	runes := []rune(str)
 	var substr []rune
@@ -51,7 +60,15 @@ func Tokenize(str string, open, close rune, keywordMatcher *KeywordTrie) []strin
 		substr = nil
 	}
 
+	quoted := false
 	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\'' {
+			quoted = !quoted
+		}
+		if quoted && !unicode.IsSpace(runes[i]) || runes[i] == '\'' {
+			substr = append(substr, runes[i])
+			continue
+		}
 		if runes[i] == open || runes[i] == close {
 			if len(substr) > 0 {
 				push()
@@ -2,6 +2,7 @@ package bools
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"RuleEngineAST/ast/parse"
@@ -67,6 +68,14 @@ func Eval(expr parse.AST, interpreter parse.Interpreter[bool]) (bool, error) {
 			return false, err
 		}
 		return !val, nil
+	case *AtLeastExpr:
+		count, err := countMatches(expr.Children, interpreter)
+		return count >= expr.K, err
+	case *ExactlyExpr:
+		count, err := countMatches(expr.Children, interpreter)
+		return count == expr.K, err
+	case *PriorityExpr:
+		return false, fmt.Errorf("%w: PRIORITY needs an evaluator which knows the attributes of a record", parse.ErrEval)
 	default:
 		return interpreter(expr)
 	}
@@ -74,6 +83,20 @@ func Eval(expr parse.AST, interpreter parse.Interpreter[bool]) (bool, error) {
 	return false, fmt.Errorf("unexpected expression")
 }
 
+func countMatches(children []parse.AST, interpreter parse.Interpreter[bool]) (int, error) {
+	count := 0
+	for _, child := range children {
+		val, err := Eval(child, interpreter)
+		if err != nil {
+			return 0, err
+		}
+		if val {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // BinExpr represents a boolean expression consisting of clauses of one boolean operator.
 type BinExpr struct {
 	LHS parse.AST // LHS is the left-hand side
@@ -104,6 +127,20 @@ func (b *BinExpr) Parse(p parse.Parser) error {
 	return nil
 }
 
+// String renders the expression using the default tokens. Binary sub-expressions are parenthesized wherever leaving
+// them bare would parse back into a different tree.
+func (b *BinExpr) String() string {
+	lhs := fmt.Sprint(b.LHS)
+	if _, ok := b.LHS.(*BinExpr); ok {
+		lhs = "(" + lhs + ")"
+	}
+	rhs := fmt.Sprint(b.RHS)
+	if r, ok := b.RHS.(*BinExpr); ok && r.Op != b.Op {
+		rhs = "(" + rhs + ")"
+	}
+	return lhs + " " + b.Op.String() + " " + rhs
+}
+
 // UnaryExpr represents a unary boolean expression.
 type UnaryExpr struct {
 	Op   Op
@@ -124,6 +161,33 @@ func (u *UnaryExpr) Parse(p parse.Parser) error {
 	return nil
 }
 
+// String renders the expression using the default tokens.
+func (u *UnaryExpr) String() string {
+	return fmt.Sprintf("%s (%v)", u.Op, u.Expr)
+}
+
+// Flatten returns the operands of the chain of op expressions rooted at expr, from left to right. If expr is not a
+// BinExpr using op, it is returned as the only operand.
+func Flatten(expr parse.AST, op Op) []parse.AST {
+	bin, ok := expr.(*BinExpr)
+	if !ok || bin.Op != op {
+		return []parse.AST{expr}
+	}
+	return append(Flatten(bin.LHS, op), Flatten(bin.RHS, op)...)
+}
+
+// Chain joins the provided operands with op, nesting to the right in the same way as the parser. A single operand is
+// returned as is, and nil is returned when no operands are provided.
+func Chain(op Op, exprs ...parse.AST) parse.AST {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return exprs[0]
+	}
+	return &BinExpr{LHS: exprs[0], RHS: Chain(op, exprs[1:]...), Op: op}
+}
+
 // Op represents a boolean operation recognized by this grammar.
 type Op uint8
 
@@ -155,21 +219,40 @@ const (
 	Not                         // Not represents boolean not.
 	OpenParen                   // OpenParen represents the start of a sub-expression.
 	CloseParen                  // CloseParen represents the end of a sub-expression.
+	AtLeast                     // AtLeast starts a list of rules of which at least a number must match.
+	Majority                    // Majority starts a list of rules of which more than half must match.
+	Exactly                     // Exactly starts a list of rules of which exactly a number must match.
+	Xor                         // Xor starts a list of rules of which exactly one must match.
+	Priority                    // Priority starts a list of rules of which the first with an opinion decides.
+	Separator                   // Separator separates the arguments of a list of rules.
 )
 
+// defaultTokens is the syntax used by parsers unless configured otherwise.
+var defaultTokens = map[Token]string{
+	And:        "AND",
+	Or:         "OR",
+	Not:        "NOT",
+	OpenParen:  "(",
+	CloseParen: ")",
+	AtLeast:    "ATLEAST",
+	Majority:   "MAJORITY",
+	Exactly:    "EXACTLY",
+	Xor:        "XOR",
+	Priority:   "PRIORITY",
+	Separator:  ",",
+}
+
 type ParserOpt func(*Parser)
 
+// Parser parses boolean expressions. A Parser is not modified after NewParser returns, so it is safe for concurrent use.
 type Parser struct {
 	config          map[Token]string
 	caseInsensitive bool
 	matcher         *parse.KeywordTrie
-
-	tokens []string
-	curr   int
 }
 
 // WithTokens configures the syntax used by this parser using the provided token mapping. The provided map must contain
-// distinct entries for each Token provided in this package: And, Or, Not, OpenParen, and CloseParen.
+// distinct entries for And, Or, Not, OpenParen and CloseParen; the other tokens keep their default unless provided.
 func WithTokens(config map[Token]string) ParserOpt {
 	return func(parser *Parser) {
 		parser.config = config
@@ -187,13 +270,7 @@ func WithCaseSensitive(caseSensitive bool) ParserOpt {
 // parser is returned.
 func NewParser(opts ...ParserOpt) (*Parser, error) {
 	p := &Parser{
-		config: map[Token]string{
-			And:        "AND",
-			Or:         "OR",
-			Not:        "NOT",
-			OpenParen:  "(",
-			CloseParen: ")",
-		},
+		config:  defaultTokens,
 		matcher: &parse.KeywordTrie{},
 	}
 	for _, opt := range opts {
@@ -212,17 +289,29 @@ func (p *Parser) init() error {
 	if p.config[OpenParen] == p.config[CloseParen] {
 		return fmt.Errorf("%w: OpenParen and CloseParen must each be distinct", parse.ErrConfig)
 	}
-	if p.caseInsensitive {
-		newTokens := make(map[Token]string, len(p.config))
-		for token, str := range p.config {
-			newTokens[token] = strings.ToLower(str)
+	// copy the tokens, so that later changes to a map passed to WithTokens cannot affect the parser
+	newTokens := make(map[Token]string, len(defaultTokens))
+	for token, str := range defaultTokens {
+		if configured, ok := p.config[token]; ok {
+			str = configured
+		}
+		if p.caseInsensitive {
+			str = strings.ToLower(str)
 		}
-		p.config = newTokens
+		newTokens[token] = str
 	}
-	for _, str := range p.config {
-		p.matcher.Add(str)
+	p.config = newTokens
+	if p.config[Separator] == "" {
+		return fmt.Errorf("%w: Separator must not be empty", parse.ErrConfig)
 	}
-	if p.matcher.Count() != 5 {
+	// the separator only splits the arguments of lists of rules, so it is not a keyword and may appear in literals
+	// elsewhere
+	for token, str := range p.config {
+		if token != Separator {
+			p.matcher.Add(str)
+		}
+	}
+	if p.matcher.Count() != len(p.config)-1 || p.matcher.Contains(p.config[Separator]) {
 		return fmt.Errorf("%w: token collision detected; at least two of the configured tokens are identical", parse.ErrConfig)
 	}
 	return nil
@@ -236,24 +325,32 @@ func (p *Parser) ParseStr(str string) (parse.AST, error) {
 // Parse parses the provided list of tokens, producing a parse.AST. An error is returned if the tokens provided cannot
 // be parsed.
 func (p *Parser) Parse(tokens []string) (parse.AST, error) {
-	p.curr = 0
-	p.tokens = tokens
-	ast, err := p.parseExpr()
+	s := &state{Parser: p, tokens: tokens}
+	ast, err := s.parseExpr()
 	if err != nil {
 		return nil, err
 	}
-	if p.curr != len(p.tokens) {
-		return nil, fmt.Errorf("%w: expected end of expression, found '%s'", parse.ErrParse, p.tokens[p.curr])
+	if s.curr != len(s.tokens) {
+		return nil, fmt.Errorf("%w: expected end of expression, found '%s'", parse.ErrParse, s.tokens[s.curr])
 	}
 	return ast, nil
 }
 
+// state holds the position of a single call to Parse, so that a Parser is never modified after NewParser returns and
+// can be shared between goroutines.
+type state struct {
+	*Parser
+	tokens []string
+	curr   int
+	lists  int // lists is the number of lists of rules being parsed, in which Separator ends a rule
+}
+
 func (p *Parser) tokenize(str string) []string {
 	openP, closeP := []rune(p.config[OpenParen])[0], []rune(p.config[CloseParen])[0]
 	return parse.Tokenize(str, openP, closeP, p.matcher)
 }
 
-func (p *Parser) match(token Token) bool {
+func (p *state) match(token Token) bool {
 	if p.curr == len(p.tokens) {
 		return false
 	}
@@ -268,7 +365,7 @@ func (p *Parser) match(token Token) bool {
 	return false
 }
 
-func (p *Parser) peek() string {
+func (p *state) peek() string {
 	return p.tokens[p.curr]
 }
 
@@ -279,11 +376,11 @@ func (p *Parser) isKeyword(str string) bool {
 	return p.matcher.Contains(str)
 }
 
-func (p *Parser) parseExpr() (parse.AST, error) {
+func (p *state) parseExpr() (parse.AST, error) {
 	return p.parseAnd()
 }
 
-func (p *Parser) parseAnd() (parse.AST, error) {
+func (p *state) parseAnd() (parse.AST, error) {
 	lhs, err := p.parseOr()
 	if err != nil {
 		return nil, err
@@ -298,7 +395,7 @@ func (p *Parser) parseAnd() (parse.AST, error) {
 	return lhs, nil
 }
 
-func (p *Parser) parseOr() (parse.AST, error) {
+func (p *state) parseOr() (parse.AST, error) {
 	lhs, err := p.parseNot()
 	if err != nil {
 		return nil, err
@@ -313,7 +410,7 @@ func (p *Parser) parseOr() (parse.AST, error) {
 	return lhs, nil
 }
 
-func (p *Parser) parseNot() (parse.AST, error) {
+func (p *state) parseNot() (parse.AST, error) {
 	if p.match(Not) {
 		rest, err := p.parseParens()
 		if err != nil {
@@ -325,7 +422,12 @@ func (p *Parser) parseNot() (parse.AST, error) {
 }
 
 // parseParens parses parentheses, which must be correctly matched
-func (p *Parser) parseParens() (parse.AST, error) {
+func (p *state) parseParens() (parse.AST, error) {
+	for _, token := range []Token{AtLeast, Majority, Exactly, Xor, Priority} {
+		if p.match(token) {
+			return p.parseList(token)
+		}
+	}
 	if p.match(OpenParen) {
 		ast, err := p.parseExpr()
 		if err != nil {
@@ -339,9 +441,116 @@ func (p *Parser) parseParens() (parse.AST, error) {
 	return p.parseRest()
 }
 
-func (p *Parser) parseRest() (parse.AST, error) {
+// parseList parses the parenthesized arguments following one of the tokens starting a list of rules: a count for
+// AtLeast and Exactly, followed by the rules, all separated by Separator.
+func (p *state) parseList(token Token) (parse.AST, error) {
+	name := p.config[token]
+	if !p.match(OpenParen) {
+		return nil, fmt.Errorf("%w: expected '%s' after '%s'", parse.ErrParse, p.config[OpenParen], name)
+	}
+	if err := p.splitSeparators(); err != nil {
+		return nil, err
+	}
+	p.lists++
+	defer func() { p.lists-- }()
+
+	k := 0
+	if token == AtLeast || token == Exactly {
+		if p.curr == len(p.tokens) {
+			return nil, fmt.Errorf("%w: expected a count after '%s'", parse.ErrParse, name)
+		}
+		count, err := strconv.Atoi(p.peek())
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("%w: expected a count after '%s', found '%s'", parse.ErrParse, name, p.peek())
+		}
+		p.curr++
+		if !p.match(Separator) {
+			return nil, fmt.Errorf("%w: expected '%s' after the count of '%s'", parse.ErrParse, p.config[Separator], name)
+		}
+		k = count
+	}
+
+	var children []parse.AST
+	for {
+		child, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		if p.match(CloseParen) {
+			break
+		}
+		if !p.match(Separator) {
+			return nil, fmt.Errorf("%w: expected '%s' or '%s' in '%s'", parse.ErrParse, p.config[Separator], p.config[CloseParen], name)
+		}
+	}
+
+	if (token == AtLeast || token == Exactly) && !ValidCount(k, len(children)) {
+		return nil, fmt.Errorf("%w: the count of '%s' must be between 1 and the number of rules, %d", parse.ErrParse, name, len(children))
+	}
+
+	switch token {
+	case AtLeast:
+		return &AtLeastExpr{K: k, Children: children}, nil
+	case Majority:
+		return MajorityOf(children...), nil
+	case Exactly:
+		return &ExactlyExpr{K: k, Children: children}, nil
+	case Xor:
+		return &ExactlyExpr{K: 1, Children: children}, nil
+	}
+	return &PriorityExpr{Children: children}, nil
+}
+
+// splitSeparators splits the tokens up to the parenthesis closing the list being parsed around each Separator, which
+// the tokenizer leaves attached to the neighbouring words. Separators between single quotes belong to a literal and are
+// left alone; an error is returned if a quote is not closed. The tokens are copied first, as they belong to the caller.
+func (p *state) splitSeparators() error {
+	sep := p.config[Separator]
+	tokens := append([]string{}, p.tokens[:p.curr]...)
+	depth := 1
+	quoted := false
+	idx := p.curr
+	for ; idx < len(p.tokens) && depth > 0; idx++ {
+		token := p.tokens[idx]
+		switch token {
+		case p.config[OpenParen]:
+			depth++
+		case p.config[CloseParen]:
+			depth--
+		}
+
+		start := 0
+		for i := 0; i < len(token); {
+			switch {
+			case token[i] == '\'':
+				quoted = !quoted
+				i++
+			case !quoted && strings.HasPrefix(token[i:], sep):
+				if start < i {
+					tokens = append(tokens, token[start:i])
+				}
+				tokens = append(tokens, sep)
+				i += len(sep)
+				start = i
+			default:
+				i++
+			}
+		}
+		if start < len(token) {
+			tokens = append(tokens, token[start:])
+		}
+	}
+	if quoted {
+		return fmt.Errorf("%w: unterminated quote", parse.ErrParse)
+	}
+	p.tokens = append(tokens, p.tokens[idx:]...)
+	return nil
+}
+
+func (p *state) parseRest() (parse.AST, error) {
 	var result []string
-	for p.curr < len(p.tokens) && !p.isKeyword(p.peek()) {
+	for p.curr < len(p.tokens) && !p.isKeyword(p.peek()) && !(p.lists > 0 && p.peek() == p.config[Separator]) {
 		result = append(result, p.peek())
 		p.curr++
 	}
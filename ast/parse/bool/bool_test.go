@@ -0,0 +1,218 @@
+package bools
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"RuleEngineAST/ast/parse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseList(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		ruleString   string
+		expectedRule string
+		expectedErr  error
+	}{
+		{
+			desc:         "at least",
+			ruleString:   "ATLEAST(2, a > 1, b > 2, c > 3)",
+			expectedRule: "ATLEAST(2, a > 1, b > 2, c > 3)",
+		},
+		{
+			desc:         "separators attached to words",
+			ruleString:   "EXACTLY(1,a > 1,b > 2)",
+			expectedRule: "EXACTLY(1, a > 1, b > 2)",
+		},
+		{
+			desc:         "majority and xor",
+			ruleString:   "MAJORITY(a, b, c) AND XOR(d, e)",
+			expectedRule: "ATLEAST(2, a, b, c) AND EXACTLY(1, d, e)",
+		},
+		{
+			desc:         "nested lists",
+			ruleString:   "PRIORITY(ATLEAST(1, a > 1, NOT (b > 2)), (c > 3 OR d > 4), XOR(e, f))",
+			expectedRule: "PRIORITY(ATLEAST(1, a > 1, NOT (b > 2)), c > 3 OR d > 4, EXACTLY(1, e, f))",
+		},
+		{
+			desc:         "separator inside quotes",
+			ruleString:   "ATLEAST(1, name == 'Smith, John', age > 3)",
+			expectedRule: "ATLEAST(1, name == 'Smith, John', age > 3)",
+		},
+		{
+			desc:         "separator outside of lists",
+			ruleString:   "name == 'a,b' AND age > 3",
+			expectedRule: "name == 'a,b' AND age > 3",
+		},
+		{
+			desc:        "unterminated quote",
+			ruleString:  "ATLEAST(1, name == 'Smith, age > 3)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "missing count",
+			ruleString:  "ATLEAST(a > 1, b > 2)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "missing count at end",
+			ruleString:  "EXACTLY(",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "negative count",
+			ruleString:  "ATLEAST(-1, a > 1, b > 2)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "zero count",
+			ruleString:  "ATLEAST(0, a > 1, b > 2)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "zero exact count",
+			ruleString:  "EXACTLY(0, a > 1)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "count above the number of rules",
+			ruleString:  "ATLEAST(3, a > 1, b > 2)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "exact count above the number of rules",
+			ruleString:  "NOT (EXACTLY(2, a > 1))",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:         "count of all rules",
+			ruleString:   "EXACTLY(2, a > 1, b > 2)",
+			expectedRule: "EXACTLY(2, a > 1, b > 2)",
+		},
+		{
+			desc:        "empty list",
+			ruleString:  "MAJORITY()",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "count without rules",
+			ruleString:  "ATLEAST(1)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "empty rule in list",
+			ruleString:  "XOR(a, , b)",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:         "list keyword inside a literal",
+			ruleString:   "dept == 'MAJORITY_X'",
+			expectedRule: "dept == 'MAJORITY_X'",
+		},
+		{
+			desc:         "list keyword inside a literal in a list",
+			ruleString:   "XOR(dept == 'XORG', a)",
+			expectedRule: "EXACTLY(1, dept == 'XORG', a)",
+		},
+		{
+			desc:         "parentheses inside a literal",
+			ruleString:   "ATLEAST(1, dept == 'R(D)', team == 'NOT(x)')",
+			expectedRule: "ATLEAST(1, dept == 'R(D)', team == 'NOT(x)')",
+		},
+		{
+			desc:         "keywords are case-sensitive",
+			ruleString:   "dept == 'majority_x' AND team == 'Priority'",
+			expectedRule: "dept == 'majority_x' AND team == 'Priority'",
+		},
+		{
+			desc:        "missing parenthesis",
+			ruleString:  "XOR a, b",
+			expectedErr: parse.ErrParse,
+		},
+		{
+			desc:        "unclosed list",
+			ruleString:  "XOR(a, b",
+			expectedErr: parse.ErrParse,
+		},
+	}
+
+	p, err := NewParser()
+	assert.Nil(t, err)
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast, err := p.ParseStr(tt.ruleString)
+			if tt.expectedErr != nil {
+				assert.True(t, errors.Is(err, tt.expectedErr), "expected %v, got %v", tt.expectedErr, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(ast))
+		})
+	}
+}
+
+func TestWithTokens(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		opts         []ParserOpt
+		ruleString   string
+		expectedRule string
+		expectedErr  error
+	}{
+		{
+			desc:         "list tokens fall back to the defaults",
+			opts:         []ParserOpt{WithTokens(map[Token]string{And: "&&", Or: "||", Not: "!", OpenParen: "[", CloseParen: "]"})},
+			ruleString:   "a && ATLEAST[1, b, c || d]",
+			expectedRule: "a AND ATLEAST(1, b, c OR d)",
+		},
+		{
+			desc: "custom list tokens",
+			opts: []ParserOpt{WithTokens(map[Token]string{
+				And: "AND", Or: "OR", Not: "NOT", OpenParen: "(", CloseParen: ")", AtLeast: "MIN", Separator: ";",
+			})},
+			ruleString:   "MIN(1; a == 'x,y'; b)",
+			expectedRule: "ATLEAST(1, a == 'x,y', b)",
+		},
+		{
+			desc:         "case insensitive",
+			opts:         []ParserOpt{WithCaseSensitive(false)},
+			ruleString:   "xor(a, b) and not (c)",
+			expectedRule: "EXACTLY(1, a, b) AND NOT (c)",
+		},
+		{
+			desc:        "list token colliding with another token",
+			opts:        []ParserOpt{WithTokens(map[Token]string{And: "AND", Or: "OR", Not: "NOT", OpenParen: "(", CloseParen: ")", Xor: "AND"})},
+			expectedErr: parse.ErrConfig,
+		},
+		{
+			desc:        "separator colliding with another token",
+			opts:        []ParserOpt{WithTokens(map[Token]string{And: "AND", Or: "OR", Not: "NOT", OpenParen: "(", CloseParen: ")", Separator: "OR"})},
+			expectedErr: parse.ErrConfig,
+		},
+		{
+			desc:        "empty separator",
+			opts:        []ParserOpt{WithTokens(map[Token]string{And: "AND", Or: "OR", Not: "NOT", OpenParen: "(", CloseParen: ")", Separator: ""})},
+			expectedErr: parse.ErrConfig,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			p, err := NewParser(tt.opts...)
+			if tt.expectedErr != nil {
+				assert.True(t, errors.Is(err, tt.expectedErr), "expected %v, got %v", tt.expectedErr, err)
+				return
+			}
+			assert.Nil(t, err)
+			ast, err := p.ParseStr(tt.ruleString)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(ast))
+		})
+	}
+}
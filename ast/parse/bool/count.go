@@ -0,0 +1,110 @@
+package bools
+
+import (
+	"fmt"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+)
+
+// AtLeastExpr represents a rule which matches when at least K of its children match.
+type AtLeastExpr struct {
+	K        int
+	Children []parse.AST
+}
+
+// MajorityOf returns a rule which matches when more than half of the provided rules match.
+func MajorityOf(children ...parse.AST) *AtLeastExpr {
+	return &AtLeastExpr{K: len(children)/2 + 1, Children: children}
+}
+
+// Parse runs the provided parse.Parser on all the unparsed nodes in this AST.
+func (a *AtLeastExpr) Parse(p parse.Parser) error {
+	return parseChildren(a.Children, p)
+}
+
+// String renders the expression using the default tokens.
+func (a *AtLeastExpr) String() string {
+	return fmt.Sprintf("ATLEAST(%d, %s)", a.K, joinChildren(a.Children))
+}
+
+// ValidCount reports whether k is a count ATLEAST and EXACTLY accept over n rules: between 1 and n. A count of 0 or
+// above n makes the rule match always, never, or only when no rule matches, which is better written without a count.
+func ValidCount(k, n int) bool {
+	return k >= 1 && k <= n
+}
+
+// ExactlyExpr represents a rule which matches when exactly K of its children match. XOR is an ExactlyExpr with K set
+// to 1.
+type ExactlyExpr struct {
+	K        int
+	Children []parse.AST
+}
+
+// Parse runs the provided parse.Parser on all the unparsed nodes in this AST.
+func (e *ExactlyExpr) Parse(p parse.Parser) error {
+	return parseChildren(e.Children, p)
+}
+
+// String renders the expression using the default tokens.
+func (e *ExactlyExpr) String() string {
+	return fmt.Sprintf("EXACTLY(%d, %s)", e.K, joinChildren(e.Children))
+}
+
+// PriorityExpr represents a rule which gives the result of its first child having an opinion on the record, and does not
+// match if none has. What makes an opinion is left to the evaluator: the rule engine considers that a child has an
+// opinion on a record when the record has every attribute the child compares.
+type PriorityExpr struct {
+	Children []parse.AST
+}
+
+// Parse runs the provided parse.Parser on all the unparsed nodes in this AST.
+func (p *PriorityExpr) Parse(parser parse.Parser) error {
+	return parseChildren(p.Children, parser)
+}
+
+// String renders the expression using the default tokens.
+func (p *PriorityExpr) String() string {
+	return fmt.Sprintf("PRIORITY(%s)", joinChildren(p.Children))
+}
+
+// Children returns the operands of a boolean node, from left to right, or nil if the node is not one of the nodes of
+// this package.
+func Children(expr parse.AST) []parse.AST {
+	switch expr := expr.(type) {
+	case *BinExpr:
+		return []parse.AST{expr.LHS, expr.RHS}
+	case *UnaryExpr:
+		return []parse.AST{expr.Expr}
+	case *AtLeastExpr:
+		return expr.Children
+	case *ExactlyExpr:
+		return expr.Children
+	case *PriorityExpr:
+		return expr.Children
+	}
+	return nil
+}
+
+func parseChildren(children []parse.AST, p parse.Parser) error {
+	for idx, child := range children {
+		if unparsed, ok := child.(parse.Unparsed); ok {
+			parsed, err := p.Parse(unparsed.Contents)
+			if err != nil {
+				return err
+			}
+			children[idx] = parsed
+		} else if err := child.Parse(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinChildren(children []parse.AST) string {
+	var result []string
+	for _, child := range children {
+		result = append(result, fmt.Sprint(child))
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,55 @@
+package comp
+
+import (
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+)
+
+// Attributes returns the attributes compared by a rule, in order of first appearance. Like the rule engine, it takes
+// the attribute of a comparison whose left-hand side is not unparsed to be the empty string.
+func Attributes(ast parse.AST) []string {
+	var result []string
+	seen := map[string]bool{}
+	var walk func(ast parse.AST)
+	walk = func(ast parse.AST) {
+		var lhs parse.AST
+		switch ast := ast.(type) {
+		case *EqualExpr:
+			lhs = ast.LHS
+		case *OrdinalExpr:
+			lhs = ast.LHS
+		default:
+			for _, child := range bools.Children(ast) {
+				walk(child)
+			}
+			return
+		}
+		attr := ""
+		if unparsed, ok := lhs.(parse.Unparsed); ok {
+			attr = strings.ReplaceAll(unparsed.String(), "'", "")
+		}
+		if !seen[attr] {
+			seen[attr] = true
+			result = append(result, attr)
+		}
+	}
+	walk(ast)
+	return result
+}
+
+// Present returns a rule which matches the records having every one of the provided attributes, whatever their value.
+// It matches every record if no attribute is provided.
+func Present(attrs ...string) parse.AST {
+	var clauses []parse.AST
+	for _, attr := range attrs {
+		equal := Predicate{Attr: attr, Op: OpEqual, Quoted: true}
+		notEqual := Predicate{Attr: attr, Op: OpNotEqual, Quoted: true}
+		clauses = append(clauses, &bools.BinExpr{LHS: equal.AST(), RHS: notEqual.AST(), Op: bools.OpOr})
+	}
+	if len(clauses) == 0 {
+		return parse.Unparsed{Contents: []string{"TRUE"}}
+	}
+	return bools.Chain(bools.OpAnd, clauses...)
+}
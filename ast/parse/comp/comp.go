@@ -36,6 +36,11 @@ func (e *EqualExpr) Parse(p parse.Parser) error {
 	return nil
 }
 
+// String renders the comparison using the default tokens.
+func (e *EqualExpr) String() string {
+	return operandString(e.LHS) + " " + e.Op.String() + " " + operandString(e.RHS)
+}
+
 // OrdinalExpr represents a ordinal expression.
 type OrdinalExpr struct {
 	LHS parse.AST
@@ -65,6 +70,20 @@ func (e *OrdinalExpr) Parse(p parse.Parser) error {
 	return nil
 }
 
+// String renders the comparison using the default tokens.
+func (e *OrdinalExpr) String() string {
+	return operandString(e.LHS) + " " + e.Op.String() + " " + operandString(e.RHS)
+}
+
+// operandString renders one side of a comparison, parenthesizing nested comparisons.
+func operandString(ast parse.AST) string {
+	switch ast.(type) {
+	case *EqualExpr, *OrdinalExpr:
+		return fmt.Sprintf("(%v)", ast)
+	}
+	return fmt.Sprint(ast)
+}
+
 // Op represents one of six possible comparison operations recognized by this grammar.
 type Op uint8
 
@@ -112,14 +131,12 @@ const (
 
 type ParserOpt func(*Parser)
 
-// Parser parses this grammar.
+// Parser parses this grammar. Parsers are safe for concurrent use.
 type Parser struct {
 	config          map[Token]string
 	caseInsensitive bool
 
 	matcher *parse.KeywordTrie
-	tokens  []string
-	curr    int
 }
 
 // WithTokens configures the syntax used by this parser using the provided token mapping. The provided map must contain
@@ -170,13 +187,15 @@ func (p *Parser) init() error {
 	if p.config[OpenParen] == p.config[CloseParen] {
 		return fmt.Errorf("%w: OpenParen and CloseParen must each be distinct", parse.ErrConfig)
 	}
-	if p.caseInsensitive {
-		newTokens := make(map[Token]string, len(p.config))
-		for token, str := range p.config {
-			newTokens[token] = strings.ToLower(str)
+	// copy the tokens, so that later changes to a map passed to WithTokens cannot affect the parser
+	newTokens := make(map[Token]string, len(p.config))
+	for token, str := range p.config {
+		if p.caseInsensitive {
+			str = strings.ToLower(str)
 		}
-		p.config = newTokens
+		newTokens[token] = str
 	}
+	p.config = newTokens
 	for _, str := range p.config {
 		p.matcher.Add(str)
 	}
@@ -194,18 +213,24 @@ func (p *Parser) ParseStr(str string) (parse.AST, error) {
 // Parse parses the provided list of tokens, producing a parse.AST. An error is returned if the provided tokens do not
 // conform to the grammar specified in this package.
 func (p *Parser) Parse(tokens []string) (parse.AST, error) {
-	p.curr = 0
-	p.tokens = tokens
-	ast, err := p.parseExpr()
+	s := &state{Parser: p, tokens: tokens}
+	ast, err := s.parseExpr()
 	if err != nil {
 		return nil, err
 	}
-	if p.curr != len(p.tokens) {
-		return nil, fmt.Errorf("%w: expected end of expression; found '%s'", parse.ErrParse, p.tokens[p.curr])
+	if s.curr != len(s.tokens) {
+		return nil, fmt.Errorf("%w: expected end of expression; found '%s'", parse.ErrParse, s.tokens[s.curr])
 	}
 	return ast, nil
 }
 
+// state tracks the tokens consumed by one call to Parse.
+type state struct {
+	*Parser
+	tokens []string
+	curr   int
+}
+
 func (p *Parser) tokenize(str string) []string {
 	openP, closeP := []rune(p.config[OpenParen])[0], []rune(p.config[CloseParen])[0]
 	return parse.Tokenize(str, openP, closeP, p.matcher)
@@ -218,7 +243,7 @@ func (p *Parser) isKeyword(str string) bool {
 	return p.matcher.Contains(str)
 }
 
-func (p *Parser) match(token Token) bool {
+func (p *state) match(token Token) bool {
 	if p.curr == len(p.tokens) {
 		return false
 	}
@@ -233,15 +258,15 @@ func (p *Parser) match(token Token) bool {
 	return false
 }
 
-func (p *Parser) peek() string {
+func (p *state) peek() string {
 	return p.tokens[p.curr]
 }
 
-func (p *Parser) parseExpr() (parse.AST, error) {
+func (p *state) parseExpr() (parse.AST, error) {
 	return p.parseEqual()
 }
 
-func (p *Parser) parseEqual() (parse.AST, error) {
+func (p *state) parseEqual() (parse.AST, error) {
 	lhs, err := p.parseOrdinal()
 	if err != nil {
 		return nil, err
@@ -256,7 +281,7 @@ func (p *Parser) parseEqual() (parse.AST, error) {
 	return lhs, nil
 }
 
-func (p *Parser) parseOrdinal() (parse.AST, error) {
+func (p *state) parseOrdinal() (parse.AST, error) {
 	lhs, err := p.parseTerm()
 	if err != nil {
 		return nil, err
@@ -271,7 +296,7 @@ func (p *Parser) parseOrdinal() (parse.AST, error) {
 	return lhs, nil
 }
 
-func (p *Parser) parseTerm() (parse.AST, error) {
+func (p *state) parseTerm() (parse.AST, error) {
 	if p.match(OpenParen) {
 		ast, err := p.parseExpr()
 		if err != nil {
@@ -285,7 +310,7 @@ func (p *Parser) parseTerm() (parse.AST, error) {
 	return p.parseRest()
 }
 
-func (p *Parser) parseRest() (parse.AST, error) {
+func (p *state) parseRest() (parse.AST, error) {
 	var result []string
 	for p.curr < len(p.tokens) && !p.isKeyword(p.peek()) {
 		result = append(result, p.peek())
@@ -298,7 +323,7 @@ func (p *Parser) parseRest() (parse.AST, error) {
 }
 
 // matchOps attempts to match all of the provided ops in order, returning the first one matched. If none match, 0 is returned.
-func (p *Parser) matchOps(ops ...Token) Token {
+func (p *state) matchOps(ops ...Token) Token {
 	for _, op := range ops {
 		if p.match(op) {
 			return op
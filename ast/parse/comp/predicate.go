@@ -0,0 +1,147 @@
+package comp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+)
+
+// Predicate is the flattened form of a comparison between an attribute on the left-hand side and a literal on the
+// right-hand side, which is the only shape of comparison the rule engine evaluates.
+type Predicate struct {
+	Attr   string // Attr is the attribute name, with quotes removed.
+	Op     Op
+	Value  string // Value is the literal, with quotes removed.
+	Quoted bool   // Quoted reports whether the literal was written as a quoted string.
+}
+
+// AsPredicate returns the Predicate represented by the provided node. The second result is false if the node is not an
+// EqualExpr or OrdinalExpr with unparsed operands on both sides.
+func AsPredicate(ast parse.AST) (Predicate, bool) {
+	var lhs, rhs parse.AST
+	var op Op
+	switch ast := ast.(type) {
+	case *EqualExpr:
+		lhs, rhs, op = ast.LHS, ast.RHS, ast.Op
+	case *OrdinalExpr:
+		lhs, rhs, op = ast.LHS, ast.RHS, ast.Op
+	default:
+		return Predicate{}, false
+	}
+	attr, ok := lhs.(parse.Unparsed)
+	if !ok {
+		return Predicate{}, false
+	}
+	lit, ok := rhs.(parse.Unparsed)
+	if !ok {
+		return Predicate{}, false
+	}
+	value := lit.String()
+	return Predicate{
+		Attr:   strings.ReplaceAll(attr.String(), "'", ""),
+		Op:     op,
+		Value:  strings.ReplaceAll(value, "'", ""),
+		Quoted: strings.HasPrefix(value, "'"),
+	}, true
+}
+
+// AST returns the comparison node for this predicate.
+func (p Predicate) AST() parse.AST {
+	lhs := parse.Unparsed{Contents: strings.Fields(p.Attr)}
+	value := p.Value
+	if p.Quoted {
+		value = "'" + value + "'"
+	}
+	rhs := parse.Unparsed{Contents: strings.Fields(value)}
+	switch p.Op {
+	case OpEqual, OpNotEqual:
+		return &EqualExpr{LHS: lhs, RHS: rhs, Op: p.Op}
+	}
+	return &OrdinalExpr{LHS: lhs, RHS: rhs, Op: p.Op}
+}
+
+// String renders the predicate using the default tokens.
+func (p Predicate) String() string {
+	return fmt.Sprint(p.AST())
+}
+
+// Holds reports whether the comparison matches a record in which the attribute has the provided value, or is missing
+// if present is false, the way the rule engine evaluates it: a comparison on a missing attribute never matches,
+// equality compares strings and ordinal comparisons only match numbers.
+func (p Predicate) Holds(val string, present bool) bool {
+	if !present {
+		return false
+	}
+	switch p.Op {
+	case OpEqual:
+		return val == p.Value
+	case OpNotEqual:
+		return val != p.Value
+	}
+	lit, ok := p.Number()
+	num, isNum := Number(val)
+	if !ok || !isNum {
+		return false
+	}
+	switch p.Op {
+	case OpGreater:
+		return num > lit
+	case OpGreaterOrEqual:
+		return num >= lit
+	case OpLess:
+		return num < lit
+	case OpLessOrEqual:
+		return num <= lit
+	}
+	return false
+}
+
+// Number parses the literal the way ordinal comparisons do. The second result is false if the literal is not a number.
+func (p Predicate) Number() (float32, bool) {
+	return Number(p.Value)
+}
+
+// Literal returns the literal as a typed value for translation into other query languages. Quoted literals are
+// strings, while unquoted ones are returned as an int64, float64 or bool where they parse as such, and as a string
+// otherwise.
+func (p Predicate) Literal() any {
+	if p.Quoted {
+		return p.Value
+	}
+	if p.Value == "true" || p.Value == "false" {
+		return p.Value == "true"
+	}
+	if n, ok := p.NumericLiteral(); ok {
+		return n
+	}
+	return p.Value
+}
+
+// NumericLiteral returns the literal as an int64 or float64, as used by ordinal comparisons regardless of quoting. The
+// second result is false if the literal is not a finite number.
+func (p Predicate) NumericLiteral() (any, bool) {
+	if i, err := strconv.ParseInt(p.Value, 10, 64); err == nil {
+		return i, true
+	}
+	if _, ok := p.Number(); !ok {
+		return nil, false
+	}
+	f, err := strconv.ParseFloat(p.Value, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, false
+	}
+	return f, true
+}
+
+// Number parses a value the way ordinal comparisons do, as a 32-bit float. The second result is false if the value
+// is not a number.
+func Number(s string) (float32, bool) {
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return 0, false
+	}
+	return float32(f), true
+}
@@ -0,0 +1,38 @@
+package comp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredicateHolds(t *testing.T) {
+
+	testCases := []struct {
+		desc     string
+		pred     Predicate
+		val      string
+		present  bool
+		expected bool
+	}{
+		{desc: "equal", pred: Predicate{Attr: "dept", Op: OpEqual, Value: "Sales"}, val: "Sales", present: true, expected: true},
+		{desc: "equal compares strings", pred: Predicate{Attr: "age", Op: OpEqual, Value: "30"}, val: "30.0", present: true},
+		{desc: "not equal", pred: Predicate{Attr: "dept", Op: OpNotEqual, Value: "Sales"}, val: "HR", present: true, expected: true},
+		{desc: "not equal on missing attribute", pred: Predicate{Attr: "dept", Op: OpNotEqual, Value: "Sales"}},
+		{desc: "greater", pred: Predicate{Attr: "age", Op: OpGreater, Value: "30"}, val: "30.5", present: true, expected: true},
+		{desc: "greater at bound", pred: Predicate{Attr: "age", Op: OpGreater, Value: "30"}, val: "30", present: true},
+		{desc: "greater or equal at bound", pred: Predicate{Attr: "age", Op: OpGreaterOrEqual, Value: "30"}, val: "30.0", present: true, expected: true},
+		{desc: "less", pred: Predicate{Attr: "age", Op: OpLess, Value: "30"}, val: "1e1", present: true, expected: true},
+		{desc: "less or equal", pred: Predicate{Attr: "age", Op: OpLessOrEqual, Value: "30"}, val: "31", present: true},
+		{desc: "ordinal compares 32-bit floats", pred: Predicate{Attr: "age", Op: OpGreater, Value: "30"}, val: "30.0000001", present: true},
+		{desc: "ordinal on non-numeric value", pred: Predicate{Attr: "age", Op: OpLess, Value: "30"}, val: "young", present: true},
+		{desc: "ordinal against non-numeric literal", pred: Predicate{Attr: "age", Op: OpGreater, Value: "thirty"}, val: "31", present: true},
+		{desc: "ordinal on missing attribute", pred: Predicate{Attr: "age", Op: OpLessOrEqual, Value: "30"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.pred.Holds(tt.val, tt.present))
+		})
+	}
+}
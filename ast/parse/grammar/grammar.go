@@ -0,0 +1,48 @@
+// Package grammar parses rule text with the rule engine's two parsers: the boolean parser splits a rule into its
+// boolean structure, and the comparison parser then parses the comparisons left unparsed.
+package grammar
+
+import (
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// parsers use the default syntax. Parsers are safe for concurrent use, so they are created once.
+var parsers = struct {
+	bools *bools.Parser
+	comp  *comp.Parser
+}{bools: must(bools.NewParser()), comp: must(comp.NewParser())}
+
+func must[T any](p T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Parse parses a rule written in the default syntax.
+func Parse(rule string) (parse.AST, error) {
+	ast, err := parsers.bools.ParseStr(rule)
+	if err != nil {
+		return nil, err
+	}
+	return ParseComparisons(ast, parsers.comp)
+}
+
+// MustParse is Parse for rules known to be valid, such as those in tests. It panics if the rule cannot be parsed.
+func MustParse(rule string) parse.AST {
+	return must(Parse(rule))
+}
+
+// ParseComparisons runs the comparison parser on the nodes a boolean parser left unparsed, and returns the resulting
+// rule. A rule made of a single comparison has no boolean operator, so its root itself is unparsed and is replaced.
+func ParseComparisons(ast parse.AST, p *comp.Parser) (parse.AST, error) {
+	if unparsed, ok := ast.(parse.Unparsed); ok {
+		return p.Parse(unparsed.Contents)
+	}
+	if err := ast.Parse(p); err != nil {
+		return nil, err
+	}
+	return ast, nil
+}
@@ -0,0 +1,54 @@
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/comp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		rule         string
+		expectedRule string
+		expectedErr  error
+	}{
+		{desc: "single comparison", rule: "age > 30", expectedRule: "age > 30"},
+		{desc: "boolean structure", rule: "age > 30 AND NOT (dept == 'Sales')", expectedRule: "age > 30 AND NOT (dept == 'Sales')"},
+		{desc: "list", rule: "XOR(a == 1, b == 2)", expectedRule: "EXACTLY(1, a == 1, b == 2)"},
+		{desc: "invalid boolean structure", rule: "age > 30 AND", expectedErr: parse.ErrParse},
+		{desc: "invalid comparison", rule: "age > 30 AND dept ==", expectedErr: parse.ErrParse},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast, err := Parse(tt.rule)
+			if tt.expectedErr != nil {
+				assert.True(t, errors.Is(err, tt.expectedErr), "unexpected error: %v", err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(ast))
+		})
+	}
+}
+
+func TestParseComparisonsReplacesUnparsedRoot(t *testing.T) {
+	p, err := comp.NewParser()
+	assert.Nil(t, err)
+	ast, err := ParseComparisons(parse.Unparsed{Contents: []string{"age", ">", "30"}}, p)
+	assert.Nil(t, err)
+	_, ok := comp.AsPredicate(ast)
+	assert.True(t, ok)
+}
+
+func TestMustParse(t *testing.T) {
+	assert.Equal(t, "age > 30", fmt.Sprint(MustParse("age > 30")))
+	assert.Panics(t, func() { MustParse("age > 30 AND") })
+}
@@ -0,0 +1,501 @@
+// Package ranges works out which values of each attribute let a rule match.
+//
+// The rule is converted to disjunctive normal form, and each branch is analysed on its own: within a conjunction, the
+// comparisons on one attribute only constrain that attribute. Branches which differ in the values allowed for a
+// single attribute are then merged, so age > 30 AND (dept == 'A' OR dept == 'B') gives one branch in which
+// age ∈ (30, ∞) and dept ∈ {A, B}.
+package ranges
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/normal"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrUnsupported is returned for comparisons which are not between an attribute and a literal.
+var ErrUnsupported = errors.New("unsupported construct")
+
+// Interval is a range of numbers. An empty Min or Max leaves that side unbounded.
+type Interval struct {
+	Min          string `json:"min,omitempty"`
+	MinInclusive bool   `json:"min_inclusive,omitempty"`
+	Max          string `json:"max,omitempty"`
+	MaxInclusive bool   `json:"max_inclusive,omitempty"`
+}
+
+// Range describes the values of one attribute for which a branch can match. If Values is set, the attribute must
+// hold one of them. Otherwise it may hold any number within Interval, when Interval is set, and any value which is not
+// a number, NaN included, if NonNumeric is set, except for the Excluded values. Missing tells whether the branch also matches records
+// without the attribute.
+type Range struct {
+	Attribute   string    `json:"attribute"`
+	Values      []string  `json:"values,omitempty"`
+	Interval    *Interval `json:"interval,omitempty"`
+	NonNumeric  bool      `json:"non_numeric,omitempty"`
+	Excluded    []string  `json:"excluded,omitempty"`
+	Missing     bool      `json:"missing,omitempty"`
+	Description string    `json:"description"`
+}
+
+// Branch is a set of ranges which together let the rule match: a record matches the branch if every attribute it
+// lists is within its range. Attributes which are not listed can hold anything.
+type Branch struct {
+	Ranges []Range `json:"ranges"`
+}
+
+// Matches reports whether the record is within every range of the branch.
+func (b Branch) Matches(record map[string]string) bool {
+	for _, r := range b.Ranges {
+		val, present := record[r.Attribute]
+		if !r.Allows(val, present) {
+			return false
+		}
+	}
+	return true
+}
+
+// Allows reports whether the range allows a missing attribute, or the provided value.
+func (r Range) Allows(val string, present bool) bool {
+	switch {
+	case !present:
+		return r.Missing
+	case len(r.Values) > 0:
+		return contains(r.Values, val)
+	case contains(r.Excluded, val):
+		return false
+	}
+	num, ok := comp.Number(val)
+	if !ok || math.IsNaN(float64(num)) {
+		return r.NonNumeric
+	}
+	return r.Interval != nil && r.Interval.contains(num)
+}
+
+func (i Interval) contains(num float32) bool {
+	if min, ok := comp.Number(i.Min); ok && (num < min || num == min && !i.MinInclusive) {
+		return false
+	}
+	if max, ok := comp.Number(i.Max); ok && (num > max || num == max && !i.MaxInclusive) {
+		return false
+	}
+	return true
+}
+
+// Analyze returns the branches of the rule, in the order of its disjunctive normal form. A rule which never matches
+// has no branch, and one which always matches has a single branch without ranges.
+func Analyze(ast parse.AST) ([]Branch, error) {
+	dnf, err := normal.Convert(ast, normal.DNF)
+	if err != nil {
+		return nil, err
+	}
+	if not, ok := dnf.(*bools.UnaryExpr); ok && not.Op == bools.OpNot {
+		if _, ok := not.Expr.(parse.Unparsed); ok {
+			return nil, nil
+		}
+	}
+
+	var branches [][]*state
+	for _, clause := range bools.Flatten(dnf, bools.OpOr) {
+		states, ok, err := analyzeClause(clause)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			branches = append(branches, states)
+		}
+	}
+	branches = mergeBranches(branches)
+
+	result := []Branch{}
+	for _, states := range branches {
+		branch := Branch{Ranges: []Range{}}
+		for _, s := range states {
+			branch.Ranges = append(branch.Ranges, s.toRange())
+		}
+		result = append(result, branch)
+	}
+	return result, nil
+}
+
+// literal is a comparison, or its negation, on one attribute.
+type literal struct {
+	pred    comp.Predicate
+	negated bool
+}
+
+// holds reports whether the literal holds for a record in which the attribute is missing, or has the provided value.
+func (l literal) holds(val string, present bool) bool {
+	return l.pred.Holds(val, present) != l.negated
+}
+
+// analyzeClause works out the range of each attribute of a DNF clause, in the order the attributes first appear. It
+// returns false if the clause cannot match.
+func analyzeClause(clause parse.AST) ([]*state, bool, error) {
+	var order []string
+	literals := map[string][]literal{}
+	for _, operand := range bools.Flatten(clause, bools.OpAnd) {
+		if _, ok := operand.(parse.Unparsed); ok {
+			continue
+		}
+		lit := literal{}
+		if not, ok := operand.(*bools.UnaryExpr); ok {
+			lit.negated, operand = true, not.Expr
+		}
+		pred, ok := comp.AsPredicate(operand)
+		if !ok {
+			return nil, false, fmt.Errorf("%w: comparison '%v' must have an attribute on the left and a literal on the right", ErrUnsupported, operand)
+		}
+		lit.pred = pred
+		if literals[pred.Attr] == nil {
+			order = append(order, pred.Attr)
+		}
+		literals[pred.Attr] = append(literals[pred.Attr], lit)
+	}
+
+	var states []*state
+	for _, attr := range order {
+		s := newState(attr, literals[attr])
+		if !s.present && !s.missing {
+			return nil, false, nil
+		}
+		states = append(states, s)
+	}
+	return states, true, nil
+}
+
+// bound is one side of an interval of float32 values.
+type bound struct {
+	value     float32
+	inclusive bool
+	lower     bool
+}
+
+func newBound(op comp.Op, value float32) bound {
+	switch op {
+	case comp.OpGreater:
+		return bound{value: value, lower: true}
+	case comp.OpGreaterOrEqual:
+		return bound{value: value, inclusive: true, lower: true}
+	case comp.OpLess:
+		return bound{value: value}
+	}
+	return bound{value: value, inclusive: true}
+}
+
+// complement returns the bound holding for the numbers this one does not hold for.
+func (b bound) complement() bound {
+	return bound{value: b.value, inclusive: !b.inclusive, lower: !b.lower}
+}
+
+func (b bound) allows(num float32) bool {
+	switch {
+	case b.lower && b.inclusive:
+		return num >= b.value
+	case b.lower:
+		return num > b.value
+	case b.inclusive:
+		return num <= b.value
+	}
+	return num < b.value
+}
+
+// tighter reports whether b excludes more numbers than other, which must be on the same side.
+func (b bound) tighter(other bound) bool {
+	if b.value != other.value {
+		return b.lower == (b.value > other.value)
+	}
+	return !b.inclusive && other.inclusive
+}
+
+// state is the range of one attribute while it is being worked out.
+type state struct {
+	attr       string
+	missing    bool
+	present    bool
+	restricted bool
+	values     []string
+	lo, hi     bound
+	nonNumeric bool
+	excluded   []string
+}
+
+func newState(attr string, lits []literal) *state {
+	inf := float32(math.Inf(1))
+	s := &state{
+		attr:       attr,
+		lo:         bound{value: -inf, inclusive: true, lower: true},
+		hi:         bound{value: inf, inclusive: true},
+		nonNumeric: true,
+	}
+	holdsAll := func(val string, present bool) bool {
+		for _, lit := range lits {
+			if !lit.holds(val, present) {
+				return false
+			}
+		}
+		return true
+	}
+	s.missing = holdsAll("", false)
+
+	// == and NOT (!=) restrict the attribute to their literals
+	for _, lit := range lits {
+		if lit.pred.Op == comp.OpEqual && !lit.negated || lit.pred.Op == comp.OpNotEqual && lit.negated {
+			s.restricted = true
+			if holdsAll(lit.pred.Value, true) && !contains(s.values, lit.pred.Value) {
+				s.values = append(s.values, lit.pred.Value)
+			}
+		}
+	}
+	if s.restricted {
+		s.present = len(s.values) > 0
+		return s
+	}
+
+	for _, lit := range lits {
+		if lit.pred.Op == comp.OpEqual || lit.pred.Op == comp.OpNotEqual {
+			if !contains(s.excluded, lit.pred.Value) {
+				s.excluded = append(s.excluded, lit.pred.Value)
+			}
+			continue
+		}
+		num, ok := comp.Number(lit.pred.Value)
+		if !ok || math.IsNaN(float64(num)) {
+			if !lit.negated {
+				// no number compares with a value which is not a number
+				s.lo, s.hi = bound{value: inf, lower: true}, bound{value: -inf}
+				s.nonNumeric = false
+			}
+			continue
+		}
+		b := newBound(lit.pred.Op, num)
+		if lit.negated {
+			b = b.complement()
+		} else {
+			s.nonNumeric = false
+		}
+		if b.lower && b.tighter(s.lo) {
+			s.lo = b
+		} else if !b.lower && b.tighter(s.hi) {
+			s.hi = b
+		}
+	}
+
+	// only keep the exclusions of values which would otherwise be allowed
+	var excluded []string
+	for _, val := range s.excluded {
+		if s.allows(val) {
+			excluded = append(excluded, val)
+		}
+	}
+	s.excluded = excluded
+	s.present = s.numbers() || s.nonNumeric
+	return s
+}
+
+func contains(values []string, val string) bool {
+	for _, v := range values {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// numbers reports whether any float32 is within the interval.
+func (s *state) numbers() bool {
+	lo, hi := s.lo.value, s.hi.value
+	if !s.lo.inclusive {
+		lo = math.Nextafter32(lo, float32(math.Inf(1)))
+	}
+	if !s.hi.inclusive {
+		hi = math.Nextafter32(hi, float32(math.Inf(-1)))
+	}
+	return lo <= hi
+}
+
+// allows reports whether a present value is within the interval or non-numeric values, ignoring the exclusions.
+func (s *state) allows(val string) bool {
+	num, ok := comp.Number(val)
+	if !ok || math.IsNaN(float64(num)) {
+		return s.nonNumeric
+	}
+	return s.lo.allows(num) && s.hi.allows(num)
+}
+
+func (s *state) key() string {
+	return fmt.Sprint(s.attr, s.missing, s.present, s.restricted, s.values, s.lo, s.hi, s.nonNumeric, s.excluded)
+}
+
+// union returns a state allowing the values of both states, or false if that cannot be expressed by a single state.
+func (s *state) union(other *state) (*state, bool) {
+	if s.missing != other.missing || s.restricted != other.restricted || !s.present || !other.present {
+		return nil, false
+	}
+	result := *s
+	if s.restricted {
+		result.values = append([]string{}, s.values...)
+		for _, val := range other.values {
+			if !contains(result.values, val) {
+				result.values = append(result.values, val)
+			}
+		}
+		return &result, true
+	}
+
+	if s.nonNumeric != other.nonNumeric || fmt.Sprint(s.excluded) != fmt.Sprint(other.excluded) {
+		return nil, false
+	}
+	// the intervals must overlap or touch for their union to be an interval
+	first, second := s, other
+	if s.lo.tighter(other.lo) {
+		first, second = other, s
+	}
+	if first.hi.value < second.lo.value ||
+		first.hi.value == second.lo.value && !first.hi.inclusive && !second.lo.inclusive {
+		return nil, false
+	}
+	result.lo, result.hi = first.lo, first.hi
+	if first.hi.tighter(second.hi) {
+		result.hi = second.hi
+	}
+	return &result, true
+}
+
+// mergeBranches repeatedly merges pairs of branches with the same ranges for all attributes but one.
+func mergeBranches(branches [][]*state) [][]*state {
+	for {
+		merged := false
+		for i := 0; i < len(branches) && !merged; i++ {
+			for j := i + 1; j < len(branches) && !merged; j++ {
+				if result, ok := mergePair(branches[i], branches[j]); ok {
+					branches[i] = result
+					branches = append(branches[:j], branches[j+1:]...)
+					merged = true
+				}
+			}
+		}
+		if !merged {
+			return branches
+		}
+	}
+}
+
+func mergePair(a, b []*state) ([]*state, bool) {
+	if len(a) != len(b) {
+		return nil, false
+	}
+	others := map[string]*state{}
+	for _, s := range b {
+		others[s.attr] = s
+	}
+	differing := -1
+	for idx, s := range a {
+		other, ok := others[s.attr]
+		if !ok {
+			return nil, false
+		}
+		if s.key() != other.key() {
+			if differing >= 0 {
+				return nil, false
+			}
+			differing = idx
+		}
+	}
+	if differing < 0 {
+		return a, true
+	}
+	union, ok := a[differing].union(others[a[differing].attr])
+	if !ok {
+		return nil, false
+	}
+	result := append([]*state{}, a...)
+	result[differing] = union
+	return result, true
+}
+
+func formatNumber(num float32) string {
+	return strconv.FormatFloat(float64(num), 'g', -1, 32)
+}
+
+func (s *state) toRange() Range {
+	r := Range{Attribute: s.attr, Missing: s.missing}
+	if s.present {
+		if s.restricted {
+			r.Values = s.values
+		} else {
+			r.NonNumeric, r.Excluded = s.nonNumeric, s.excluded
+			if s.numbers() {
+				r.Interval = &Interval{MinInclusive: s.lo.inclusive, MaxInclusive: s.hi.inclusive}
+				if !math.IsInf(float64(s.lo.value), -1) {
+					r.Interval.Min = formatNumber(s.lo.value)
+				} else {
+					r.Interval.MinInclusive = false
+				}
+				if !math.IsInf(float64(s.hi.value), 1) {
+					r.Interval.Max = formatNumber(s.hi.value)
+				} else {
+					r.Interval.MaxInclusive = false
+				}
+			}
+		}
+	}
+	r.Description = r.describe()
+	return r
+}
+
+// describe renders the range in set notation, such as age ∈ (30, ∞) or dept ∈ {A, B}.
+func (r Range) describe() string {
+	var parts []string
+	switch {
+	case len(r.Values) > 0:
+		values := append([]string{}, r.Values...)
+		sort.Strings(values)
+		parts = append(parts, "{"+strings.Join(values, ", ")+"}")
+	case r.Interval != nil && r.NonNumeric && r.Interval.Min == "" && r.Interval.Max == "":
+		parts = append(parts, "any value")
+	default:
+		if r.Interval != nil {
+			parts = append(parts, r.Interval.String())
+		}
+		if r.NonNumeric {
+			parts = append(parts, "non-numbers")
+		}
+	}
+	if r.Missing {
+		if len(parts) == 0 {
+			return r.Attribute + " is missing"
+		}
+		parts = append(parts, "missing")
+	}
+	desc := r.Attribute + " ∈ " + strings.Join(parts, " ∪ ")
+	if len(r.Excluded) > 0 {
+		desc += " \\ {" + strings.Join(r.Excluded, ", ") + "}"
+	}
+	return desc
+}
+
+// String renders the interval in interval notation, such as (30, ∞).
+func (i Interval) String() string {
+	lo, hi := "(-∞", "∞)"
+	if i.Min != "" {
+		lo = "(" + i.Min
+		if i.MinInclusive {
+			lo = "[" + i.Min
+		}
+	}
+	if i.Max != "" {
+		hi = i.Max + ")"
+		if i.MaxInclusive {
+			hi = i.Max + "]"
+		}
+	}
+	return lo + ", " + hi
+}
@@ -0,0 +1,162 @@
+package ranges
+
+import (
+	"math/rand"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var records = func() []map[string]string {
+	var result []map[string]string
+	for _, age := range []string{"", "19", "20", "25", "30", "30.0", "31", "NaN", "thirty"} {
+		for _, dept := range []string{"", "A", "B", "C"} {
+			record := map[string]string{}
+			if age != "" {
+				record["age"] = age
+			}
+			if dept != "" {
+				record["dept"] = dept
+			}
+			result = append(result, record)
+		}
+	}
+	return result
+}()
+
+// assertBranchesMatch checks that a record is matched by the rule exactly when it is within one of the branches.
+func assertBranchesMatch(t *testing.T, rule string, ast parse.AST, branches []Branch) bool {
+	match, err := compile.Compile(ast)
+	assert.Nil(t, err)
+	for _, record := range records {
+		inBranch := false
+		for _, branch := range branches {
+			inBranch = inBranch || branch.Matches(record)
+		}
+		if !assert.Equal(t, match(record), inBranch, "rule %q, record %v, branches %v", rule, record, branches) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAnalyze(t *testing.T) {
+
+	testCases := []struct {
+		desc                 string
+		ruleString           string
+		expectedDescriptions [][]string
+	}{
+		{
+			desc:                 "readme example",
+			ruleString:           "age > 30 AND (dept == 'A' OR dept == 'B')",
+			expectedDescriptions: [][]string{{"age ∈ (30, ∞)", "dept ∈ {A, B}"}},
+		},
+		{
+			desc:                 "bounds are intersected",
+			ruleString:           "age > 20 AND age <= 60 AND age >= 30",
+			expectedDescriptions: [][]string{{"age ∈ [30, 60]"}},
+		},
+		{
+			desc:                 "separate branches",
+			ruleString:           "(age < 20 AND dept == 'A') OR (age > 30 AND dept == 'B')",
+			expectedDescriptions: [][]string{{"age ∈ (-∞, 20)", "dept ∈ {A}"}, {"age ∈ (30, ∞)", "dept ∈ {B}"}},
+		},
+		{
+			desc:                 "overlapping intervals are merged",
+			ruleString:           "age < 25 OR (age >= 20 AND age < 40)",
+			expectedDescriptions: [][]string{{"age ∈ (-∞, 40)"}},
+		},
+		{
+			desc:                 "negated bound also allows non-numbers and missing attributes",
+			ruleString:           "NOT (age > 30)",
+			expectedDescriptions: [][]string{{"age ∈ (-∞, 30] ∪ non-numbers ∪ missing"}},
+		},
+		{
+			desc:                 "inequality",
+			ruleString:           "dept != 'A' AND dept != 'B'",
+			expectedDescriptions: [][]string{{"dept ∈ any value \\ {A, B}"}},
+		},
+		{
+			desc:                 "negated inequality",
+			ruleString:           "NOT (dept != 'A')",
+			expectedDescriptions: [][]string{{"dept ∈ {A} ∪ missing"}},
+		},
+		{
+			desc:                 "equality filtered by bounds",
+			ruleString:           "(age == 25 OR age == 35 OR age == 'x') AND age > 30",
+			expectedDescriptions: [][]string{{"age ∈ {35}"}},
+		},
+		{
+			desc:                 "only missing",
+			ruleString:           "NOT (dept == 'A' OR dept != 'A')",
+			expectedDescriptions: [][]string{{"dept is missing"}},
+		},
+		{
+			desc:                 "never matches",
+			ruleString:           "age > 40 AND age < 30",
+			expectedDescriptions: [][]string{},
+		},
+		{
+			desc:                 "always matches",
+			ruleString:           "active",
+			expectedDescriptions: [][]string{{}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := grammar.MustParse(tt.ruleString)
+			branches, err := Analyze(ast)
+			assert.Nil(t, err)
+
+			descriptions := [][]string{}
+			for _, branch := range branches {
+				ranges := []string{}
+				for _, r := range branch.Ranges {
+					ranges = append(ranges, r.Description)
+				}
+				descriptions = append(descriptions, ranges)
+			}
+			assert.Equal(t, tt.expectedDescriptions, descriptions)
+			assertBranchesMatch(t, tt.ruleString, ast, branches)
+		})
+	}
+}
+
+func TestAnalyzeRandomRules(t *testing.T) {
+
+	atoms := []string{
+		"age > 30", "age >= 30", "age < 30", "age <= 20", "age == 30", "age == 'thirty'", "age != 30",
+		"age > 'x'", "dept == 'A'", "dept == 'B'", "dept != 'A'", "flag",
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	var build func(depth int) string
+	build = func(depth int) string {
+		switch n := rnd.Intn(6); {
+		case depth == 0 || n < 2:
+			return atoms[rnd.Intn(len(atoms))]
+		case n == 2:
+			return "NOT (" + build(depth-1) + ")"
+		case n == 3:
+			return "(" + build(depth-1) + ") OR (" + build(depth-1) + ")"
+		default:
+			return "(" + build(depth-1) + ") AND (" + build(depth-1) + ")"
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+		rule := build(4)
+		ast := grammar.MustParse(rule)
+		branches, err := Analyze(ast)
+		assert.Nil(t, err)
+		if !assertBranchesMatch(t, rule, ast, branches) {
+			return
+		}
+	}
+}
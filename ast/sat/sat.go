@@ -0,0 +1,354 @@
+// Package sat decides whether a rule can match any record, following the rule engine's semantics, and finds a record
+// it matches when one exists.
+//
+// Rules are put into negation normal form, keeping negated comparisons as they are: NOT (age > 30) holds when age is
+// missing or not a number, so it is not the same as age <= 30. The boolean structure is then searched branch by branch
+// for a set of comparisons and negated comparisons which can hold together. Attributes are independent of each other,
+// so each set is satisfiable if a value can be found for every attribute. The values tried are enough to cover every
+// case the comparisons can tell apart: a missing attribute, every literal compared against, numbers on either side of
+// every bound, as the engine compares them as 32-bit floats, and a string which is not a number.
+package sat
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/expand"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrLimit is returned when a rule has too many branches to be decided within the search limit.
+var ErrLimit = errors.New("search limit exceeded")
+
+// DefaultLimit is the number of branches Solve explores before giving up.
+const DefaultLimit = 100000
+
+// Solve reports whether any record matches the rule, and returns such a record if one does. Attributes missing from
+// the record are not set in it.
+func Solve(ast parse.AST) (map[string]string, bool, error) {
+	return SolveLimit(ast, DefaultLimit)
+}
+
+// SolveLimit is Solve with a custom limit on the number of branches explored.
+func SolveLimit(ast parse.AST, limit int) (map[string]string, bool, error) {
+	return NewSolver(limit).Solve(ast)
+}
+
+// Valid reports whether the rule matches every record. If it does not, a record it does not match is returned.
+func Valid(ast parse.AST) (map[string]string, bool, error) {
+	return NewSolver(DefaultLimit).Valid(ast)
+}
+
+// Solver solves several rules within one limit on the number of branches explored by all of them together.
+type Solver struct {
+	limit    int
+	branches int
+	exceeded bool
+}
+
+// NewSolver returns a Solver which explores at most limit branches in total.
+func NewSolver(limit int) *Solver {
+	return &Solver{limit: limit}
+}
+
+// Solve is the package's Solve, counting the branches explored against the solver's limit.
+func (s *Solver) Solve(ast parse.AST) (map[string]string, bool, error) {
+	node, err := nnf(ast, false)
+	if err != nil {
+		return nil, false, err
+	}
+	s.exceeded = false
+	witness, ok := s.search([]*formula{node}, map[string][]literal{})
+	if s.exceeded {
+		return nil, false, fmt.Errorf("%w: more than %d branches", ErrLimit, s.limit)
+	}
+	return witness, ok, nil
+}
+
+// Valid is the package's Valid, counting the branches explored against the solver's limit.
+func (s *Solver) Valid(ast parse.AST) (map[string]string, bool, error) {
+	counter, ok, err := s.Solve(&bools.UnaryExpr{Op: bools.OpNot, Expr: ast})
+	return counter, !ok, err
+}
+
+// literal is a comparison, or its negation, on one attribute.
+type literal struct {
+	pred    comp.Predicate
+	negated bool
+}
+
+// holds reports whether the literal holds for a record in which the attribute is missing, or has the provided value.
+func (l literal) holds(val string, present bool) bool {
+	return l.pred.Holds(val, present) != l.negated
+}
+
+// formula is a rule in negation normal form.
+type formula struct {
+	op       bools.Op // op is OpAnd or OpOr for chains, and 0 for literals, constants and counts
+	operands []*formula
+	lit      *literal
+	constant bool // constant is the value of a node with no operands and no literal
+	count    bool // count is set for nodes holding when at least k of their operands hold
+	k        int
+}
+
+func atLeast(k int, operands []*formula) *formula {
+	return &formula{operands: operands, count: true, k: k}
+}
+
+// unfold rewrites a count node by branching on its first operand: either it holds along with k-1 of the others, or k
+// of the others hold.
+func (f *formula) unfold() *formula {
+	switch {
+	case f.k <= 0:
+		return &formula{constant: true}
+	case f.k > len(f.operands):
+		return &formula{constant: false}
+	}
+	first, rest := f.operands[0], f.operands[1:]
+	return &formula{op: bools.OpOr, operands: []*formula{
+		{op: bools.OpAnd, operands: []*formula{first, atLeast(f.k-1, rest)}},
+		atLeast(f.k, rest),
+	}}
+}
+
+// nnf converts a rule, negated if negate is set, into negation normal form.
+func nnf(ast parse.AST, negate bool) (*formula, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		op := ast.Op
+		if op != bools.OpAnd && op != bools.OpOr {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, op)
+		}
+		if negate && op == bools.OpAnd {
+			op = bools.OpOr
+		} else if negate {
+			op = bools.OpAnd
+		}
+		f := &formula{op: op}
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			child, err := nnf(operand, negate)
+			if err != nil {
+				return nil, err
+			}
+			f.operands = append(f.operands, child)
+		}
+		return f, nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		return nnf(ast.Expr, !negate)
+	case *bools.AtLeastExpr:
+		operands, err := nnfAll(ast.Children, negate)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			// fewer than k match when more than n-k do not
+			return atLeast(len(operands)-ast.K+1, operands), nil
+		}
+		return atLeast(ast.K, operands), nil
+	case *bools.ExactlyExpr:
+		matching, err := nnfAll(ast.Children, false)
+		if err != nil {
+			return nil, err
+		}
+		failing, err := nnfAll(ast.Children, true)
+		if err != nil {
+			return nil, err
+		}
+		n := len(matching)
+		if negate {
+			return &formula{op: bools.OpOr, operands: []*formula{atLeast(ast.K+1, matching), atLeast(n-ast.K+1, failing)}}, nil
+		}
+		return &formula{op: bools.OpAnd, operands: []*formula{atLeast(ast.K, matching), atLeast(n-ast.K, failing)}}, nil
+	case *bools.PriorityExpr:
+		return nnf(expand.Priority(ast), negate)
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		return &formula{lit: &literal{pred: predicate(ast), negated: negate}}, nil
+	case parse.Unparsed:
+		// bare terms always match
+		return &formula{constant: !negate}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func nnfAll(children []parse.AST, negate bool) ([]*formula, error) {
+	var result []*formula
+	for _, child := range children {
+		f, err := nnf(child, negate)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// predicate returns the comparison the rule engine evaluates for a comparison node. Like the engine, it resolves
+// operands which are not unparsed to the empty string, so comparisons on nested comparisons are handled too.
+func predicate(ast parse.AST) comp.Predicate {
+	if pred, ok := comp.AsPredicate(ast); ok {
+		return pred
+	}
+	var lhs, rhs parse.AST
+	var op comp.Op
+	switch ast := ast.(type) {
+	case *comp.EqualExpr:
+		lhs, rhs, op = ast.LHS, ast.RHS, ast.Op
+	case *comp.OrdinalExpr:
+		lhs, rhs, op = ast.LHS, ast.RHS, ast.Op
+	}
+	operand := func(ast parse.AST) string {
+		if unparsed, ok := ast.(parse.Unparsed); ok {
+			return strings.ReplaceAll(unparsed.String(), "'", "")
+		}
+		return ""
+	}
+	return comp.Predicate{Attr: operand(lhs), Op: op, Value: operand(rhs)}
+}
+
+// search looks for a record satisfying all the formulas in todo as well as the literals already chosen, which are
+// kept per attribute. Literals, constants and conjunctions are all taken in before branching on any disjunction, so a
+// conflict among them is found once rather than in every branch.
+func (s *Solver) search(todo []*formula, lits map[string][]literal) (map[string]string, bool) {
+	var ors []*formula
+	for len(todo) > 0 {
+		f := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if f.count {
+			f = f.unfold()
+		}
+
+		switch {
+		case f.op == bools.OpAnd:
+			todo = append(todo, f.operands...)
+		case f.op == bools.OpOr:
+			ors = append(ors, f)
+		case f.lit != nil:
+			attr := f.lit.pred.Attr
+			lits[attr] = append(lits[attr], *f.lit)
+			if _, _, ok := value(lits[attr]); !ok {
+				return nil, false
+			}
+		case !f.constant:
+			return nil, false
+		}
+	}
+
+	if len(ors) > 0 {
+		f, rest := ors[len(ors)-1], ors[:len(ors)-1]
+		for _, operand := range f.operands {
+			s.branches++
+			if s.branches > s.limit {
+				s.exceeded = true
+				return nil, false
+			}
+			branch := append(append([]*formula{}, rest...), operand)
+			if witness, ok := s.search(branch, copyLiterals(lits)); ok {
+				return witness, true
+			}
+			if s.exceeded {
+				return nil, false
+			}
+		}
+		return nil, false
+	}
+
+	witness := map[string]string{}
+	for attr, attrLits := range lits {
+		if val, present, _ := value(attrLits); present {
+			witness[attr] = val
+		}
+	}
+	return witness, true
+}
+
+func copyLiterals(lits map[string][]literal) map[string][]literal {
+	result := make(map[string][]literal, len(lits))
+	for attr, attrLits := range lits {
+		result[attr] = append([]literal{}, attrLits...)
+	}
+	return result
+}
+
+// value finds a value for one attribute which satisfies all the provided literals, which must be on that attribute.
+// The second result is false if the attribute should be missing, and the third is false if no value satisfies the
+// literals.
+func value(lits []literal) (string, bool, bool) {
+	satisfies := func(val string, present bool) bool {
+		for _, lit := range lits {
+			if !lit.holds(val, present) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if satisfies("", false) {
+		return "", false, true
+	}
+	for _, val := range candidates(lits) {
+		if satisfies(val, true) {
+			return val, true, true
+		}
+	}
+	return "", false, false
+}
+
+// candidates returns present values covering every combination of results the literals can tell apart.
+func candidates(lits []literal) []string {
+	literals := map[string]bool{}
+	var result []string
+	var thresholds []float32
+	for _, lit := range lits {
+		literals[lit.pred.Value] = true
+		result = append(result, lit.pred.Value)
+		if lit.pred.Op != comp.OpEqual && lit.pred.Op != comp.OpNotEqual {
+			if num, ok := comp.Number(lit.pred.Value); ok && !math.IsNaN(float64(num)) {
+				thresholds = append(thresholds, num)
+			}
+		}
+	}
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+
+	numbers := []float32{0}
+	for _, t := range thresholds {
+		numbers = append(numbers, t, math.Nextafter32(t, float32(math.Inf(-1))), math.Nextafter32(t, float32(math.Inf(1))))
+	}
+	for _, num := range numbers {
+		// the value must be a number different from every literal, so it is tried in several spellings
+		for _, spelling := range spellings(num) {
+			if !literals[spelling] {
+				result = append(result, spelling)
+				break
+			}
+		}
+	}
+
+	// a string which is not a number and differs from every literal
+	other := "?"
+	for literals[other] {
+		other += "?"
+	}
+	return append(result, other)
+}
+
+// spellings returns different strings which all parse to the provided number.
+func spellings(num float32) []string {
+	s := strconv.FormatFloat(float64(num), 'g', -1, 32)
+	var result []string
+	for _, spelling := range []string{s, "+" + s, s + "e0", "0" + s, "+0" + s} {
+		if parsed, ok := comp.Number(spelling); ok && parsed == num {
+			result = append(result, spelling)
+		}
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package sat
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolve(t *testing.T) {
+
+	testCases := []struct {
+		desc        string
+		ruleString  string
+		ast         parse.AST // ast is solved instead of ruleString, for counts the parser rejects
+		satisfiable bool
+		valid       bool
+	}{
+		{desc: "comparison", ruleString: "age > 30", satisfiable: true},
+		{desc: "empty interval", ruleString: "age > 40 AND age < 30"},
+		{desc: "touching bounds", ruleString: "age >= 30 AND age <= 30", satisfiable: true},
+		{desc: "no float32 between bounds", ruleString: "age > 1 AND age < 1.00000001"},
+		{desc: "conflicting equalities", ruleString: "dept == 'A' AND dept == 'B'"},
+		{desc: "equality and inequality", ruleString: "dept == 'A' AND dept != 'A'"},
+		{desc: "non-numeric equality and bound", ruleString: "age == 'thirty' AND age > 1"},
+		{desc: "numeric spelling differs from literal", ruleString: "age == 30 AND age != 30.0", satisfiable: true},
+		{desc: "negation holds for missing attribute", ruleString: "NOT (age > 30) AND NOT (age <= 30)", satisfiable: true},
+		{desc: "negated bounds and presence", ruleString: "NOT (age > 30) AND NOT (age <= 30) AND age != 'x' AND age > 1"},
+		{desc: "excluded middle", ruleString: "dept == 'A' OR NOT (dept == 'A')", satisfiable: true, valid: true},
+		{desc: "bounds do not cover missing attribute", ruleString: "age > 30 OR age <= 30", satisfiable: true},
+		{desc: "bare term", ruleString: "active", satisfiable: true, valid: true},
+		{desc: "negated bare term", ruleString: "NOT (active) AND age > 1"},
+		{desc: "ordinal comparison on non-number", ruleString: "age > 'thirty'"},
+		{desc: "ordinal comparison on NaN", ruleString: "age >= NaN OR age <= NaN"},
+		{desc: "infinite bound", ruleString: "age >= inf", satisfiable: true},
+		{desc: "at least two of three", ruleString: "ATLEAST(2, age > 30, age < 20, dept == 'A')", satisfiable: true},
+		{desc: "at least two exclusive", ruleString: "ATLEAST(2, age > 30, age < 20)"},
+		{
+			desc:        "at least none",
+			ast:         &bools.AtLeastExpr{K: 0, Children: []parse.AST{grammar.MustParse("age > 30"), grammar.MustParse("age < 20")}},
+			satisfiable: true,
+			valid:       true,
+		},
+		{
+			desc: "at least more than the children",
+			ast:  &bools.AtLeastExpr{K: 3, Children: []parse.AST{grammar.MustParse("age > 30"), grammar.MustParse("age < 20")}},
+		},
+		{desc: "negated at least", ruleString: "NOT (ATLEAST(1, age > 30, age <= 30)) AND age > 1"},
+		{desc: "xor of complements", ruleString: "XOR(dept == 'A', NOT (dept == 'A'))", satisfiable: true, valid: true},
+		{desc: "exactly two of complements", ruleString: "EXACTLY(2, dept == 'A', NOT (dept == 'A'))"},
+		{desc: "negated exactly", ruleString: "NOT (EXACTLY(1, age > 30, age <= 30)) AND age > 1"},
+		{desc: "priority decided by first child", ruleString: "PRIORITY(age > 30, dept == 'A') AND age > 1 AND age < 20"},
+		{desc: "priority falls through", ruleString: "PRIORITY(age > 30, dept == 'A') AND dept == 'A'", satisfiable: true},
+		{
+			desc:        "nested disjunctions",
+			ruleString:  "(age > 30 OR dept == 'A') AND (age < 20 OR dept == 'B') AND (dept != 'B' OR age > 50)",
+			satisfiable: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := tt.ast
+			if ast == nil {
+				ast = grammar.MustParse(tt.ruleString)
+			}
+			match, err := compile.Compile(ast)
+			assert.Nil(t, err)
+
+			witness, ok, err := Solve(ast)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.satisfiable, ok)
+			if ok {
+				assert.True(t, match(witness), "witness %v", witness)
+			}
+
+			counter, valid, err := Valid(ast)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.valid, valid)
+			if !valid {
+				assert.False(t, match(counter), "counterexample %v", counter)
+			}
+		})
+	}
+}
+
+func TestSolveLimit(t *testing.T) {
+
+	// four values cannot be spread over three attributes, which takes every branch to find out
+	rule := "(a == 1 OR b == 1 OR c == 1) AND (a == 2 OR b == 2 OR c == 2) AND (a == 3 OR b == 3 OR c == 3) AND " +
+		"(a == 4 OR b == 4 OR c == 4)"
+	_, _, err := SolveLimit(grammar.MustParse(rule), 10)
+	assert.True(t, errors.Is(err, ErrLimit))
+
+	_, ok, err := Solve(grammar.MustParse(rule))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// conflicting comparisons are found before branching on any disjunction
+	rule = "x > 5 AND x < 3"
+	for i := 0; i < 32; i++ {
+		rule += fmt.Sprintf(" AND (a%d > 1 OR b%d > 1)", i, i)
+	}
+	_, ok, err = SolveLimit(grammar.MustParse(rule), 1)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestSolverSharesLimit(t *testing.T) {
+
+	rule := grammar.MustParse("(a == 1 OR b == 1) AND (a == 2 OR b == 2)")
+	solver := NewSolver(3)
+	_, ok, err := solver.Solve(rule)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// the branches explored by the first call count against the second
+	_, _, err = solver.Solve(rule)
+	assert.True(t, errors.Is(err, ErrLimit))
+}
+
+// TestSolveRandomRules checks the solver against evaluation of random rules on records covering the values the rules
+// can tell apart.
+func TestSolveRandomRules(t *testing.T) {
+
+	atoms := []string{
+		"age > 30", "age >= 30", "age < 30", "age <= 20", "age == 30", "age == 'thirty'", "age != 30",
+		"dept == 'A'", "dept == 'B'", "dept != 'A'", "flag",
+	}
+	var records []map[string]string
+	for _, age := range []string{"", "19", "20", "25", "30", "30.0", "31", "NaN", "thirty"} {
+		for _, dept := range []string{"", "A", "B", "C"} {
+			record := map[string]string{}
+			if age != "" {
+				record["age"] = age
+			}
+			if dept != "" {
+				record["dept"] = dept
+			}
+			records = append(records, record)
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	// the parser only takes counts between 1 and the number of rules
+	count := func() string {
+		if k := rnd.Intn(4); k > 0 {
+			return fmt.Sprint(k)
+		}
+		return "1"
+	}
+	var build func(depth int) string
+	build = func(depth int) string {
+		switch n := rnd.Intn(8); {
+		case depth == 0 || n < 2:
+			return atoms[rnd.Intn(len(atoms))]
+		case n == 2:
+			return "NOT (" + build(depth-1) + ")"
+		case n == 3:
+			return "(" + build(depth-1) + ") OR (" + build(depth-1) + ")"
+		case n == 4:
+			return "ATLEAST(" + count() + ", " + build(depth-1) + ", " + build(depth-1) + ", " + build(depth-1) + ")"
+		case n == 5:
+			return "EXACTLY(" + count() + ", " + build(depth-1) + ", " + build(depth-1) + ", " + build(depth-1) + ")"
+		case n == 6:
+			return "PRIORITY(" + build(depth-1) + ", " + build(depth-1) + ")"
+		default:
+			return "(" + build(depth-1) + ") AND (" + build(depth-1) + ")"
+		}
+	}
+
+	for i := 0; i < 500; i++ {
+		rule := build(4)
+		ast := grammar.MustParse(rule)
+		match, err := compile.Compile(ast)
+		assert.Nil(t, err)
+
+		// a few of the generated rules branch more than the default limit allows
+		witness, ok, err := SolveLimit(ast, 10*DefaultLimit)
+		assert.Nil(t, err)
+		if ok {
+			assert.True(t, match(witness), "rule %q, witness %v", rule, witness)
+			continue
+		}
+		for _, record := range records {
+			assert.False(t, match(record), "rule %q is satisfiable by %v", rule, record)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package schema type-checks rules against a catalog of known attributes, so that misspelt attribute names and
+// literals which can never match are reported when a rule is written rather than silently never matching.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrSchema is returned for attribute definitions which are not valid.
+var ErrSchema = errors.New("invalid attribute")
+
+// Type is the type of the values an attribute holds.
+type Type string
+
+const (
+	TypeInt     Type = "int"
+	TypeDecimal Type = "decimal"
+	TypeString  Type = "string"
+	TypeBool    Type = "bool"
+	TypeDate    Type = "date" // TypeDate values are written as YYYY-MM-DD.
+	TypeEnum    Type = "enum" // TypeEnum values must be one of the attribute's allowed values.
+)
+
+// DateLayout is the layout of date values.
+const DateLayout = "2006-01-02"
+
+// Attribute describes one attribute which rules may refer to.
+type Attribute struct {
+	Name   string
+	Type   Type
+	Values []string // Values holds the allowed values of an enum attribute.
+
+	// Default, if set, is used by Validate in place of a missing value.
+	Default *string
+}
+
+// Validate checks that the attribute is well formed: it must have a name and a known type, enum attributes, and only
+// enum attributes, must list their allowed values, and any default must be a valid value.
+func (a Attribute) Validate() error {
+	if strings.TrimSpace(a.Name) == "" || strings.Join(strings.Fields(a.Name), " ") != a.Name {
+		return fmt.Errorf("%w: name '%s' must be non-empty, with single spaces between words", ErrSchema, a.Name)
+	}
+	if strings.Contains(a.Name, "'") {
+		return fmt.Errorf("%w: name '%s' must not contain quotes", ErrSchema, a.Name)
+	}
+	switch a.Type {
+	case TypeInt, TypeDecimal, TypeString, TypeBool, TypeDate:
+		if len(a.Values) > 0 {
+			return fmt.Errorf("%w: only enum attributes have allowed values", ErrSchema)
+		}
+	case TypeEnum:
+		if len(a.Values) == 0 {
+			return fmt.Errorf("%w: enum attribute '%s' must list its allowed values", ErrSchema, a.Name)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type '%s'", ErrSchema, a.Type)
+	}
+	if a.Default != nil {
+		if _, problem, ok := a.coerce(*a.Default); !ok {
+			return fmt.Errorf("%w: default: %s", ErrSchema, problem.Message)
+		}
+	}
+	return nil
+}
+
+// Catalog holds the known attributes by name.
+type Catalog map[string]Attribute
+
+// NewCatalog returns a catalog of the provided attributes.
+func NewCatalog(attrs ...Attribute) Catalog {
+	catalog := make(Catalog, len(attrs))
+	for _, attr := range attrs {
+		catalog[attr.Name] = attr
+	}
+	return catalog
+}
+
+// Kinds of Problem found by Check.
+const (
+	ProblemUnknownAttribute = "unknown_attribute"
+	ProblemTypeMismatch     = "type_mismatch"
+	ProblemEnumValue        = "enum_value"
+	ProblemUnsupported      = "unsupported"
+)
+
+// Problem describes one part of a rule which does not agree with the catalog.
+type Problem struct {
+	Attribute string `json:"attribute,omitempty"`
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+}
+
+// Check type-checks a parsed rule against the catalog, returning the problems found in the order they appear in the
+// rule. Every comparison must be between a known attribute on the left and a literal of the attribute's type on the
+// right. Ordinal comparisons are only allowed on int and decimal attributes, since the rule engine compares them as
+// numbers.
+func (c Catalog) Check(ast parse.AST) ([]Problem, error) {
+	var problems []Problem
+	err := c.check(ast, &problems)
+	return problems, err
+}
+
+func (c Catalog) check(ast parse.AST, problems *[]Problem) error {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		if err := c.check(ast.LHS, problems); err != nil {
+			return err
+		}
+		return c.check(ast.RHS, problems)
+	case *bools.UnaryExpr:
+		return c.check(ast.Expr, problems)
+	case *bools.AtLeastExpr, *bools.ExactlyExpr, *bools.PriorityExpr:
+		for _, child := range bools.Children(ast) {
+			if err := c.check(child, problems); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			*problems = append(*problems, Problem{
+				Kind:    ProblemUnsupported,
+				Message: fmt.Sprintf("comparison '%v' must have an attribute on the left and a literal on the right", ast),
+			})
+			return nil
+		}
+		if problem, ok := c.checkPredicate(pred); !ok {
+			*problems = append(*problems, problem)
+		}
+		return nil
+	case parse.Unparsed:
+		*problems = append(*problems, Problem{
+			Kind:    ProblemUnsupported,
+			Message: fmt.Sprintf("'%v' is not a comparison and always matches", ast),
+		})
+		return nil
+	}
+	return fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func (c Catalog) checkPredicate(pred comp.Predicate) (Problem, bool) {
+	attr, ok := c[pred.Attr]
+	if !ok {
+		msg := fmt.Sprintf("unknown attribute '%s'", pred.Attr)
+		if suggestion := c.closest(pred.Attr); suggestion != "" {
+			msg += fmt.Sprintf("; did you mean '%s'?", suggestion)
+		}
+		return Problem{Attribute: pred.Attr, Kind: ProblemUnknownAttribute, Message: msg}, false
+	}
+	mismatch := func(format string, args ...any) (Problem, bool) {
+		return Problem{Attribute: attr.Name, Kind: ProblemTypeMismatch, Message: fmt.Sprintf(format, args...)}, false
+	}
+
+	if pred.Op != comp.OpEqual && pred.Op != comp.OpNotEqual {
+		if attr.Type != TypeInt && attr.Type != TypeDecimal {
+			return mismatch("'%s' compares numbers, but '%s' is a %s attribute", pred.Op, attr.Name, attr.Type)
+		}
+		if _, ok := pred.Number(); !ok {
+			return mismatch("'%s' compares numbers, but '%s' is not a number", pred.Op, pred.Value)
+		}
+		return Problem{}, true
+	}
+
+	switch attr.Type {
+	case TypeInt:
+		if _, err := strconv.ParseInt(pred.Value, 10, 64); err != nil {
+			return mismatch("'%s' is not an int", pred.Value)
+		}
+	case TypeDecimal:
+		if _, ok := pred.Number(); !ok {
+			return mismatch("'%s' is not a decimal", pred.Value)
+		}
+	case TypeBool:
+		if pred.Value != "true" && pred.Value != "false" {
+			return mismatch("'%s' is not a bool; use true or false", pred.Value)
+		}
+	case TypeDate:
+		if _, err := time.Parse(DateLayout, pred.Value); err != nil {
+			return mismatch("'%s' is not a date in the form YYYY-MM-DD", pred.Value)
+		}
+	case TypeEnum:
+		for _, allowed := range attr.Values {
+			if pred.Value == allowed {
+				return Problem{}, true
+			}
+		}
+		return Problem{
+			Attribute: attr.Name,
+			Kind:      ProblemEnumValue,
+			Message:   fmt.Sprintf("'%s' is not one of the allowed values: %s", pred.Value, strings.Join(attr.Values, ", ")),
+		}, false
+	}
+	return Problem{}, true
+}
+
+// closest returns the name of the known attribute nearest to name, if one is within two edits of it.
+func (c Catalog) closest(name string) string {
+	var names []string
+	for known := range c {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", 3
+	for _, known := range names {
+		if d := distance(strings.ToLower(name), strings.ToLower(known)); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(vals ...int) int {
+	m := vals[0]
+	for _, v := range vals[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var catalog = NewCatalog(
+	Attribute{Name: "age", Type: TypeInt},
+	Attribute{Name: "salary", Type: TypeDecimal},
+	Attribute{Name: "department", Type: TypeString},
+	Attribute{Name: "active", Type: TypeBool},
+	Attribute{Name: "joined", Type: TypeDate},
+	Attribute{Name: "level", Type: TypeEnum, Values: []string{"junior", "senior"}},
+)
+
+func TestCheck(t *testing.T) {
+
+	testCases := []struct {
+		desc             string
+		ruleString       string
+		expectedProblems []Problem
+	}{
+		{
+			desc:       "valid rule",
+			ruleString: "age > 30 AND salary <= 2000.5 AND department == 'Sales' AND active == true AND joined != '2024-02-29' AND level == 'senior'",
+		},
+		{
+			desc:       "ordinal comparison with decimal literal on int attribute",
+			ruleString: "NOT (age >= 30.5)",
+		},
+		{
+			desc:       "misspelt attribute",
+			ruleString: "deparment == 'Sales'",
+			expectedProblems: []Problem{
+				{Attribute: "deparment", Kind: ProblemUnknownAttribute, Message: "unknown attribute 'deparment'; did you mean 'department'?"},
+			},
+		},
+		{
+			desc:       "unknown attribute",
+			ruleString: "height > 180",
+			expectedProblems: []Problem{
+				{Attribute: "height", Kind: ProblemUnknownAttribute, Message: "unknown attribute 'height'"},
+			},
+		},
+		{
+			desc:       "type mismatches",
+			ruleString: "age == 'thirty' OR salary > 'high' OR department > 10 OR active == 'yes' OR joined == '01/02/2024'",
+			expectedProblems: []Problem{
+				{Attribute: "age", Kind: ProblemTypeMismatch, Message: "'thirty' is not an int"},
+				{Attribute: "salary", Kind: ProblemTypeMismatch, Message: "'>' compares numbers, but 'high' is not a number"},
+				{Attribute: "department", Kind: ProblemTypeMismatch, Message: "'>' compares numbers, but 'department' is a string attribute"},
+				{Attribute: "active", Kind: ProblemTypeMismatch, Message: "'yes' is not a bool; use true or false"},
+				{Attribute: "joined", Kind: ProblemTypeMismatch, Message: "'01/02/2024' is not a date in the form YYYY-MM-DD"},
+			},
+		},
+		{
+			desc:       "enum value not allowed",
+			ruleString: "level == 'principal'",
+			expectedProblems: []Problem{
+				{Attribute: "level", Kind: ProblemEnumValue, Message: "'principal' is not one of the allowed values: junior, senior"},
+			},
+		},
+		{
+			desc:       "bare term",
+			ruleString: "active AND age > 1",
+			expectedProblems: []Problem{
+				{Kind: ProblemUnsupported, Message: "'active' is not a comparison and always matches"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			problems, err := catalog.Check(grammar.MustParse(tt.ruleString))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedProblems, problems)
+		})
+	}
+}
+
+func TestAttributeValidate(t *testing.T) {
+
+	validDefault, invalidDefault := "18", "eighteen"
+
+	testCases := []struct {
+		desc      string
+		attribute Attribute
+		valid     bool
+	}{
+		{desc: "int", attribute: Attribute{Name: "age", Type: TypeInt}, valid: true},
+		{desc: "multi-word name", attribute: Attribute{Name: "first name", Type: TypeString}, valid: true},
+		{desc: "enum", attribute: Attribute{Name: "level", Type: TypeEnum, Values: []string{"junior"}}, valid: true},
+		{desc: "empty name", attribute: Attribute{Name: " ", Type: TypeInt}},
+		{desc: "name with quote", attribute: Attribute{Name: "o'clock", Type: TypeInt}},
+		{desc: "unknown type", attribute: Attribute{Name: "age", Type: "integer"}},
+		{desc: "enum without values", attribute: Attribute{Name: "level", Type: TypeEnum}},
+		{desc: "values on non-enum", attribute: Attribute{Name: "age", Type: TypeInt, Values: []string{"1"}}},
+		{desc: "valid default", attribute: Attribute{Name: "age", Type: TypeInt, Default: &validDefault}, valid: true},
+		{desc: "invalid default", attribute: Attribute{Name: "age", Type: TypeInt, Default: &invalidDefault}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := tt.attribute.Validate()
+			if tt.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrSchema), "unexpected error: %v", err)
+			}
+		})
+	}
+}
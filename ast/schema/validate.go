@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// Validate checks record data against the catalog and coerces it into the string values the rule engine compares.
+// Values may be JSON strings, numbers or booleans, so "31" and 31 are both accepted for an int attribute and become
+// "31", while "TRUE" and true become "true" for a bool attribute. Missing and null values are replaced by the
+// attribute's default, if it has one, and are otherwise left missing. Attributes which are not in the catalog are
+// reported as problems, as are values which cannot be coerced. Problems are sorted by attribute name.
+func (c Catalog) Validate(data map[string]any) (map[string]string, []Problem) {
+	result := make(map[string]string, len(data))
+	var problems []Problem
+
+	for name, raw := range data {
+		attr, ok := c[name]
+		if !ok {
+			problems = append(problems, Problem{
+				Attribute: name,
+				Kind:      ProblemUnknownAttribute,
+				Message:   fmt.Sprintf("unknown attribute '%s'", name),
+			})
+			continue
+		}
+		if raw == nil {
+			continue
+		}
+		val, problem, ok := attr.coerce(raw)
+		if !ok {
+			problems = append(problems, problem)
+			continue
+		}
+		result[name] = val
+	}
+
+	for name, attr := range c {
+		if _, ok := result[name]; ok || attr.Default == nil {
+			continue
+		}
+		if raw, ok := data[name]; ok && raw != nil {
+			continue // the value was present but not valid
+		}
+		if val, _, ok := attr.coerce(*attr.Default); ok {
+			result[name] = val
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].Attribute < problems[j].Attribute
+	})
+	return result, problems
+}
+
+// Canonicalize returns a copy of the rule in which the literals compared by == and != with int and decimal attributes
+// are written in the canonical form Validate coerces values into, so that salary == 20000.50 matches the coerced value
+// "20000.5"; the provided AST is not modified. The second result reports whether any literal was rewritten. Literals
+// which are not valid values of their attribute are left as they are, for Check to report.
+func (c Catalog) Canonicalize(ast parse.AST) (parse.AST, bool) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		lhs, lhsChanged := c.Canonicalize(ast.LHS)
+		rhs, rhsChanged := c.Canonicalize(ast.RHS)
+		return &bools.BinExpr{LHS: lhs, RHS: rhs, Op: ast.Op}, lhsChanged || rhsChanged
+	case *bools.UnaryExpr:
+		expr, changed := c.Canonicalize(ast.Expr)
+		return &bools.UnaryExpr{Op: ast.Op, Expr: expr}, changed
+	case *bools.AtLeastExpr:
+		children, changed := c.canonicalizeAll(ast.Children)
+		return &bools.AtLeastExpr{K: ast.K, Children: children}, changed
+	case *bools.ExactlyExpr:
+		children, changed := c.canonicalizeAll(ast.Children)
+		return &bools.ExactlyExpr{K: ast.K, Children: children}, changed
+	case *bools.PriorityExpr:
+		children, changed := c.canonicalizeAll(ast.Children)
+		return &bools.PriorityExpr{Children: children}, changed
+	case *comp.EqualExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return ast, false
+		}
+		attr, ok := c[pred.Attr]
+		if !ok || (attr.Type != TypeInt && attr.Type != TypeDecimal) {
+			return ast, false
+		}
+		val, _, ok := attr.coerce(pred.Value)
+		if !ok || val == pred.Value {
+			return ast, false
+		}
+		pred.Value = val
+		return pred.AST(), true
+	}
+	return ast, false
+}
+
+func (c Catalog) canonicalizeAll(children []parse.AST) ([]parse.AST, bool) {
+	result := make([]parse.AST, len(children))
+	changed := false
+	for idx, child := range children {
+		var childChanged bool
+		result[idx], childChanged = c.Canonicalize(child)
+		changed = changed || childChanged
+	}
+	return result, changed
+}
+
+// coerce converts a string, number or boolean into the canonical string form of a value of the attribute's type.
+func (a Attribute) coerce(raw any) (string, Problem, bool) {
+	mismatch := func(format string, args ...any) (string, Problem, bool) {
+		return "", Problem{Attribute: a.Name, Kind: ProblemTypeMismatch, Message: fmt.Sprintf(format, args...)}, false
+	}
+
+	var text string
+	switch raw := raw.(type) {
+	case string:
+		text = strings.TrimSpace(raw)
+	case float64:
+		text = strconv.FormatFloat(raw, 'f', -1, 64)
+	case bool:
+		text = strconv.FormatBool(raw)
+	case fmt.Stringer: // json.Number
+		text = raw.String()
+	default:
+		return mismatch("value of type %T is not a %s", raw, a.Type)
+	}
+
+	switch a.Type {
+	case TypeInt:
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10), Problem{}, true
+		}
+		// JSON numbers such as 31.0 or 3.1e1 are accepted as long as they are whole
+		if _, isString := raw.(string); !isString {
+			if f, err := strconv.ParseFloat(text, 64); err == nil && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+				return strconv.FormatInt(int64(f), 10), Problem{}, true
+			}
+		}
+		return mismatch("'%s' is not an int", text)
+	case TypeDecimal:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return mismatch("'%s' is not a decimal", text)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), Problem{}, true
+	case TypeString:
+		if raw, ok := raw.(string); ok {
+			return raw, Problem{}, true
+		}
+		return text, Problem{}, true
+	case TypeBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return mismatch("'%s' is not a bool", text)
+		}
+		return strconv.FormatBool(b), Problem{}, true
+	case TypeDate:
+		if _, err := time.Parse(DateLayout, text); err != nil {
+			return mismatch("'%s' is not a date in the form YYYY-MM-DD", text)
+		}
+		return text, Problem{}, true
+	case TypeEnum:
+		for _, allowed := range a.Values {
+			if text == allowed {
+				return text, Problem{}, true
+			}
+		}
+		return "", Problem{
+			Attribute: a.Name,
+			Kind:      ProblemEnumValue,
+			Message:   fmt.Sprintf("'%s' is not one of the allowed values: %s", text, strings.Join(a.Values, ", ")),
+		}, false
+	}
+	return mismatch("unknown type '%s'", a.Type)
+}
@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+
+	zero, junior, spaced := "0", "junior", " 7 "
+	catalog := NewCatalog(
+		Attribute{Name: "age", Type: TypeInt},
+		Attribute{Name: "experience", Type: TypeInt, Default: &zero},
+		Attribute{Name: "bonus", Type: TypeInt, Default: &spaced},
+		Attribute{Name: "salary", Type: TypeDecimal},
+		Attribute{Name: "department", Type: TypeString},
+		Attribute{Name: "active", Type: TypeBool},
+		Attribute{Name: "joined", Type: TypeDate},
+		Attribute{Name: "level", Type: TypeEnum, Values: []string{"junior", "senior"}, Default: &junior},
+	)
+
+	testCases := []struct {
+		desc             string
+		data             string
+		expectedData     map[string]string
+		expectedProblems []Problem
+	}{
+		{
+			desc: "coerces values and applies defaults",
+			data: `{"age": "31", "salary": 20000.50, "department": "Sales ", "active": "TRUE", "joined": "2024-02-29", "bonus": null}`,
+			expectedData: map[string]string{
+				"age": "31", "experience": "0", "bonus": "7", "salary": "20000.5", "department": "Sales ",
+				"active": "true", "joined": "2024-02-29", "level": "junior",
+			},
+		},
+		{
+			desc:         "json numbers and booleans",
+			data:         `{"age": 3.1e1, "salary": "1e5", "department": 42, "active": false, "experience": 5, "level": "senior"}`,
+			expectedData: map[string]string{"age": "31", "salary": "100000", "department": "42", "active": "false", "experience": "5", "bonus": "7", "level": "senior"},
+		},
+		{
+			desc: "reports every problem",
+			data: `{"age": "thirty", "salary": "NaN", "active": "yes", "joined": "29/02/2024", "level": "principal", "deparment": "Sales", "experience": 2.5, "department": {}}`,
+			expectedProblems: []Problem{
+				{Attribute: "active", Kind: ProblemTypeMismatch, Message: "'yes' is not a bool"},
+				{Attribute: "age", Kind: ProblemTypeMismatch, Message: "'thirty' is not an int"},
+				{Attribute: "deparment", Kind: ProblemUnknownAttribute, Message: "unknown attribute 'deparment'"},
+				{Attribute: "department", Kind: ProblemTypeMismatch, Message: "value of type map[string]interface {} is not a string"},
+				{Attribute: "experience", Kind: ProblemTypeMismatch, Message: "'2.5' is not an int"},
+				{Attribute: "joined", Kind: ProblemTypeMismatch, Message: "'29/02/2024' is not a date in the form YYYY-MM-DD"},
+				{Attribute: "level", Kind: ProblemEnumValue, Message: "'principal' is not one of the allowed values: junior, senior"},
+				{Attribute: "salary", Kind: ProblemTypeMismatch, Message: "'NaN' is not a decimal"},
+			},
+		},
+		{
+			desc:             "whole numbers in strings must be written as ints",
+			data:             `{"age": "31.0"}`,
+			expectedProblems: []Problem{{Attribute: "age", Kind: ProblemTypeMismatch, Message: "'31.0' is not an int"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]any
+			decoder := json.NewDecoder(strings.NewReader(tt.data))
+			decoder.UseNumber()
+			assert.Nil(t, decoder.Decode(&data))
+
+			coerced, problems := catalog.Validate(data)
+			assert.Equal(t, tt.expectedProblems, problems)
+			if tt.expectedProblems == nil {
+				assert.Equal(t, tt.expectedData, coerced)
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+
+	catalog := NewCatalog(
+		Attribute{Name: "age", Type: TypeInt},
+		Attribute{Name: "salary", Type: TypeDecimal},
+		Attribute{Name: "department", Type: TypeString},
+	)
+
+	testCases := []struct {
+		desc            string
+		ruleString      string
+		expectedRule    string
+		expectedChanged bool
+	}{
+		{desc: "decimal", ruleString: "salary == 20000.50", expectedRule: "salary == 20000.5", expectedChanged: true},
+		{desc: "int", ruleString: "age != 031", expectedRule: "age != 31", expectedChanged: true},
+		{desc: "quoted", ruleString: "salary == '1e5'", expectedRule: "salary == '100000'", expectedChanged: true},
+		{
+			desc:            "nested",
+			ruleString:      "department == '007' AND NOT (ATLEAST(1, age == 031, PRIORITY(salary == 1.10)))",
+			expectedRule:    "department == '007' AND NOT (ATLEAST(1, age == 31, PRIORITY(salary == 1.1)))",
+			expectedChanged: true,
+		},
+		{desc: "already canonical", ruleString: "salary == 20000.5 AND age == 31", expectedRule: "salary == 20000.5 AND age == 31"},
+		{desc: "ordinal comparisons compare numbers", ruleString: "salary > 20000.50", expectedRule: "salary > 20000.50"},
+		{desc: "string attribute", ruleString: "department == 007", expectedRule: "department == 007"},
+		{desc: "unknown attribute", ruleString: "bonus == 1.50", expectedRule: "bonus == 1.50"},
+		{desc: "invalid literal", ruleString: "age == 31.0", expectedRule: "age == 31.0"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := grammar.MustParse(tt.ruleString)
+			canonical, changed := catalog.Canonicalize(ast)
+			assert.Equal(t, tt.expectedChanged, changed)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(canonical))
+		})
+	}
+}
@@ -0,0 +1,565 @@
+// Package simplify rewrites rule ASTs into smaller rules which match exactly the same records.
+//
+// The rewrites follow the rule engine's semantics rather than textbook logic: a comparison on a missing attribute is
+// false, so NOT (age > 30) is not the same as age <= 30 and is never rewritten into it; ordinal comparisons parse both
+// sides as 32-bit floats, so bounds are compared as float32 values; and a bare term always matches. The constant TRUE
+// is therefore written as a bare term, and FALSE as NOT (TRUE).
+package simplify
+
+import (
+	"fmt"
+	"math"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// maxPasses bounds the number of times Simplify rewrites a rule while it keeps getting smaller.
+const maxPasses = 8
+
+// True returns a rule which matches every record.
+func True() parse.AST {
+	return parse.Unparsed{Contents: []string{"TRUE"}}
+}
+
+// False returns a rule which matches no record.
+func False() parse.AST {
+	return &bools.UnaryExpr{Op: bools.OpNot, Expr: True()}
+}
+
+// IsTrue reports whether the node is a bare term, which always matches.
+func IsTrue(ast parse.AST) bool {
+	_, ok := ast.(parse.Unparsed)
+	return ok
+}
+
+// IsFalse reports whether the node is the negation of a bare term, which never matches.
+func IsFalse(ast parse.AST) bool {
+	not, ok := ast.(*bools.UnaryExpr)
+	return ok && not.Op == bools.OpNot && IsTrue(not.Expr)
+}
+
+// Simplify returns a simplified copy of the rule; the provided AST is not modified. It flattens nested AND and OR
+// chains, folds constants, removes duplicate clauses, applies the double negation, complement and absorption laws, and
+// merges comparisons on the same attribute, keeping only the tightest bounds in a conjunction and the loosest in a
+// disjunction, and folding conjunctions which no value can satisfy.
+func Simplify(ast parse.AST) (parse.AST, error) {
+	result, err := simplify(ast)
+	if err != nil {
+		return nil, err
+	}
+	for pass := 1; pass < maxPasses; pass++ {
+		next, err := simplify(result)
+		if err != nil {
+			return nil, err
+		}
+		if fmt.Sprint(next) == fmt.Sprint(result) {
+			break
+		}
+		result = next
+	}
+	return result, nil
+}
+
+func simplify(ast parse.AST) (parse.AST, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		if ast.Op != bools.OpAnd && ast.Op != bools.OpOr {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		var operands []parse.AST
+		for _, operand := range bools.Flatten(ast, ast.Op) {
+			simplified, err := simplify(operand)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, bools.Flatten(simplified, ast.Op)...)
+		}
+		return chain(ast.Op, operands), nil
+
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		expr, err := simplify(ast.Expr)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case IsTrue(expr):
+			return False(), nil
+		case IsFalse(expr):
+			return True(), nil
+		}
+		if inner, ok := expr.(*bools.UnaryExpr); ok && inner.Op == bools.OpNot {
+			return inner.Expr, nil
+		}
+		return &bools.UnaryExpr{Op: bools.OpNot, Expr: expr}, nil
+
+	case *bools.AtLeastExpr:
+		k, children, err := count(ast.K, ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case k <= 0:
+			return True(), nil
+		case k > len(children):
+			return False(), nil
+		case k == 1:
+			return chain(bools.OpOr, children), nil
+		case k == len(children):
+			return chain(bools.OpAnd, children), nil
+		}
+		return &bools.AtLeastExpr{K: k, Children: children}, nil
+
+	case *bools.ExactlyExpr:
+		k, children, err := count(ast.K, ast.Children)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case k < 0 || k > len(children):
+			return False(), nil
+		case k == 0:
+			return simplify(&bools.UnaryExpr{Op: bools.OpNot, Expr: bools.Chain(bools.OpOr, append(children, False())...)})
+		case k == len(children):
+			return chain(bools.OpAnd, children), nil
+		}
+		return &bools.ExactlyExpr{K: k, Children: children}, nil
+
+	case *bools.PriorityExpr:
+		// rewriting a child could change the attributes it compares, and so whether it has an opinion
+		return ast, nil
+
+	case *comp.EqualExpr:
+		return ast, nil
+
+	case *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return ast, nil
+		}
+		if b, ok := newBound(pred); !ok || b.never() {
+			return False(), nil
+		}
+		return ast, nil
+
+	case parse.Unparsed:
+		return True(), nil
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+// count simplifies the children of an ATLEAST or EXACTLY node, dropping those which never match and those which always
+// do, which lowers the count.
+func count(k int, children []parse.AST) (int, []parse.AST, error) {
+	var kept []parse.AST
+	for _, child := range children {
+		simplified, err := simplify(child)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch {
+		case IsTrue(simplified):
+			k--
+		case !IsFalse(simplified):
+			kept = append(kept, simplified)
+		}
+	}
+	return k, kept, nil
+}
+
+// chain simplifies a flattened AND or OR chain of simplified operands.
+func chain(op bools.Op, operands []parse.AST) parse.AST {
+	// unit is the constant which can be dropped from the chain, and zero the constant which decides it
+	isUnit, isZero, unit, zero := IsTrue, IsFalse, True, False
+	if op == bools.OpOr {
+		isUnit, isZero, unit, zero = IsFalse, IsTrue, False, True
+	}
+
+	kept, seen, decided := collect(operands, isUnit, isZero)
+	if decided {
+		return zero()
+	}
+
+	// within a conjunction, the other clauses only matter for records which satisfy its comparisons
+	if op == bools.OpAnd {
+		if facts := conjunctionFacts(kept); len(facts) > 0 {
+			for idx, operand := range kept {
+				if _, ok := comp.AsPredicate(operand); !ok {
+					kept[idx] = substitute(operand, facts)
+				}
+			}
+			if kept, seen, decided = collect(kept, isUnit, isZero); decided {
+				return zero()
+			}
+		}
+	}
+
+	// complement: x AND NOT (x) never matches, and x OR NOT (x) always does
+	for _, operand := range kept {
+		if not, ok := operand.(*bools.UnaryExpr); ok && not.Op == bools.OpNot && seen[fmt.Sprint(not.Expr)] {
+			return zero()
+		}
+	}
+
+	// absorption: x AND (x OR y) is x, and x OR (x AND y) is x
+	dual := bools.OpOr
+	if op == bools.OpOr {
+		dual = bools.OpAnd
+	}
+	var absorbed []parse.AST
+	for _, operand := range kept {
+		if !absorbs(seen, operand, dual) {
+			absorbed = append(absorbed, operand)
+		}
+	}
+
+	merged, ok := mergePredicates(op, absorbed)
+	if !ok {
+		return zero()
+	}
+	if len(merged) == 0 {
+		return unit()
+	}
+	return bools.Chain(op, merged...)
+}
+
+// collect drops units and duplicates from a chain, returning the remaining operands and the set of their keys. The
+// third result is true if an operand decides the chain.
+func collect(operands []parse.AST, isUnit, isZero func(parse.AST) bool) ([]parse.AST, map[string]bool, bool) {
+	seen := map[string]bool{}
+	var kept []parse.AST
+	for _, operand := range operands {
+		if isZero(operand) {
+			return nil, nil, true
+		}
+		key := fmt.Sprint(operand)
+		if isUnit(operand) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, operand)
+	}
+	return kept, seen, false
+}
+
+// fact is what the comparisons in a conjunction establish about one attribute: that it holds a known value, or that it
+// holds a number within bounds.
+type fact struct {
+	value  *string
+	lo, hi *bound
+}
+
+// conjunctionFacts returns the facts established by the comparisons in a conjunction. Attributes compared for equality
+// with different values are left out; mergePredicates folds such conjunctions.
+func conjunctionFacts(operands []parse.AST) map[string]*fact {
+	facts := map[string]*fact{}
+	conflicts := map[string]bool{}
+	for _, operand := range operands {
+		pred, ok := comp.AsPredicate(operand)
+		if !ok || pred.Op == comp.OpNotEqual {
+			continue
+		}
+		f := facts[pred.Attr]
+		if f == nil {
+			f = &fact{}
+			facts[pred.Attr] = f
+		}
+		if pred.Op == comp.OpEqual {
+			value := pred.Value
+			if f.value != nil && *f.value != value {
+				conflicts[pred.Attr] = true
+			}
+			f.value = &value
+			continue
+		}
+		b, ok := newBound(pred)
+		switch {
+		case !ok:
+		case b.lower() && (f.lo == nil || b.tighter(*f.lo)):
+			f.lo = &b
+		case !b.lower() && (f.hi == nil || b.tighter(*f.hi)):
+			f.hi = &b
+		}
+	}
+	for attr := range conflicts {
+		delete(facts, attr)
+	}
+	return facts
+}
+
+// decide returns the result of the predicate for records in which the fact holds. The second result is false if the
+// fact does not determine the result.
+func (f *fact) decide(pred comp.Predicate) (bool, bool) {
+	if f.value != nil {
+		return matches(pred, *f.value), true
+	}
+	switch pred.Op {
+	case comp.OpEqual, comp.OpNotEqual:
+		// the attribute holds a number within the bounds, so it cannot be equal to a value outside them, or to a
+		// value which is not a number at all
+		num, ok := comp.Number(pred.Value)
+		if ok && f.admits(num) {
+			return false, false
+		}
+		return pred.Op == comp.OpNotEqual, true
+	}
+	b, ok := newBound(pred)
+	switch {
+	case !ok:
+		return false, true
+	case b.lower() && f.lo != nil && !b.tighter(*f.lo):
+		return true, true
+	case !b.lower() && f.hi != nil && !b.tighter(*f.hi):
+		return true, true
+	case b.lower() && f.hi != nil && empty(b, *f.hi):
+		return false, true
+	case !b.lower() && f.lo != nil && empty(*f.lo, b):
+		return false, true
+	}
+	return false, false
+}
+
+// admits reports whether the number is within the fact's bounds.
+func (f *fact) admits(num float32) bool {
+	return (f.lo == nil || f.lo.holds(num)) && (f.hi == nil || f.hi.holds(num))
+}
+
+// substitute returns a simplified copy of the rule with every comparison decided by the facts replaced by its result.
+func substitute(ast parse.AST, facts map[string]*fact) parse.AST {
+	var replace func(parse.AST) parse.AST
+	replace = func(ast parse.AST) parse.AST {
+		switch ast := ast.(type) {
+		case *bools.BinExpr:
+			return &bools.BinExpr{LHS: replace(ast.LHS), RHS: replace(ast.RHS), Op: ast.Op}
+		case *bools.UnaryExpr:
+			return &bools.UnaryExpr{Op: ast.Op, Expr: replace(ast.Expr)}
+		case *bools.AtLeastExpr:
+			return &bools.AtLeastExpr{K: ast.K, Children: replaceAll(ast.Children, replace)}
+		case *bools.ExactlyExpr:
+			return &bools.ExactlyExpr{K: ast.K, Children: replaceAll(ast.Children, replace)}
+		case *bools.PriorityExpr:
+			return ast
+		}
+		pred, ok := comp.AsPredicate(ast)
+		if !ok || facts[pred.Attr] == nil {
+			return ast
+		}
+		result, ok := facts[pred.Attr].decide(pred)
+		switch {
+		case !ok:
+			return ast
+		case result:
+			return True()
+		}
+		return False()
+	}
+	// the copy only holds node types which have already been simplified once, so this cannot fail
+	result, err := simplify(replace(ast))
+	if err != nil {
+		return ast
+	}
+	return result
+}
+
+func replaceAll(children []parse.AST, replace func(parse.AST) parse.AST) []parse.AST {
+	var result []parse.AST
+	for _, child := range children {
+		result = append(result, replace(child))
+	}
+	return result
+}
+
+// matches reports whether the predicate matches a record holding the provided value for its attribute.
+func matches(pred comp.Predicate, val string) bool {
+	switch pred.Op {
+	case comp.OpEqual:
+		return val == pred.Value
+	case comp.OpNotEqual:
+		return val != pred.Value
+	}
+	b, ok := newBound(pred)
+	num, isNum := comp.Number(val)
+	return ok && isNum && b.holds(num)
+}
+
+// absorbs reports whether operand is a dual chain with one of its operands also present in the enclosing chain.
+func absorbs(seen map[string]bool, operand parse.AST, dual bools.Op) bool {
+	bin, ok := operand.(*bools.BinExpr)
+	if !ok || bin.Op != dual {
+		return false
+	}
+	for _, inner := range bools.Flatten(bin, dual) {
+		if seen[fmt.Sprint(inner)] {
+			return true
+		}
+	}
+	return false
+}
+
+// bound is an ordinal comparison against a number.
+type bound struct {
+	op  comp.Op
+	val float32
+}
+
+func newBound(pred comp.Predicate) (bound, bool) {
+	val, ok := pred.Number()
+	return bound{op: pred.Op, val: val}, ok
+}
+
+// lower reports whether the bound is a lower bound, that is > or >=.
+func (b bound) lower() bool {
+	return b.op == comp.OpGreater || b.op == comp.OpGreaterOrEqual
+}
+
+func (b bound) strict() bool {
+	return b.op == comp.OpGreater || b.op == comp.OpLess
+}
+
+// holds reports whether the bound holds for the provided value, as the rule engine compares them.
+func (b bound) holds(val float32) bool {
+	switch b.op {
+	case comp.OpGreater:
+		return val > b.val
+	case comp.OpGreaterOrEqual:
+		return val >= b.val
+	case comp.OpLess:
+		return val < b.val
+	case comp.OpLessOrEqual:
+		return val <= b.val
+	}
+	return false
+}
+
+// never reports whether no value satisfies the bound.
+func (b bound) never() bool {
+	v := float64(b.val)
+	return math.IsNaN(v) || (b.op == comp.OpGreater && math.IsInf(v, 1)) || (b.op == comp.OpLess && math.IsInf(v, -1))
+}
+
+// tighter reports whether b admits fewer values than other, which must bound in the same direction.
+func (b bound) tighter(other bound) bool {
+	if b.val == other.val {
+		return b.strict() && !other.strict()
+	}
+	return b.lower() == (b.val > other.val)
+}
+
+// empty reports whether no value satisfies both the lower bound lo and the upper bound hi.
+func empty(lo, hi bound) bool {
+	return lo.val > hi.val || (lo.val == hi.val && (lo.strict() || hi.strict()))
+}
+
+// attribute collects the comparisons on one attribute in a chain, by their position in the chain.
+type attribute struct {
+	equal    []int
+	notEqual []int
+	lower    []int
+	upper    []int
+}
+
+// mergePredicates merges the comparisons on each attribute in a chain. The second result is false if the chain is a
+// conjunction which no record can satisfy.
+func mergePredicates(op bools.Op, operands []parse.AST) ([]parse.AST, bool) {
+	preds := make([]comp.Predicate, len(operands))
+	bounds := make([]bound, len(operands))
+	attrs := map[string]*attribute{}
+	for idx, operand := range operands {
+		pred, ok := comp.AsPredicate(operand)
+		if !ok {
+			continue
+		}
+		preds[idx] = pred
+		attr := attrs[pred.Attr]
+		if attr == nil {
+			attr = &attribute{}
+			attrs[pred.Attr] = attr
+		}
+		switch pred.Op {
+		case comp.OpEqual:
+			attr.equal = append(attr.equal, idx)
+		case comp.OpNotEqual:
+			attr.notEqual = append(attr.notEqual, idx)
+		default:
+			// ordinal comparisons against non-numbers have already been folded
+			bounds[idx], _ = newBound(pred)
+			if bounds[idx].lower() {
+				attr.lower = append(attr.lower, idx)
+			} else {
+				attr.upper = append(attr.upper, idx)
+			}
+		}
+	}
+
+	drop := make([]bool, len(operands))
+	// keep drops all but the tightest (in a conjunction) or loosest (in a disjunction) of the bounds
+	keep := func(idxs []int) int {
+		if len(idxs) == 0 {
+			return -1
+		}
+		best := idxs[0]
+		for _, idx := range idxs[1:] {
+			if bounds[idx].tighter(bounds[best]) == (op == bools.OpAnd) && bounds[idx] != bounds[best] {
+				drop[best], best = true, idx
+			} else {
+				drop[idx] = true
+			}
+		}
+		return best
+	}
+
+	for _, attr := range attrs {
+		if op == bools.OpOr {
+			lo, hi := keep(attr.lower), keep(attr.upper)
+			// a value equal to a number within a remaining bound is already matched by the bound
+			for _, idx := range attr.equal {
+				val, ok := comp.Number(preds[idx].Value)
+				if ok && ((lo >= 0 && bounds[lo].holds(val)) || (hi >= 0 && bounds[hi].holds(val))) {
+					drop[idx] = true
+				}
+			}
+			continue
+		}
+
+		if len(attr.equal) > 0 {
+			val := preds[attr.equal[0]].Value
+			for _, idx := range attr.equal[1:] {
+				if preds[idx].Value != val {
+					return nil, false
+				}
+				drop[idx] = true
+			}
+			for _, idx := range attr.notEqual {
+				if preds[idx].Value == val {
+					return nil, false
+				}
+				drop[idx] = true
+			}
+			num, isNum := comp.Number(val)
+			for _, idx := range append(attr.lower, attr.upper...) {
+				if !isNum || !bounds[idx].holds(num) {
+					return nil, false
+				}
+				drop[idx] = true
+			}
+			continue
+		}
+
+		lo, hi := keep(attr.lower), keep(attr.upper)
+		if lo >= 0 && hi >= 0 && empty(bounds[lo], bounds[hi]) {
+			return nil, false
+		}
+	}
+
+	var result []parse.AST
+	for idx, operand := range operands {
+		if !drop[idx] {
+			result = append(result, operand)
+		}
+	}
+	return result, true
+}
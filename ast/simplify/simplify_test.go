@@ -0,0 +1,252 @@
+package simplify
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// records holds values around the literals used in the test cases, including missing and non-numeric values.
+var records = func() []map[string]string {
+	var result []map[string]string
+	for _, age := range []string{"", "19", "20", "25", "30", "31", "45", "NaN", "thirty"} {
+		for _, dept := range []string{"", "A", "B", "Sales"} {
+			record := map[string]string{"x": "1"}
+			if age != "" {
+				record["age"] = age
+			}
+			if dept != "" {
+				record["dept"] = dept
+			}
+			result = append(result, record)
+		}
+	}
+	return result
+}()
+
+func TestSimplify(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		ruleString   string
+		expectedRule string
+	}{
+		{
+			desc:         "merged rule with duplicate clauses",
+			ruleString:   "(age > 30) AND ((age > 30) AND (x == 1))",
+			expectedRule: "age > 30 AND x == 1",
+		},
+		{
+			desc:         "subsumed lower bound",
+			ruleString:   "age > 30 AND age > 20",
+			expectedRule: "age > 30",
+		},
+		{
+			desc:         "strict bound at the same value is tighter",
+			ruleString:   "age >= 30 AND age > 30.0 AND age <= 45 AND age < 50",
+			expectedRule: "age > 30.0 AND age <= 45",
+		},
+		{
+			desc:         "loosest bounds in a disjunction",
+			ruleString:   "age > 30 OR age >= 30 OR age < 20 OR age < 10 OR age == 25 OR age == 31",
+			expectedRule: "age >= 30 OR age < 20 OR age == 25",
+		},
+		{
+			desc:         "empty interval",
+			ruleString:   "age > 40 AND age < 30",
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:         "touching strict bounds",
+			ruleString:   "age >= 30 AND age < 30",
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:         "conflicting equalities",
+			ruleString:   "dept == 'A' AND dept == 'B'",
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:         "equality implies inequality and bounds",
+			ruleString:   "dept == 'A' AND dept != 'B' AND age == 31 AND age > 30",
+			expectedRule: "dept == 'A' AND age == 31",
+		},
+		{
+			desc:         "equality outside bound",
+			ruleString:   "age == 25 AND age > 30",
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:         "double negation",
+			ruleString:   "NOT (NOT (age > 30))",
+			expectedRule: "age > 30",
+		},
+		{
+			desc:         "negation is not pushed into comparisons",
+			ruleString:   "NOT (age > 30) AND age > 20",
+			expectedRule: "NOT (age > 30) AND age > 20",
+		},
+		{
+			desc:         "complement",
+			ruleString:   "age > 30 AND dept == 'A' AND NOT (age > 30)",
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:         "excluded middle",
+			ruleString:   "dept == 'A' OR NOT (dept == 'A')",
+			expectedRule: "TRUE",
+		},
+		{
+			desc:         "absorption",
+			ruleString:   "age > 30 AND (age > 30 OR dept == 'A')",
+			expectedRule: "age > 30",
+		},
+		{
+			desc:         "absorption in a disjunction",
+			ruleString:   "dept == 'A' OR (dept == 'A' AND age > 30) OR dept == 'B'",
+			expectedRule: "dept == 'A' OR dept == 'B'",
+		},
+		{
+			desc:         "bare terms always match",
+			ruleString:   "active AND age > 1",
+			expectedRule: "age > 1",
+		},
+		{
+			desc:         "comparisons which never match",
+			ruleString:   "age > 'thirty' OR age > NaN OR age > inf OR dept == 'A'",
+			expectedRule: "dept == 'A'",
+		},
+		{
+			desc:         "folding leaves the other clauses",
+			ruleString:   "(age > 40 AND age < 30) OR (dept == 'A' AND (dept == 'B' OR age < 20))",
+			expectedRule: "dept == 'A' AND age < 20",
+		},
+		{
+			desc:         "bounds decide nested comparisons",
+			ruleString:   "age > 30 AND (age > 20 OR dept == 'Sales') AND (age < 25 OR dept == 'A') AND NOT (age == 'thirty')",
+			expectedRule: "age > 30 AND dept == 'A'",
+		},
+		{
+			desc:         "bounds leave undecided comparisons",
+			ruleString:   "age > 30 AND (age == 31 OR age != 40 OR age >= 45)",
+			expectedRule: "age > 30 AND (age == 31 OR age != 40 OR age >= 45)",
+		},
+		{
+			desc:         "unrelated clauses are kept in order",
+			ruleString:   "((age > 30 AND dept == 'Sales')) AND (x > 20000 OR age > 50)",
+			expectedRule: "age > 30 AND dept == 'Sales' AND (x > 20000 OR age > 50)",
+		},
+		{
+			desc:         "constant children change the count",
+			ruleString:   "ATLEAST(2, flag, age > 40 AND age < 30, age > 30, dept == 'A')",
+			expectedRule: "age > 30 OR dept == 'A'",
+		},
+		{
+			desc:         "count of all children",
+			ruleString:   "ATLEAST(3, age > 30, age > 20, dept == 'A')",
+			expectedRule: "age > 30 AND dept == 'A'",
+		},
+		{
+			desc:         "count above the number of children",
+			ruleString:   "ATLEAST(3, age > 30, NOT (flag), dept == 'A')",
+			expectedRule: "NOT (TRUE)",
+		},
+		{
+			desc:         "simplified children",
+			ruleString:   "MAJORITY(age > 30 AND age > 20, dept == 'A', NOT (NOT (dept == 'B')))",
+			expectedRule: "ATLEAST(2, age > 30, dept == 'A', dept == 'B')",
+		},
+		{
+			desc:         "exactly none",
+			ruleString:   "EXACTLY(1, flag, age > 30, dept == 'A')",
+			expectedRule: "NOT (age > 30 OR dept == 'A')",
+		},
+		{
+			desc:         "priority is kept",
+			ruleString:   "PRIORITY(age > 30 OR NOT (age > 30), dept == 'A')",
+			expectedRule: "PRIORITY(age > 30 OR NOT (age > 30), dept == 'A')",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := grammar.MustParse(tt.ruleString)
+			original := fmt.Sprint(ast)
+
+			simplified, err := Simplify(ast)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedRule, fmt.Sprint(simplified))
+			assert.Equal(t, original, fmt.Sprint(ast), "the input must not be modified")
+
+			// the simplified rule must round trip and match the same records
+			reparsed := grammar.MustParse(fmt.Sprint(simplified))
+			before, err := compile.Compile(ast)
+			assert.Nil(t, err)
+			after, err := compile.Compile(reparsed)
+			assert.Nil(t, err)
+			for _, record := range records {
+				assert.Equal(t, before(record), after(record), "record %v", record)
+			}
+		})
+	}
+}
+
+// TestSimplifyRandomRules checks that random rules built from comparisons on a few attributes keep matching the same
+// records once simplified.
+func TestSimplifyRandomRules(t *testing.T) {
+
+	atoms := []string{
+		"age > 30", "age >= 30", "age < 30", "age <= 20", "age > 20", "age == 30", "age == 25", "age != 30",
+		"dept == 'A'", "dept == 'B'", "dept != 'A'", "flag",
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	// the parser only takes counts between 1 and the number of rules
+	count := func() string {
+		if k := rnd.Intn(4); k > 0 {
+			return fmt.Sprint(k)
+		}
+		return "1"
+	}
+	var build func(depth int) string
+	build = func(depth int) string {
+		switch n := rnd.Intn(8); {
+		case depth == 0 || n < 2:
+			return atoms[rnd.Intn(len(atoms))]
+		case n == 2:
+			return "NOT (" + build(depth-1) + ")"
+		case n == 3:
+			return "(" + build(depth-1) + ") OR (" + build(depth-1) + ")"
+		case n == 4:
+			return "ATLEAST(" + count() + ", " + build(depth-1) + ", " + build(depth-1) + ", " + build(depth-1) + ")"
+		case n == 5:
+			return "EXACTLY(" + count() + ", " + build(depth-1) + ", " + build(depth-1) + ", " + build(depth-1) + ")"
+		case n == 6:
+			return "PRIORITY(" + build(depth-1) + ", " + build(depth-1) + ")"
+		default:
+			return "(" + build(depth-1) + ") AND (" + build(depth-1) + ")"
+		}
+	}
+
+	for i := 0; i < 500; i++ {
+		rule := build(4)
+		ast := grammar.MustParse(rule)
+		simplified, err := Simplify(ast)
+		assert.Nil(t, err)
+
+		before, err := compile.Compile(ast)
+		assert.Nil(t, err)
+		after, err := compile.Compile(grammar.MustParse(fmt.Sprint(simplified)))
+		assert.Nil(t, err)
+		for _, record := range records {
+			if !assert.Equal(t, before(record), after(record), "rule %q simplified to %q, record %v", rule, simplified, record) {
+				return
+			}
+		}
+	}
+}
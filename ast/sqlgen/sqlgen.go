@@ -0,0 +1,248 @@
+// Package sqlgen compiles rule ASTs into parameterized SQL WHERE fragments, so that a rule can be run against a table
+// instead of a single record.
+package sqlgen
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// ErrUnsupported is returned for rule constructs which have no SQL equivalent.
+var ErrUnsupported = errors.New("unsupported construct")
+
+// ErrIdentifier is returned for attribute or column names which cannot be safely quoted.
+var ErrIdentifier = errors.New("invalid identifier")
+
+// identifier matches one dot-separated part of a column name. Quote characters are excluded because the GORM
+// dialectors do not escape them.
+var identifier = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_$]*$`)
+
+// Where is a parameterized SQL boolean expression. SQL uses the placeholder syntax of the dialect it was compiled for
+// and Args holds the bind arguments in order, so both can be passed directly to database/sql.
+type Where struct {
+	SQL  string
+	Args []any
+}
+
+// Dialector returns the GORM dialector for one of the supported dialect names: sqlite, postgres or mysql. The
+// dialector is only used for quoting and placeholders and is never connected.
+func Dialector(name string) (gorm.Dialector, error) {
+	switch strings.ToLower(name) {
+	case "sqlite":
+		return sqlite.Open(""), nil
+	case "postgres":
+		return postgres.New(postgres.Config{}), nil
+	case "mysql":
+		return mysql.New(mysql.Config{}), nil
+	}
+	return nil, fmt.Errorf("%w: unknown SQL dialect '%s'", ErrUnsupported, name)
+}
+
+// Compile translates a parsed rule into a WHERE fragment for the provided dialector. The columns map translates
+// attribute names into column names, which may be qualified with a table name; when it is nil, attribute names are
+// used as column names. Attributes missing from a non-nil map are reported as an error.
+//
+// Comparisons keep the engine's semantics: ordinal comparisons bind their literal as a number, while equality binds
+// every literal as a string, as the engine compares the text of values. The engine never matches a comparison on a
+// missing attribute, so comparisons under NOT also check that their column is not NULL; elsewhere a comparison with
+// NULL rejects the row just as false does.
+func Compile(ast parse.AST, dialector gorm.Dialector, columns map[string]string) (Where, error) {
+	c := &compiler{
+		dialector: dialector,
+		columns:   columns,
+		stmt:      &gorm.Statement{},
+	}
+	if err := c.compile(ast); err != nil {
+		return Where{}, err
+	}
+	return Where{SQL: c.sql.String(), Args: c.stmt.Vars}, nil
+}
+
+type compiler struct {
+	dialector gorm.Dialector
+	columns   map[string]string
+
+	sql  strings.Builder
+	stmt *gorm.Statement // stmt collects the bind arguments, which some dialectors use to number their placeholders
+
+	negated bool // negated is set under NOT, where a comparison with NULL must be false rather than unknown
+}
+
+func (c *compiler) compile(ast parse.AST) error {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		if ast.Op != bools.OpAnd && ast.Op != bools.OpOr {
+			return fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		c.sql.WriteByte('(')
+		for idx, operand := range bools.Flatten(ast, ast.Op) {
+			if idx > 0 {
+				c.sql.WriteString(" " + ast.Op.String() + " ")
+			}
+			if err := c.compile(operand); err != nil {
+				return err
+			}
+		}
+		c.sql.WriteByte(')')
+		return nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return fmt.Errorf("%w: boolean operator %v", ErrUnsupported, ast.Op)
+		}
+		// every other node, including a comparison under NOT, is written in parentheses of its own
+		_, nested := ast.Expr.(*bools.UnaryExpr)
+		negated := c.negated
+		c.negated = true
+		c.sql.WriteString("NOT ")
+		if nested {
+			c.sql.WriteByte('(')
+		}
+		if err := c.compile(ast.Expr); err != nil {
+			return err
+		}
+		if nested {
+			c.sql.WriteByte(')')
+		}
+		c.negated = negated
+		return nil
+	case *bools.AtLeastExpr:
+		return c.compileCount(ast.Children, ">=", ast.K)
+	case *bools.ExactlyExpr:
+		return c.compileCount(ast.Children, "=", ast.K)
+	case *bools.PriorityExpr:
+		return c.compilePriority(ast.Children)
+	case *comp.EqualExpr, *comp.OrdinalExpr:
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return fmt.Errorf("%w: comparison '%v' must have an attribute on the left and a literal on the right", ErrUnsupported, ast)
+		}
+		return c.compilePredicate(pred)
+	case parse.Unparsed:
+		return fmt.Errorf("%w: '%v' is not a comparison and has no SQL equivalent", ErrUnsupported, ast)
+	}
+	return fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+// compileCount adds up the children which match and compares the sum with k. A CASE condition which is unknown counts
+// as not matching, so the children are compiled as if they were not negated.
+func (c *compiler) compileCount(children []parse.AST, op string, k int) error {
+	negated := c.negated
+	c.negated = false
+	defer func() { c.negated = negated }()
+	c.sql.WriteByte('(')
+	for idx, child := range children {
+		if idx > 0 {
+			c.sql.WriteString(" + ")
+		}
+		c.sql.WriteString("CASE WHEN ")
+		if err := c.compile(child); err != nil {
+			return err
+		}
+		c.sql.WriteString(" THEN 1 ELSE 0 END")
+	}
+	fmt.Fprintf(&c.sql, " %s %d)", op, k)
+	return nil
+}
+
+// compilePriority selects the first child whose attributes are all set, as columns which are not NULL.
+func (c *compiler) compilePriority(children []parse.AST) error {
+	c.sql.WriteString("(CASE")
+	for _, child := range children {
+		attrs := comp.Attributes(child)
+		if len(attrs) == 0 {
+			// a child comparing no attribute always has an opinion, so no later child is ever used
+			c.sql.WriteString(" ELSE ")
+			if err := c.compile(child); err != nil {
+				return err
+			}
+			c.sql.WriteString(" END)")
+			return nil
+		}
+		c.sql.WriteString(" WHEN ")
+		for idx, attr := range attrs {
+			column, err := c.column(attr)
+			if err != nil {
+				return err
+			}
+			if idx > 0 {
+				c.sql.WriteString(" AND ")
+			}
+			c.dialector.QuoteTo(&c.sql, column)
+			c.sql.WriteString(" IS NOT NULL")
+		}
+		c.sql.WriteString(" THEN ")
+		if err := c.compile(child); err != nil {
+			return err
+		}
+	}
+	c.sql.WriteString(" ELSE 1 = 0 END)")
+	return nil
+}
+
+func (c *compiler) compilePredicate(pred comp.Predicate) error {
+	column, err := c.column(pred.Attr)
+	if err != nil {
+		return err
+	}
+
+	var op string
+	var arg any
+	switch pred.Op {
+	case comp.OpEqual, comp.OpNotEqual:
+		op = "="
+		if pred.Op == comp.OpNotEqual {
+			op = "<>"
+		}
+		arg = pred.Value
+	case comp.OpGreater, comp.OpGreaterOrEqual, comp.OpLess, comp.OpLessOrEqual:
+		op = pred.Op.String()
+		var ok bool
+		if arg, ok = pred.NumericLiteral(); !ok {
+			return fmt.Errorf("%w: ordinal comparison '%v' against non-numeric literal", ErrUnsupported, pred)
+		}
+	default:
+		return fmt.Errorf("%w: comparison operator %v", ErrUnsupported, pred.Op)
+	}
+
+	if c.negated {
+		c.sql.WriteByte('(')
+		c.dialector.QuoteTo(&c.sql, column)
+		c.sql.WriteString(" IS NOT NULL AND ")
+	}
+	c.dialector.QuoteTo(&c.sql, column)
+	c.sql.WriteString(" " + op + " ")
+	c.stmt.Vars = append(c.stmt.Vars, arg)
+	c.dialector.BindVarTo(&c.sql, c.stmt, arg)
+	if c.negated {
+		c.sql.WriteByte(')')
+	}
+	return nil
+}
+
+// column returns the column for attr, checking that every part of it is a plain identifier.
+func (c *compiler) column(attr string) (string, error) {
+	column := attr
+	if c.columns != nil {
+		var ok bool
+		if column, ok = c.columns[attr]; !ok {
+			return "", fmt.Errorf("%w: no column mapped for attribute '%s'", ErrIdentifier, attr)
+		}
+	}
+	for _, part := range strings.Split(column, ".") {
+		if !identifier.MatchString(part) {
+			return "", fmt.Errorf("%w: column '%s' for attribute '%s'", ErrIdentifier, column, attr)
+		}
+	}
+	return column, nil
+}
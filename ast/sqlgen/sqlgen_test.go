@@ -0,0 +1,259 @@
+package sqlgen
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCompile(t *testing.T) {
+
+	rule := "(age > 30 AND department == 'Marketing') AND (salary >= 20000.5 OR NOT (experience < 5))"
+
+	testCases := []struct {
+		desc        string
+		dialect     string
+		columns     map[string]string
+		expectedSQL string
+	}{
+		{
+			desc:        "sqlite",
+			dialect:     "sqlite",
+			expectedSQL: "(`age` > ? AND `department` = ? AND (`salary` >= ? OR NOT (`experience` IS NOT NULL AND `experience` < ?)))",
+		},
+		{
+			desc:        "postgres",
+			dialect:     "postgres",
+			expectedSQL: `("age" > $1 AND "department" = $2 AND ("salary" >= $3 OR NOT ("experience" IS NOT NULL AND "experience" < $4)))`,
+		},
+		{
+			desc:        "mysql with column mapping",
+			dialect:     "mysql",
+			columns:     map[string]string{"age": "e.age", "department": "e.dept", "salary": "pay", "experience": "years"},
+			expectedSQL: "(`e`.`age` > ? AND `e`.`dept` = ? AND (`pay` >= ? OR NOT (`years` IS NOT NULL AND `years` < ?)))",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			dialector, err := Dialector(tt.dialect)
+			assert.Nil(t, err)
+
+			where, err := Compile(grammar.MustParse(rule), dialector, tt.columns)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedSQL, where.SQL)
+			assert.Equal(t, []any{int64(30), "Marketing", 20000.5, int64(5)}, where.Args)
+		})
+	}
+}
+
+func TestCompileCountsAndPriority(t *testing.T) {
+
+	dialector, err := Dialector("sqlite")
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		desc         string
+		rule         string
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			desc:         "at least",
+			rule:         "ATLEAST(2, age > 30, department == 'Marketing', salary > 20000)",
+			expectedSQL:  "(CASE WHEN `age` > ? THEN 1 ELSE 0 END + CASE WHEN `department` = ? THEN 1 ELSE 0 END + CASE WHEN `salary` > ? THEN 1 ELSE 0 END >= 2)",
+			expectedArgs: []any{int64(30), "Marketing", int64(20000)},
+		},
+		{
+			desc:         "unquoted equality literals are strings",
+			rule:         "age == 30 AND active != true",
+			expectedSQL:  "(`age` = ? AND `active` <> ?)",
+			expectedArgs: []any{"30", "true"},
+		},
+		{
+			desc:         "not",
+			rule:         "NOT (age > 30 AND NOT (department == 'Sales'))",
+			expectedSQL:  "NOT ((`age` IS NOT NULL AND `age` > ?) AND NOT (`department` IS NOT NULL AND `department` = ?))",
+			expectedArgs: []any{int64(30), "Sales"},
+		},
+		{
+			desc:         "xor",
+			rule:         "XOR(age > 30, department == 'Marketing')",
+			expectedSQL:  "(CASE WHEN `age` > ? THEN 1 ELSE 0 END + CASE WHEN `department` = ? THEN 1 ELSE 0 END = 1)",
+			expectedArgs: []any{int64(30), "Marketing"},
+		},
+		{
+			desc:         "priority",
+			rule:         "PRIORITY(vip == 'true' AND region == 'EU', age > 30)",
+			expectedSQL:  "(CASE WHEN `vip` IS NOT NULL AND `region` IS NOT NULL THEN (`vip` = ? AND `region` = ?) WHEN `age` IS NOT NULL THEN `age` > ? ELSE 1 = 0 END)",
+			expectedArgs: []any{"true", "EU", int64(30)},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			where, err := Compile(grammar.MustParse(tt.rule), dialector, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedSQL, where.SQL)
+			assert.Equal(t, tt.expectedArgs, where.Args)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+
+	dialector, err := Dialector("sqlite")
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		desc          string
+		ruleString    string
+		columns       map[string]string
+		expectedError error
+	}{
+		{
+			desc:          "ordinal comparison against a string",
+			ruleString:    "age > 'thirty'",
+			expectedError: ErrUnsupported,
+		},
+		{
+			desc:          "unmapped attribute",
+			ruleString:    "age > 30 AND department == 'Sales'",
+			columns:       map[string]string{"age": "age"},
+			expectedError: ErrIdentifier,
+		},
+		{
+			desc:          "column with quote characters",
+			ruleString:    "age > 30",
+			columns:       map[string]string{"age": "age` = 1 OR `x"},
+			expectedError: ErrIdentifier,
+		},
+		{
+			desc:          "empty column part",
+			ruleString:    "age > 30",
+			columns:       map[string]string{"age": "employees."},
+			expectedError: ErrIdentifier,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := Compile(grammar.MustParse(tt.ruleString), dialector, tt.columns)
+			assert.True(t, errors.Is(err, tt.expectedError), "unexpected error: %v", err)
+		})
+	}
+
+	_, err = Compile(parse.Unparsed{Contents: []string{"active"}}, dialector, nil)
+	assert.True(t, errors.Is(err, ErrUnsupported))
+
+	_, err = Dialector("oracle")
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
+
+func TestCompiledQuery(t *testing.T) {
+
+	type Employee struct {
+		Id         uint
+		Age        int
+		Department string
+		Salary     float64
+	}
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&Employee{}))
+	assert.Nil(t, db.Create(&[]Employee{
+		{Age: 31, Department: "Marketing", Salary: 51000},
+		{Age: 45, Department: "Sales", Salary: 51000},
+		{Age: 25, Department: "Marketing", Salary: 30000},
+		{Age: 40, Department: "Marketing", Salary: 10000},
+	}).Error)
+
+	where, err := Compile(grammar.MustParse("age > 30 AND department == 'Marketing' AND salary > 20000"), db.Dialector, nil)
+	assert.Nil(t, err)
+
+	var count int64
+	assert.Nil(t, db.Raw("SELECT count(*) FROM employees WHERE "+where.SQL, where.Args...).Scan(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	where, err = Compile(grammar.MustParse("XOR(age > 30, department == 'Marketing')"), db.Dialector, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Raw("SELECT count(*) FROM employees WHERE "+where.SQL, where.Args...).Scan(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCompiledQueryWithNulls(t *testing.T) {
+
+	type Employee struct {
+		Id         uint
+		Age        *int
+		Department *string
+	}
+	age := func(n int) *int { return &n }
+	dept := func(s string) *string { return &s }
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&Employee{}))
+	employees := []Employee{
+		{Age: age(31), Department: dept("Sales")},
+		{Age: age(25), Department: dept("Marketing")},
+		{Age: nil, Department: dept("Sales")},
+		{Age: age(40), Department: nil},
+		{Age: nil, Department: nil},
+	}
+	assert.Nil(t, db.Create(&employees).Error)
+
+	// the engine sees a NULL column as a missing attribute
+	records := map[uint]map[string]string{}
+	for _, e := range employees {
+		record := map[string]string{}
+		if e.Age != nil {
+			record["age"] = strconv.Itoa(*e.Age)
+		}
+		if e.Department != nil {
+			record["department"] = *e.Department
+		}
+		records[e.Id] = record
+	}
+
+	rules := []string{
+		"NOT (age > 30)",
+		"NOT (age > 30 OR department == 'Sales')",
+		"NOT (NOT (age > 30))",
+		"NOT (department != 'Sales') AND NOT (age <= 30)",
+		"age == 31 OR NOT (department == 'Marketing')",
+		"XOR(NOT (age > 30), department == 'Sales')",
+		"NOT (ATLEAST(1, age > 30, department == 'Sales'))",
+		"PRIORITY(NOT (department == 'Sales'), age > 30)",
+		"NOT (PRIORITY(department == 'Sales', age > 30))",
+	}
+
+	for _, rule := range rules {
+		t.Run(rule, func(t *testing.T) {
+			ast := grammar.MustParse(rule)
+			match, err := compile.Compile(ast)
+			assert.Nil(t, err)
+			var expected []uint
+			for _, e := range employees {
+				if match(records[e.Id]) {
+					expected = append(expected, e.Id)
+				}
+			}
+
+			where, err := Compile(ast, db.Dialector, nil)
+			assert.Nil(t, err)
+			var actual []uint
+			assert.Nil(t, db.Raw("SELECT id FROM employees WHERE "+where.SQL+" ORDER BY id", where.Args...).Scan(&actual).Error)
+			assert.Equal(t, expected, actual, where.SQL)
+		})
+	}
+}
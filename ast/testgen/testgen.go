@@ -0,0 +1,251 @@
+// Package testgen generates sample records which a rule matches and records it does not match, for use as test data.
+//
+// Records are built from seeds: a record matching each branch of the rule's disjunctive normal form and a record
+// failing each clause of its conjunctive normal form, as found by the sat package. Each seed is then varied one
+// attribute at a time, trying every literal the rule compares the attribute with, the float32 numbers just above and
+// just below every bound as well as one unit away from it, a value which is not a number and a missing attribute. The
+// varied records are sorted into matching and failing ones by evaluating the rule, so every record is labelled the way
+// the rule engine would.
+package testgen
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/normal"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/sat"
+)
+
+// DefaultLimit is the largest number of matching records, and of failing records, Generate returns.
+const DefaultLimit = 50
+
+// maxSeeds is the largest number of DNF branches, and of CNF clauses, solved for seeds. All the solving shares one
+// limit of sat.DefaultLimit branches.
+const maxSeeds = 64
+
+// Result holds the generated records. Attributes missing from a record are not set in it.
+type Result struct {
+	Matching []map[string]string `json:"matching"`
+	Failing  []map[string]string `json:"failing"`
+}
+
+// Generate returns records which the rule matches and records it does not match. Either list is empty if the rule
+// matches every record or no record.
+func Generate(ast parse.AST) (*Result, error) {
+	return GenerateLimit(ast, DefaultLimit)
+}
+
+// GenerateLimit is Generate with a custom limit on the number of records of each list.
+func GenerateLimit(ast parse.AST, limit int) (*Result, error) {
+	match, err := compile.Compile(ast)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := seeds(ast, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{match: match, limit: limit, seen: map[string]bool{}, result: &Result{
+		Matching: []map[string]string{},
+		Failing:  []map[string]string{},
+	}}
+	for _, seed := range seeds {
+		g.add(seed)
+	}
+	attrs, values := candidates(ast)
+	for _, seed := range seeds {
+		for _, attr := range attrs {
+			if g.full() {
+				return g.result, nil
+			}
+			for _, val := range values[attr] {
+				g.add(with(seed, attr, val, true))
+			}
+			g.add(with(seed, attr, "", false))
+		}
+	}
+	return g.result, nil
+}
+
+// seeds returns a record matching each DNF branch of the rule and a record failing each CNF clause, or a single
+// record of each kind when a normal form is too large. At most limit, and at most maxSeeds, branches and clauses are
+// solved, and solving stops once the solver's limit is reached.
+func seeds(ast parse.AST, limit int) ([]map[string]string, error) {
+	if limit > maxSeeds {
+		limit = maxSeeds
+	}
+	var result []map[string]string
+	solver := sat.NewSolver(sat.DefaultLimit)
+	exceeded := false
+	solve := func(ast parse.AST) error {
+		if exceeded {
+			return nil
+		}
+		witness, ok, err := solver.Solve(ast)
+		if ok {
+			result = append(result, witness)
+		}
+		if errors.Is(err, sat.ErrLimit) {
+			exceeded = true
+			return nil
+		}
+		return err
+	}
+	not := func(ast parse.AST) parse.AST {
+		return &bools.UnaryExpr{Op: bools.OpNot, Expr: ast}
+	}
+
+	dnf, err := normal.Convert(ast, normal.DNF)
+	if errors.Is(err, normal.ErrTooLarge) {
+		dnf = ast
+	} else if err != nil {
+		return nil, err
+	}
+	for _, branch := range first(bools.Flatten(dnf, bools.OpOr), limit) {
+		if err := solve(branch); err != nil {
+			return nil, err
+		}
+	}
+
+	cnf, err := normal.Convert(ast, normal.CNF)
+	if errors.Is(err, normal.ErrTooLarge) {
+		cnf = ast
+	} else if err != nil {
+		return nil, err
+	}
+	for _, clause := range first(bools.Flatten(cnf, bools.OpAnd), limit) {
+		if err := solve(not(clause)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func first(asts []parse.AST, n int) []parse.AST {
+	if len(asts) > n {
+		return asts[:n]
+	}
+	return asts
+}
+
+// candidates returns the attributes the rule compares, in order of first appearance, and the values to try for each.
+func candidates(ast parse.AST) ([]string, map[string][]string) {
+	var attrs []string
+	values := map[string][]string{}
+	add := func(attr, val string) {
+		for _, v := range values[attr] {
+			if v == val {
+				return
+			}
+		}
+		values[attr] = append(values[attr], val)
+	}
+
+	var walk func(ast parse.AST)
+	walk = func(ast parse.AST) {
+		for _, child := range bools.Children(ast) {
+			walk(child)
+		}
+		pred, ok := comp.AsPredicate(ast)
+		if !ok {
+			return
+		}
+		if _, ok := values[pred.Attr]; !ok {
+			attrs = append(attrs, pred.Attr)
+			values[pred.Attr] = nil
+		}
+		add(pred.Attr, pred.Value)
+		if pred.Op == comp.OpEqual || pred.Op == comp.OpNotEqual {
+			return
+		}
+		if num, ok := comp.Number(pred.Value); ok && !math.IsNaN(float64(num)) && !math.IsInf(float64(num), 0) {
+			add(pred.Attr, format(math.Nextafter32(num, float32(math.Inf(1)))))
+			add(pred.Attr, format(math.Nextafter32(num, float32(math.Inf(-1)))))
+			add(pred.Attr, format(num+1))
+			add(pred.Attr, format(num-1))
+		}
+	}
+	walk(ast)
+
+	// a value which is not a number and differs from every literal
+	for _, attr := range attrs {
+		other := "other"
+		for idx := 2; contains(values[attr], other); idx++ {
+			other = "other" + strconv.Itoa(idx)
+		}
+		add(attr, other)
+	}
+	return attrs, values
+}
+
+func format(num float32) string {
+	return strconv.FormatFloat(float64(num), 'g', -1, 32)
+}
+
+func contains(values []string, val string) bool {
+	for _, v := range values {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// with returns a copy of the record in which the attribute has the provided value, or is missing if present is false.
+func with(record map[string]string, attr, val string, present bool) map[string]string {
+	result := make(map[string]string, len(record)+1)
+	for k, v := range record {
+		result[k] = v
+	}
+	if present {
+		result[attr] = val
+	} else {
+		delete(result, attr)
+	}
+	return result
+}
+
+type generator struct {
+	match  compile.Rule
+	limit  int
+	seen   map[string]bool
+	result *Result
+}
+
+// add sorts the record into the matching or failing records, unless it was already added or that list is full.
+func (g *generator) add(record map[string]string) {
+	key := key(record)
+	if g.seen[key] {
+		return
+	}
+	g.seen[key] = true
+	if g.match(record) {
+		if len(g.result.Matching) < g.limit {
+			g.result.Matching = append(g.result.Matching, record)
+		}
+	} else if len(g.result.Failing) < g.limit {
+		g.result.Failing = append(g.result.Failing, record)
+	}
+}
+
+// full reports whether both the matching and the failing records have reached the limit.
+func (g *generator) full() bool {
+	return len(g.result.Matching) >= g.limit && len(g.result.Failing) >= g.limit
+}
+
+func key(record map[string]string) string {
+	var pairs []string
+	for k, v := range record {
+		pairs = append(pairs, strconv.Quote(k)+"="+strconv.Quote(v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
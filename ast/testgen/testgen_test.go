@@ -0,0 +1,110 @@
+package testgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+
+	testCases := []struct {
+		desc             string
+		ruleString       string
+		expectedMatching []map[string]string
+		expectedFailing  []map[string]string
+	}{
+		{
+			desc:             "boundaries of a bound",
+			ruleString:       "age > 30",
+			expectedMatching: []map[string]string{{"age": "30.000002"}},
+			expectedFailing:  []map[string]string{{"age": "30"}, {"age": "29.999998"}, {"age": "other"}, {}},
+		},
+		{
+			desc:             "equality",
+			ruleString:       "department == 'Sales' OR department == 'other'",
+			expectedMatching: []map[string]string{{"department": "Sales"}, {"department": "other"}},
+			expectedFailing:  []map[string]string{{"department": "other2"}, {}},
+		},
+		{
+			desc:             "readme rule",
+			ruleString:       "((age > 30 AND department == 'Marketing')) AND (salary > 20000 OR experience > 5)",
+			expectedMatching: []map[string]string{{"age": "30.000002", "department": "Marketing", "salary": "20000.002"}},
+			expectedFailing: []map[string]string{
+				{"age": "30", "department": "Marketing", "salary": "20000.002"},
+				{"department": "Marketing", "salary": "20000.002"},
+				{"age": "30.000002", "department": "other", "salary": "20000.002"},
+			},
+		},
+		{
+			desc:             "negation holds for missing attributes",
+			ruleString:       "NOT (age > 30)",
+			expectedMatching: []map[string]string{{}, {"age": "30"}, {"age": "other"}},
+			expectedFailing:  []map[string]string{{"age": "30.000002"}},
+		},
+		{
+			desc:            "never matches",
+			ruleString:      "age > 30 AND age < 20",
+			expectedFailing: []map[string]string{{}},
+		},
+		{
+			desc:             "always matches",
+			ruleString:       "active",
+			expectedMatching: []map[string]string{{}},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			ast := grammar.MustParse(tt.ruleString)
+			result, err := Generate(ast)
+			assert.Nil(t, err)
+
+			match, err := compile.Compile(ast)
+			assert.Nil(t, err)
+			for _, record := range result.Matching {
+				assert.True(t, match(record), "record %v must match", record)
+			}
+			for _, record := range result.Failing {
+				assert.False(t, match(record), "record %v must not match", record)
+			}
+			assert.Equal(t, len(tt.expectedMatching) == 0, len(result.Matching) == 0)
+			assert.Equal(t, len(tt.expectedFailing) == 0, len(result.Failing) == 0)
+			for _, record := range tt.expectedMatching {
+				assert.Contains(t, result.Matching, record)
+			}
+			for _, record := range tt.expectedFailing {
+				assert.Contains(t, result.Failing, record)
+			}
+		})
+	}
+}
+
+func TestGenerateLimit(t *testing.T) {
+	result, err := GenerateLimit(grammar.MustParse("a > 1 OR b > 2 OR c > 3 OR d > 4"), 3)
+	assert.Nil(t, err)
+	assert.Len(t, result.Matching, 3)
+	assert.Len(t, result.Failing, 3)
+}
+
+func TestSeedsAreCapped(t *testing.T) {
+	clauses := make([]string, 200)
+	for idx := range clauses {
+		clauses[idx] = fmt.Sprintf("a%d > 1", idx)
+	}
+	ast := grammar.MustParse(strings.Join(clauses, " OR "))
+
+	result, err := seeds(ast, 1000)
+	assert.Nil(t, err)
+	// maxSeeds of the 200 DNF branches, and the single CNF clause
+	assert.Len(t, result, maxSeeds+1)
+
+	result, err = seeds(ast, 5)
+	assert.Nil(t, err)
+	assert.Len(t, result, 6)
+}
@@ -0,0 +1,110 @@
+// Package tree renders rule ASTs as plain trees of nodes which can be marshalled to JSON.
+package tree
+
+import (
+	"fmt"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// Types of Node.
+const (
+	And        = "and"
+	Or         = "or"
+	Not        = "not"
+	AtLeast    = "atleast"
+	Exactly    = "exactly"
+	Priority   = "priority"
+	Comparison = "comparison"
+	Term       = "term"
+)
+
+// Node is a node of a rule. AND and OR chains are flattened into a single node with one child per operand, and ATLEAST
+// and EXACTLY nodes set K. A comparison between an attribute and a literal sets Attribute, Value and Quoted; any other
+// comparison has its two operands as children.
+type Node struct {
+	Type      string  `json:"type"`
+	K         *int    `json:"k,omitempty"`
+	Op        string  `json:"op,omitempty"`
+	Attribute string  `json:"attribute,omitempty"`
+	Value     string  `json:"value,omitempty"`
+	Quoted    bool    `json:"quoted,omitempty"`
+	Children  []*Node `json:"children,omitempty"`
+}
+
+// Path renders the position of a node in its rule as the index of each child taken from the root down, as in /0/2.
+// The root itself is /.
+func Path(indexes []int) string {
+	if len(indexes) == 0 {
+		return "/"
+	}
+	var result strings.Builder
+	for _, idx := range indexes {
+		fmt.Fprintf(&result, "/%d", idx)
+	}
+	return result.String()
+}
+
+// Encode converts a parsed rule into a tree of nodes.
+func Encode(ast parse.AST) (*Node, error) {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		node := &Node{}
+		switch ast.Op {
+		case bools.OpAnd:
+			node.Type = And
+		case bools.OpOr:
+			node.Type = Or
+		default:
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		return node, encodeChildren(node, bools.Flatten(ast, ast.Op)...)
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			return nil, fmt.Errorf("%w: boolean operator %v", parse.ErrUnknownAST, ast.Op)
+		}
+		node := &Node{Type: Not}
+		return node, encodeChildren(node, ast.Expr)
+	case *bools.AtLeastExpr:
+		k := ast.K
+		node := &Node{Type: AtLeast, K: &k}
+		return node, encodeChildren(node, ast.Children...)
+	case *bools.ExactlyExpr:
+		k := ast.K
+		node := &Node{Type: Exactly, K: &k}
+		return node, encodeChildren(node, ast.Children...)
+	case *bools.PriorityExpr:
+		node := &Node{Type: Priority}
+		return node, encodeChildren(node, ast.Children...)
+	case *comp.EqualExpr:
+		return encodeComparison(ast, ast.Op, ast.LHS, ast.RHS)
+	case *comp.OrdinalExpr:
+		return encodeComparison(ast, ast.Op, ast.LHS, ast.RHS)
+	case parse.Unparsed:
+		return &Node{Type: Term, Value: ast.String()}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func encodeComparison(ast parse.AST, op comp.Op, lhs, rhs parse.AST) (*Node, error) {
+	node := &Node{Type: Comparison, Op: op.String()}
+	if pred, ok := comp.AsPredicate(ast); ok {
+		node.Attribute, node.Value, node.Quoted = pred.Attr, pred.Value, pred.Quoted
+		return node, nil
+	}
+	return node, encodeChildren(node, lhs, rhs)
+}
+
+func encodeChildren(node *Node, children ...parse.AST) error {
+	for _, child := range children {
+		encoded, err := Encode(child)
+		if err != nil {
+			return err
+		}
+		node.Children = append(node.Children, encoded)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package tree
+
+import (
+	"encoding/json"
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+
+	testCases := []struct {
+		desc         string
+		ruleString   string
+		expectedJSON string
+	}{
+		{
+			desc:         "comparison",
+			ruleString:   "age > 30",
+			expectedJSON: `{"type":"comparison","op":">","attribute":"age","value":"30"}`,
+		},
+		{
+			desc:       "flattened chains",
+			ruleString: "age > 30 AND (dept == 'A' OR (dept == 'B' OR active)) AND NOT (x != 1)",
+			expectedJSON: `{"type":"and","children":[` +
+				`{"type":"comparison","op":">","attribute":"age","value":"30"},` +
+				`{"type":"or","children":[` +
+				`{"type":"comparison","op":"==","attribute":"dept","value":"A","quoted":true},` +
+				`{"type":"comparison","op":"==","attribute":"dept","value":"B","quoted":true},` +
+				`{"type":"term","value":"active"}]},` +
+				`{"type":"not","children":[{"type":"comparison","op":"!=","attribute":"x","value":"1"}]}]}`,
+		},
+		{
+			desc:       "counts and priority",
+			ruleString: "PRIORITY(XOR(a == 1, b == 2), ATLEAST(1, c > 3))",
+			expectedJSON: `{"type":"priority","children":[` +
+				`{"type":"exactly","k":1,"children":[` +
+				`{"type":"comparison","op":"==","attribute":"a","value":"1"},` +
+				`{"type":"comparison","op":"==","attribute":"b","value":"2"}]},` +
+				`{"type":"atleast","k":1,"children":[{"type":"comparison","op":">","attribute":"c","value":"3"}]}]}`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			node, err := Encode(grammar.MustParse(tt.ruleString))
+			assert.Nil(t, err)
+			encoded, err := json.Marshal(node)
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.expectedJSON, string(encoded))
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "/", Path(nil))
+	assert.Equal(t, "/0", Path([]int{0}))
+	assert.Equal(t, "/1/0/12", Path([]int{1, 0, 12}))
+}
@@ -0,0 +1,42 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Disassemble renders the program as text for debugging: the slot table, the constant pool, then one instruction per
+// line with its offset and resolved operands.
+func (p *Program) Disassemble() string {
+	var sb strings.Builder
+	for idx, slot := range p.slots {
+		fmt.Fprintf(&sb, "slot  %d  %s\n", idx, strconv.Quote(slot))
+	}
+	for idx, konst := range p.consts {
+		fmt.Fprintf(&sb, "const %d  %s\n", idx, strconv.Quote(konst.str))
+	}
+	for pc := 0; pc < len(p.code); {
+		op := Opcode(p.code[pc])
+		fmt.Fprintf(&sb, "%04d  ", pc)
+		switch {
+		case op == OpHas:
+			slot := binary.LittleEndian.Uint16(p.code[pc+1:])
+			fmt.Fprintf(&sb, "%-4s  slot %d (%s)", op, slot, p.slots[slot])
+		case op == OpAtLeast || op == OpExactly:
+			fmt.Fprintf(&sb, "%-4s  %d", op, binary.LittleEndian.Uint16(p.code[pc+1:]))
+		case op.operands() == 0:
+			sb.WriteString(op.String())
+		case op.operands() == 1:
+			fmt.Fprintf(&sb, "%-4s  %04d", op, binary.LittleEndian.Uint16(p.code[pc+1:]))
+		case op.operands() == 2:
+			slot := binary.LittleEndian.Uint16(p.code[pc+1:])
+			konst := binary.LittleEndian.Uint16(p.code[pc+3:])
+			fmt.Fprintf(&sb, "%-4s  slot %d (%s), const %d (%s)", op, slot, p.slots[slot], konst, strconv.Quote(p.consts[konst].str))
+		}
+		sb.WriteByte('\n')
+		pc += 1 + 2*op.operands()
+	}
+	return sb.String()
+}
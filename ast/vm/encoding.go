@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// magic prefixes serialized programs and carries the format version in its last byte. Version 1 pushed the result of
+// every child of a counting rule before counting them.
+var magic = []byte{'R', 'V', 'M', 2}
+
+// ErrVersion is returned when serialized bytecode was written in another version of the format. Such bytecode is
+// not broken, and the rule it was compiled from can be compiled again.
+var ErrVersion = fmt.Errorf("%w: unsupported format version", ErrBytecode)
+
+// MarshalBinary serializes the program. The format is the magic header followed by the slot table, the constant pool
+// and the code, each as a uvarint count followed by uvarint length-prefixed entries; the code is a single entry.
+func (p *Program) MarshalBinary() ([]byte, error) {
+	buf := append([]byte{}, magic...)
+	buf = binary.AppendUvarint(buf, uint64(len(p.slots)))
+	for _, slot := range p.slots {
+		buf = appendBytes(buf, []byte(slot))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(p.consts)))
+	for _, konst := range p.consts {
+		buf = appendBytes(buf, []byte(konst.str))
+	}
+	return appendBytes(buf, p.code), nil
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// UnmarshalBinary loads a program serialized by MarshalBinary, verifying it so that it is safe to run.
+func (p *Program) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, magic) {
+		if len(data) >= len(magic) && bytes.HasPrefix(data, magic[:len(magic)-1]) {
+			return fmt.Errorf("%w %d", ErrVersion, data[len(magic)-1])
+		}
+		return fmt.Errorf("%w: unknown header", ErrBytecode)
+	}
+	r := &reader{data: data[len(magic):]}
+	loaded := &Program{}
+	for n := r.count(); n > 0 && r.err == nil; n-- {
+		loaded.slots = append(loaded.slots, string(r.bytes()))
+	}
+	for n := r.count(); n > 0 && r.err == nil; n-- {
+		loaded.consts = append(loaded.consts, newConstant(string(r.bytes())))
+	}
+	loaded.code = r.bytes()
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("%d trailing bytes", len(r.data))
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: %v", ErrBytecode, r.err)
+	}
+	if err := loaded.verify(); err != nil {
+		return fmt.Errorf("%w: %v", ErrBytecode, err)
+	}
+	*p = *loaded
+	return nil
+}
+
+// Load is a convenience wrapper around UnmarshalBinary.
+func Load(data []byte) (*Program, error) {
+	p := &Program{}
+	if err := p.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// reader decodes uvarint-prefixed entries, remembering the first error.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) count() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(r.data)
+	if size <= 0 || n > uint64(len(r.data)) {
+		// every entry takes at least one byte, so larger counts are corrupt
+		r.err = fmt.Errorf("malformed count")
+		return 0
+	}
+	r.data = r.data[size:]
+	return n
+}
+
+func (r *reader) bytes() []byte {
+	n := r.count()
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)) {
+		r.err = fmt.Errorf("truncated entry")
+		return nil
+	}
+	b := append([]byte{}, r.data[:n]...)
+	r.data = r.data[n:]
+	return b
+}
@@ -0,0 +1,538 @@
+// Package vm compiles rule ASTs into a compact bytecode run by a stack-based virtual machine. Compiled programs can
+// be serialized and stored next to their rule, then loaded and run without parsing the rule again.
+//
+// Each program has a slot table holding the attribute names it reads and a constant pool holding its literals.
+// Instructions are one opcode byte followed by zero, one or two little-endian uint16 operands: comparisons take a slot
+// and a constant, and jumps take an absolute code offset. Jumps only go forward, which bounds the running time of a
+// program by its length, and are used to short-circuit AND and OR and to select the child of a PRIORITY rule. ATLEAST
+// and EXACTLY rules open a counter on a separate counter stack and add the result of each child to it as soon as it is
+// computed, so the value stack they use does not grow with their number of children.
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/parse/comp"
+)
+
+// ErrCompile is returned when a rule cannot be compiled into bytecode.
+var ErrCompile = errors.New("compile error")
+
+// ErrBytecode is returned when serialized bytecode is malformed.
+var ErrBytecode = errors.New("invalid bytecode")
+
+// MaxStack is the deepest value or counter stack a program may use. Both grow with the nesting of the rule, not with
+// its length.
+const MaxStack = 256
+
+// Opcode is a bytecode instruction.
+type Opcode byte
+
+const (
+	OpTrue           Opcode = iota + 1 // OpTrue pushes true.
+	OpFalse                            // OpFalse pushes false.
+	OpEqual                            // OpEqual pushes whether the slot holds exactly the constant.
+	OpNotEqual                         // OpNotEqual pushes whether the slot is set and differs from the constant.
+	OpGreater                          // OpGreater pushes whether the slot is a number greater than the constant.
+	OpGreaterOrEqual                   // OpGreaterOrEqual pushes whether the slot is a number at least the constant.
+	OpLess                             // OpLess pushes whether the slot is a number less than the constant.
+	OpLessOrEqual                      // OpLessOrEqual pushes whether the slot is a number at most the constant.
+	OpNot                              // OpNot negates the top of the stack.
+	OpJumpIfFalse                      // OpJumpIfFalse jumps if the top of the stack is false, and pops it otherwise.
+	OpJumpIfTrue                       // OpJumpIfTrue jumps if the top of the stack is true, and pops it otherwise.
+	OpJump                             // OpJump jumps unconditionally.
+	OpPop                              // OpPop pops the top of the stack.
+	OpHas                              // OpHas pushes whether the slot is set.
+	OpAtLeast                          // OpAtLeast pops a counter and pushes whether it is at least k.
+	OpExactly                          // OpExactly pops a counter and pushes whether it is exactly k.
+	OpZero                             // OpZero pushes a counter set to zero.
+	OpCount                            // OpCount pops a value and adds one to the top counter if it is true.
+)
+
+var mnemonics = map[Opcode]string{
+	OpTrue:           "TRUE",
+	OpFalse:          "FALSE",
+	OpEqual:          "EQ",
+	OpNotEqual:       "NE",
+	OpGreater:        "GT",
+	OpGreaterOrEqual: "GE",
+	OpLess:           "LT",
+	OpLessOrEqual:    "LE",
+	OpNot:            "NOT",
+	OpJumpIfFalse:    "JMPF",
+	OpJumpIfTrue:     "JMPT",
+	OpJump:           "JMP",
+	OpPop:            "POP",
+	OpHas:            "HAS",
+	OpAtLeast:        "ATLEAST",
+	OpExactly:        "EXACTLY",
+	OpZero:           "ZERO",
+	OpCount:          "COUNT",
+}
+
+func (o Opcode) String() string {
+	if name, ok := mnemonics[o]; ok {
+		return name
+	}
+	return "unknown opcode"
+}
+
+// operands returns the number of uint16 operands following the opcode.
+func (o Opcode) operands() int {
+	switch o {
+	case OpEqual, OpNotEqual, OpGreater, OpGreaterOrEqual, OpLess, OpLessOrEqual:
+		return 2
+	case OpJumpIfFalse, OpJumpIfTrue, OpJump, OpHas, OpAtLeast, OpExactly:
+		return 1
+	}
+	return 0
+}
+
+var compOpcodes = map[comp.Op]Opcode{
+	comp.OpEqual:          OpEqual,
+	comp.OpNotEqual:       OpNotEqual,
+	comp.OpGreater:        OpGreater,
+	comp.OpGreaterOrEqual: OpGreaterOrEqual,
+	comp.OpLess:           OpLess,
+	comp.OpLessOrEqual:    OpLessOrEqual,
+}
+
+// constant is an entry of the constant pool. Literals used by ordinal comparisons are converted once, when the
+// program is compiled or loaded.
+type constant struct {
+	str   string
+	num   float32
+	isNum bool
+}
+
+// Program is a compiled rule. Programs are immutable and safe for concurrent use.
+type Program struct {
+	slots  []string
+	consts []constant
+	code   []byte
+}
+
+// Compile compiles a parsed rule into a program which gives the same results as the rule engine's tree-walking
+// evaluator.
+func Compile(ast parse.AST) (*Program, error) {
+	c := &compiler{
+		program:    &Program{},
+		slotIndex:  map[string]int{},
+		constIndex: map[string]int{},
+	}
+	if err := c.compile(ast); err != nil {
+		return nil, err
+	}
+	if len(c.program.code) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: program is longer than %d bytes", ErrCompile, math.MaxUint16)
+	}
+	if err := c.program.verify(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCompile, err)
+	}
+	return c.program, nil
+}
+
+type compiler struct {
+	program    *Program
+	slotIndex  map[string]int
+	constIndex map[string]int
+}
+
+func (c *compiler) compile(ast parse.AST) error {
+	switch ast := ast.(type) {
+	case *bools.BinExpr:
+		var jump Opcode
+		switch ast.Op {
+		case bools.OpAnd:
+			jump = OpJumpIfFalse
+		case bools.OpOr:
+			jump = OpJumpIfTrue
+		default:
+			c.emit(OpFalse)
+			return nil
+		}
+		// every operand but the last one jumps to the end of the chain when it decides the result
+		operands := bools.Flatten(ast, ast.Op)
+		var patches []int
+		for idx, operand := range operands {
+			if err := c.compile(operand); err != nil {
+				return err
+			}
+			if idx < len(operands)-1 {
+				patches = append(patches, c.emit(jump, 0)+1)
+			}
+		}
+		c.patch(patches)
+		return nil
+	case *bools.UnaryExpr:
+		if ast.Op != bools.OpNot {
+			c.emit(OpFalse)
+			return nil
+		}
+		if err := c.compile(ast.Expr); err != nil {
+			return err
+		}
+		c.emit(OpNot)
+		return nil
+	case *bools.AtLeastExpr:
+		return c.count(OpAtLeast, ast.K, ast.Children)
+	case *bools.ExactlyExpr:
+		return c.count(OpExactly, ast.K, ast.Children)
+	case *bools.PriorityExpr:
+		return c.priority(ast.Children)
+	case *comp.EqualExpr:
+		return c.comparison(ast.Op, ast.LHS, ast.RHS)
+	case *comp.OrdinalExpr:
+		return c.comparison(ast.Op, ast.LHS, ast.RHS)
+	case parse.Unparsed:
+		c.emit(OpTrue)
+		return nil
+	}
+	return fmt.Errorf("%w: %v", parse.ErrUnknownAST, ast)
+}
+
+func (c *compiler) count(op Opcode, k int, children []parse.AST) error {
+	if k > len(children) {
+		// too few children to ever reach the count
+		c.emit(OpFalse)
+		return nil
+	}
+	c.emit(OpZero)
+	for _, child := range children {
+		if err := c.compile(child); err != nil {
+			return err
+		}
+		c.emit(OpCount)
+	}
+	c.emit(op, k)
+	return nil
+}
+
+// priority compiles each child behind checks that the record has every attribute it compares. A failed check jumps
+// past the child, to a POP dropping the false it left, and a child which runs jumps to the end with its result.
+func (c *compiler) priority(children []parse.AST) error {
+	var ends []int
+	for _, child := range children {
+		attrs := comp.Attributes(child)
+		var fails []int
+		for _, attr := range attrs {
+			slot, err := c.slot(attr)
+			if err != nil {
+				return err
+			}
+			c.emit(OpHas, slot)
+			fails = append(fails, c.emit(OpJumpIfFalse, 0)+1)
+		}
+		if err := c.compile(child); err != nil {
+			return err
+		}
+		if len(attrs) == 0 {
+			// a child comparing no attribute always has an opinion, so no later child is ever used
+			c.patch(ends)
+			return nil
+		}
+		ends = append(ends, c.emit(OpJump, 0)+1)
+		c.patch(fails)
+		c.emit(OpPop)
+	}
+	c.emit(OpFalse)
+	c.patch(ends)
+	return nil
+}
+
+// patch points the jumps whose operands are at the provided offsets to the end of the code.
+func (c *compiler) patch(offsets []int) {
+	for _, offset := range offsets {
+		binary.LittleEndian.PutUint16(c.program.code[offset:], uint16(len(c.program.code)))
+	}
+}
+
+func (c *compiler) comparison(op comp.Op, lhs, rhs parse.AST) error {
+	opcode, ok := compOpcodes[op]
+	if !ok {
+		c.emit(OpFalse)
+		return nil
+	}
+	literal := operand(rhs)
+	if op != comp.OpEqual && op != comp.OpNotEqual {
+		if _, ok := comp.Number(literal); !ok {
+			// ordinal comparisons against non-numbers never match
+			c.emit(OpFalse)
+			return nil
+		}
+	}
+	slot, err := c.slot(operand(lhs))
+	if err != nil {
+		return err
+	}
+	konst, err := c.constant(literal)
+	if err != nil {
+		return err
+	}
+	c.emit(opcode, slot, konst)
+	return nil
+}
+
+func (c *compiler) slot(name string) (int, error) {
+	if idx, ok := c.slotIndex[name]; ok {
+		return idx, nil
+	}
+	if len(c.program.slots) == math.MaxUint16+1 {
+		return 0, fmt.Errorf("%w: more than %d attributes", ErrCompile, math.MaxUint16+1)
+	}
+	c.slotIndex[name] = len(c.program.slots)
+	c.program.slots = append(c.program.slots, name)
+	return c.slotIndex[name], nil
+}
+
+func (c *compiler) constant(literal string) (int, error) {
+	if idx, ok := c.constIndex[literal]; ok {
+		return idx, nil
+	}
+	if len(c.program.consts) == math.MaxUint16+1 {
+		return 0, fmt.Errorf("%w: more than %d literals", ErrCompile, math.MaxUint16+1)
+	}
+	c.constIndex[literal] = len(c.program.consts)
+	c.program.consts = append(c.program.consts, newConstant(literal))
+	return c.constIndex[literal], nil
+}
+
+// emit appends an instruction and returns its offset.
+func (c *compiler) emit(op Opcode, operands ...int) int {
+	offset := len(c.program.code)
+	c.program.code = append(c.program.code, byte(op))
+	for _, operand := range operands {
+		c.program.code = binary.LittleEndian.AppendUint16(c.program.code, uint16(operand))
+	}
+	return offset
+}
+
+func newConstant(literal string) constant {
+	num, ok := comp.Number(literal)
+	return constant{str: literal, num: num, isNum: ok}
+}
+
+// operand returns the attribute name or literal held by one side of a comparison. Like the tree-walking evaluator,
+// anything other than an unparsed node resolves to the empty string.
+func operand(ast parse.AST) string {
+	unparsed, ok := ast.(parse.Unparsed)
+	if !ok {
+		return ""
+	}
+	return strings.ReplaceAll(strings.Join(unparsed.Contents, " "), "'", "")
+}
+
+// Run reports whether the record matches the program. Run does not allocate.
+func (p *Program) Run(data map[string]string) bool {
+	var stack [MaxStack]bool
+	var counters [MaxStack]uint16
+	sp, cp := 0, 0
+	code := p.code
+	for pc := 0; pc < len(code); {
+		op := Opcode(code[pc])
+		switch op {
+		case OpTrue, OpFalse:
+			stack[sp] = op == OpTrue
+			sp++
+			pc++
+		case OpNot:
+			stack[sp-1] = !stack[sp-1]
+			pc++
+		case OpJumpIfFalse, OpJumpIfTrue:
+			if stack[sp-1] == (op == OpJumpIfTrue) {
+				pc = int(binary.LittleEndian.Uint16(code[pc+1:]))
+				continue
+			}
+			sp--
+			pc += 3
+		case OpJump:
+			pc = int(binary.LittleEndian.Uint16(code[pc+1:]))
+		case OpPop:
+			sp--
+			pc++
+		case OpHas:
+			_, stack[sp] = data[p.slots[binary.LittleEndian.Uint16(code[pc+1:])]]
+			sp++
+			pc += 3
+		case OpZero:
+			counters[cp] = 0
+			cp++
+			pc++
+		case OpCount:
+			sp--
+			if stack[sp] {
+				counters[cp-1]++
+			}
+			pc++
+		case OpAtLeast, OpExactly:
+			k := binary.LittleEndian.Uint16(code[pc+1:])
+			cp--
+			stack[sp] = counters[cp] >= k && (op == OpAtLeast || counters[cp] == k)
+			sp++
+			pc += 3
+		default:
+			val, ok := data[p.slots[binary.LittleEndian.Uint16(code[pc+1:])]]
+			konst := &p.consts[binary.LittleEndian.Uint16(code[pc+3:])]
+			stack[sp] = ok && compare(op, val, konst)
+			sp++
+			pc += 5
+		}
+	}
+	return stack[0]
+}
+
+func compare(op Opcode, val string, konst *constant) bool {
+	switch op {
+	case OpEqual:
+		return val == konst.str
+	case OpNotEqual:
+		return val != konst.str
+	}
+	num, ok := comp.Number(val)
+	if !ok || !konst.isNum {
+		return false
+	}
+	switch op {
+	case OpGreater:
+		return num > konst.num
+	case OpGreaterOrEqual:
+		return num >= konst.num
+	case OpLess:
+		return num < konst.num
+	case OpLessOrEqual:
+		return num <= konst.num
+	}
+	return false
+}
+
+// depth is the depth of the value and counter stacks at an offset of the code.
+type depth struct {
+	values   int
+	counters int
+}
+
+// verify checks that the code only holds known instructions with operands in range, that every jump lands forward on
+// an instruction boundary with consistent stack depths, that code following an unconditional jump is the target of
+// another jump, and that the program closes every counter and leaves exactly one value, using stacks no deeper than
+// MaxStack. Run relies on these properties instead of checking them on every step.
+func (p *Program) verify() error {
+	code := p.code
+	if len(code) == 0 {
+		return fmt.Errorf("empty program")
+	}
+	// targets records the stack depths expected at each offset a jump lands on
+	targets := map[int]depth{}
+	var cur depth
+	reachable := true // reachable is false after an unconditional jump, until the target of another jump
+	for pc := 0; pc < len(code); {
+		if d, ok := targets[pc]; ok {
+			if reachable && d != cur {
+				return fmt.Errorf("inconsistent stack depth at offset %d", pc)
+			}
+			cur, reachable = d, true
+			delete(targets, pc)
+		}
+		if !reachable {
+			return fmt.Errorf("unreachable code at offset %d", pc)
+		}
+		op := Opcode(code[pc])
+		if _, ok := mnemonics[op]; !ok {
+			return fmt.Errorf("unknown opcode %d at offset %d", op, pc)
+		}
+		next := pc + 1 + 2*op.operands()
+		if next > len(code) {
+			return fmt.Errorf("truncated instruction at offset %d", pc)
+		}
+		switch op {
+		case OpTrue, OpFalse:
+			cur.values++
+		case OpNot:
+			if cur.values < 1 {
+				return fmt.Errorf("stack underflow at offset %d", pc)
+			}
+		case OpJumpIfFalse, OpJumpIfTrue:
+			if cur.values < 1 {
+				return fmt.Errorf("stack underflow at offset %d", pc)
+			}
+			target := int(binary.LittleEndian.Uint16(code[pc+1:]))
+			if target <= pc {
+				return fmt.Errorf("backward jump at offset %d", pc)
+			}
+			if d, ok := targets[target]; ok && d != cur {
+				return fmt.Errorf("inconsistent stack depth at offset %d", target)
+			}
+			targets[target] = cur
+			cur.values--
+		case OpJump:
+			target := int(binary.LittleEndian.Uint16(code[pc+1:]))
+			if target <= pc {
+				return fmt.Errorf("backward jump at offset %d", pc)
+			}
+			if d, ok := targets[target]; ok && d != cur {
+				return fmt.Errorf("inconsistent stack depth at offset %d", target)
+			}
+			targets[target] = cur
+			reachable = false
+		case OpPop:
+			if cur.values < 1 {
+				return fmt.Errorf("stack underflow at offset %d", pc)
+			}
+			cur.values--
+		case OpHas:
+			if int(binary.LittleEndian.Uint16(code[pc+1:])) >= len(p.slots) {
+				return fmt.Errorf("slot out of range at offset %d", pc)
+			}
+			cur.values++
+		case OpZero:
+			cur.counters++
+		case OpCount:
+			if cur.values < 1 || cur.counters < 1 {
+				return fmt.Errorf("stack underflow at offset %d", pc)
+			}
+			cur.values--
+		case OpAtLeast, OpExactly:
+			if cur.counters < 1 {
+				return fmt.Errorf("counter stack underflow at offset %d", pc)
+			}
+			cur.counters--
+			cur.values++
+		default:
+			if int(binary.LittleEndian.Uint16(code[pc+1:])) >= len(p.slots) {
+				return fmt.Errorf("slot out of range at offset %d", pc)
+			}
+			if int(binary.LittleEndian.Uint16(code[pc+3:])) >= len(p.consts) {
+				return fmt.Errorf("constant out of range at offset %d", pc)
+			}
+			cur.values++
+		}
+		if cur.values > MaxStack || cur.counters > MaxStack {
+			return fmt.Errorf("stack deeper than %d at offset %d", MaxStack, pc)
+		}
+		pc = next
+	}
+	for target, d := range targets {
+		if target != len(code) {
+			return fmt.Errorf("jump to offset %d is not an instruction boundary", target)
+		}
+		if reachable && d != cur {
+			return fmt.Errorf("inconsistent stack depth at end of program")
+		}
+		cur, reachable = d, true
+	}
+	if !reachable {
+		return fmt.Errorf("program ends with a jump past its end")
+	}
+	if cur.counters != 0 {
+		return fmt.Errorf("program leaves %d counters open", cur.counters)
+	}
+	if cur.values != 1 {
+		return fmt.Errorf("program leaves %d values on the stack", cur.values)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/ast/parse/grammar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisassemble(t *testing.T) {
+
+	program, err := Compile(grammar.MustParse("age > 30 AND (department == 'Marketing' OR NOT (age >= 60)) AND salary > 'x'"))
+	assert.Nil(t, err)
+
+	expected := `slot  0  "age"
+slot  1  "department"
+const 0  "30"
+const 1  "Marketing"
+const 2  "60"
+0000  GT    slot 0 (age), const 0 ("30")
+0005  JMPF  0026
+0008  EQ    slot 1 (department), const 1 ("Marketing")
+0013  JMPT  0022
+0016  GE    slot 0 (age), const 2 ("60")
+0021  NOT
+0022  JMPF  0026
+0025  FALSE
+`
+	// the OR chain jumps to its end on true, where the AND chain continues with its own jump
+	assert.Equal(t, expected, program.Disassemble())
+}
+
+func TestRun(t *testing.T) {
+
+	program, err := Compile(grammar.MustParse("age > 30 AND (department == 'Marketing' OR NOT (age >= 60))"))
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		desc          string
+		data          map[string]string
+		expectedMatch bool
+	}{
+		{desc: "first branch", data: map[string]string{"age": "61", "department": "Marketing"}, expectedMatch: true},
+		{desc: "negated branch", data: map[string]string{"age": "31"}, expectedMatch: true},
+		{desc: "no branch", data: map[string]string{"age": "61", "department": "Sales"}, expectedMatch: false},
+		{desc: "short circuit", data: map[string]string{"age": "30", "department": "Marketing"}, expectedMatch: false},
+		{desc: "missing attribute", data: map[string]string{}, expectedMatch: false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.expectedMatch, program.Run(tt.data))
+		})
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+
+	program, err := Compile(grammar.MustParse("age > 30 AND department != 'Human Resources'"))
+	assert.Nil(t, err)
+
+	data, err := program.MarshalBinary()
+	assert.Nil(t, err)
+
+	loaded, err := Load(data)
+	assert.Nil(t, err)
+	assert.Equal(t, program, loaded)
+
+	testCases := []struct {
+		desc string
+		data []byte
+	}{
+		{desc: "empty", data: nil},
+		{desc: "wrong header", data: []byte("RVX\x02")},
+		{desc: "older version", data: []byte("RVM\x01\x00\x00\x01\x01")},
+		{desc: "truncated", data: data[:len(data)-1]},
+		{desc: "trailing bytes", data: append(append([]byte{}, data...), 0)},
+		{desc: "slot out of range", data: []byte("RVM\x02\x00\x01\x0230\x05\x05\x00\x00\x00\x00")},
+		{desc: "backward jump", data: []byte("RVM\x02\x00\x00\x04\x01\x0a\x00\x00")},
+		{desc: "jump into an instruction", data: []byte("RVM\x02\x01\x01a\x01\x01x\x09\x01\x0a\x06\x00\x03\x00\x00\x00\x00")},
+		{desc: "two results", data: []byte("RVM\x02\x00\x00\x02\x01\x01")},
+		{desc: "counter left open", data: []byte("RVM\x02\x00\x00\x02\x11\x01")},
+		{desc: "count without counter", data: []byte("RVM\x02\x00\x00\x03\x01\x12\x01")},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := Load(tt.data)
+			assert.True(t, errors.Is(err, ErrBytecode), "unexpected error: %v", err)
+		})
+	}
+
+	_, err = Load([]byte("RVM\x01\x00\x00\x01\x01"))
+	assert.True(t, errors.Is(err, ErrVersion), "unexpected error: %v", err)
+}
+
+func TestRunManyChildren(t *testing.T) {
+
+	// counting rules keep a counter instead of stacking the result of every child, so they are not limited to MaxStack
+	// children
+	children := make([]string, 2*MaxStack)
+	data := map[string]string{}
+	for idx := range children {
+		children[idx] = fmt.Sprintf("a%d == 'x'", idx)
+		if idx < MaxStack {
+			data[fmt.Sprintf("a%d", idx)] = "x"
+		}
+	}
+	list := strings.Join(children, ", ")
+
+	testCases := []struct {
+		desc          string
+		rule          string
+		expectedMatch bool
+	}{
+		{desc: "at least reached", rule: fmt.Sprintf("ATLEAST(%d, %s)", MaxStack, list), expectedMatch: true},
+		{desc: "at least missed", rule: fmt.Sprintf("ATLEAST(%d, %s)", MaxStack+1, list), expectedMatch: false},
+		{desc: "exactly", rule: fmt.Sprintf("EXACTLY(%d, %s)", MaxStack, list), expectedMatch: true},
+		{desc: "exactly missed", rule: fmt.Sprintf("EXACTLY(%d, %s)", MaxStack-1, list), expectedMatch: false},
+		{desc: "nested", rule: fmt.Sprintf("ATLEAST(1, EXACTLY(%d, %s), a0 == 'y')", MaxStack, list), expectedMatch: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			program, err := Compile(grammar.MustParse(tt.rule))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedMatch, program.Run(data))
+
+			data, err := program.MarshalBinary()
+			assert.Nil(t, err)
+			_, err = Load(data)
+			assert.Nil(t, err)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShowCacheStats reports the size and hit, miss and eviction counts of the rule engine's caches.
+func ShowCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, ruleEngine.cacheStats())
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"RuleEngineAST/ast/schema"
+	"RuleEngineAST/models"
+	"RuleEngineAST/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var attributeManager service.AttributeInterface = &service.AttributeManagerV1{}
+
+type attributeRequest struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Values      []string `json:"values"`
+	Default     *string  `json:"default"`
+	Description string   `json:"description"`
+}
+
+func toSchemaAttribute(attribute models.Attribute) schema.Attribute {
+	return schema.Attribute{
+		Name:    attribute.Name,
+		Type:    schema.Type(attribute.Type),
+		Values:  attribute.Values,
+		Default: attribute.Default,
+	}
+}
+
+// loadCatalog returns the catalog of all stored attributes.
+func loadCatalog() schema.Catalog {
+	catalog := schema.Catalog{}
+	for _, attribute := range attributeManager.FindAttributes() {
+		catalog[attribute.Name] = toSchemaAttribute(attribute)
+	}
+	return catalog
+}
+
+// findAttribute loads the stored attribute named by the id path parameter. If the id is not valid or the attribute
+// cannot be loaded the error response is written and false is returned.
+func findAttribute(c *gin.Context) (models.Attribute, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid attribute id '%s'", c.Param("id")))
+		return models.Attribute{}, false
+	}
+	attribute, err := attributeManager.FindAttributeById(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, "attribute not found")
+		return attribute, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return attribute, false
+	}
+	return attribute, true
+}
+
+// bindAttribute reads and validates an attribute definition from the request body into attribute. If the definition
+// is not valid, or its name is taken by another attribute, the error response is written and false is returned.
+func bindAttribute(c *gin.Context, attribute *models.Attribute) bool {
+	req := &attributeRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return false
+	}
+	attribute.Name = req.Name
+	attribute.Type = req.Type
+	attribute.Values = req.Values
+	attribute.Default = req.Default
+	attribute.Description = req.Description
+
+	if err := toSchemaAttribute(*attribute).Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	existing, err := attributeManager.FindAttributeByName(attribute.Name)
+	if err == nil && existing.Id != attribute.Id {
+		c.JSON(http.StatusConflict, fmt.Sprintf("attribute '%s' already exists", attribute.Name))
+		return false
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return true
+}
+
+func FindAttributes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"attributes": attributeManager.FindAttributes()})
+}
+
+func FindAttribute(c *gin.Context) {
+	attribute, ok := findAttribute(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, attribute)
+}
+
+func CreateAttribute(c *gin.Context) {
+
+	attribute := models.Attribute{}
+	if !bindAttribute(c, &attribute) {
+		return
+	}
+
+	attribute, err := attributeManager.CreateAttribute(attribute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, attribute)
+}
+
+func UpdateAttribute(c *gin.Context) {
+
+	attribute, ok := findAttribute(c)
+	if !ok {
+		return
+	}
+	if !bindAttribute(c, &attribute) {
+		return
+	}
+
+	attribute, err := attributeManager.UpdateAttribute(attribute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, attribute)
+}
+
+func DeleteAttribute(c *gin.Context) {
+
+	attribute, ok := findAttribute(c)
+	if !ok {
+		return
+	}
+
+	if err := attributeManager.DeleteAttribute(attribute.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, attribute)
+}
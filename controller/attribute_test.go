@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+
+	router := gin.New()
+	router.GET("/attributes", FindAttributes)
+	router.POST("/attributes", CreateAttribute)
+	router.GET("/attributes/:id", FindAttribute)
+	router.PUT("/attributes/:id", UpdateAttribute)
+	router.DELETE("/attributes/:id", DeleteAttribute)
+
+	// the steps run in order, each one seeing the attributes left by the previous ones
+	testCases := []struct {
+		desc             string
+		method           string
+		path             string
+		body             string
+		expectedCode     int
+		expectedContains string
+	}{
+		{
+			desc:             "create",
+			method:           http.MethodPost,
+			path:             "/attributes",
+			body:             `{"name": "age", "type": "int", "description": "age in years"}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"id":1,"name":"age","type":"int"`,
+		},
+		{
+			desc:             "create enum",
+			method:           http.MethodPost,
+			path:             "/attributes",
+			body:             `{"name": "department", "type": "enum", "values": ["Sales", "Marketing"], "default": "Sales"}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"id":2,"name":"department","type":"enum","values":["Sales","Marketing"],"default":"Sales"`,
+		},
+		{
+			desc:         "create with a taken name",
+			method:       http.MethodPost,
+			path:         "/attributes",
+			body:         `{"name": "age", "type": "decimal"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			desc:         "create with an unknown type",
+			method:       http.MethodPost,
+			path:         "/attributes",
+			body:         `{"name": "salary", "type": "money"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "create enum without values",
+			method:       http.MethodPost,
+			path:         "/attributes",
+			body:         `{"name": "grade", "type": "enum"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "create with an invalid default",
+			method:       http.MethodPost,
+			path:         "/attributes",
+			body:         `{"name": "salary", "type": "decimal", "default": "lots"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:             "get",
+			method:           http.MethodGet,
+			path:             "/attributes/2",
+			expectedCode:     http.StatusOK,
+			expectedContains: `"name":"department"`,
+		},
+		{
+			desc:             "list",
+			method:           http.MethodGet,
+			path:             "/attributes",
+			expectedCode:     http.StatusOK,
+			expectedContains: `"name":"department"`,
+		},
+		{
+			desc:             "get with an invalid id",
+			method:           http.MethodGet,
+			path:             "/attributes/abc",
+			expectedCode:     http.StatusBadRequest,
+			expectedContains: `"invalid attribute id 'abc'"`,
+		},
+		{
+			desc:         "get with id zero",
+			method:       http.MethodGet,
+			path:         "/attributes/0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:             "get unknown",
+			method:           http.MethodGet,
+			path:             "/attributes/99",
+			expectedCode:     http.StatusNotFound,
+			expectedContains: `"attribute not found"`,
+		},
+		{
+			desc:             "update",
+			method:           http.MethodPut,
+			path:             "/attributes/1",
+			body:             `{"name": "age", "type": "decimal", "description": "age in years"}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"id":1,"name":"age","type":"decimal"`,
+		},
+		{
+			desc:         "update to a taken name",
+			method:       http.MethodPut,
+			path:         "/attributes/1",
+			body:         `{"name": "department", "type": "string"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			desc:         "update with an unknown type",
+			method:       http.MethodPut,
+			path:         "/attributes/1",
+			body:         `{"name": "age", "type": "number"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:             "update with an invalid id",
+			method:           http.MethodPut,
+			path:             "/attributes/abc",
+			body:             `{"name": "age", "type": "int"}`,
+			expectedCode:     http.StatusBadRequest,
+			expectedContains: `"invalid attribute id 'abc'"`,
+		},
+		{
+			desc:         "update unknown",
+			method:       http.MethodPut,
+			path:         "/attributes/99",
+			body:         `{"name": "age", "type": "int"}`,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:             "get updated",
+			method:           http.MethodGet,
+			path:             "/attributes/1",
+			expectedCode:     http.StatusOK,
+			expectedContains: `"type":"decimal"`,
+		},
+		{
+			desc:             "delete",
+			method:           http.MethodDelete,
+			path:             "/attributes/1",
+			expectedCode:     http.StatusOK,
+			expectedContains: `"name":"age"`,
+		},
+		{
+			desc:         "delete again",
+			method:       http.MethodDelete,
+			path:         "/attributes/1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:             "delete with an invalid id",
+			method:           http.MethodDelete,
+			path:             "/attributes/abc",
+			expectedCode:     http.StatusBadRequest,
+			expectedContains: `"invalid attribute id 'abc'"`,
+		},
+		{
+			desc:         "get deleted",
+			method:       http.MethodGet,
+			path:         "/attributes/1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:             "create with the name of a deleted attribute",
+			method:           http.MethodPost,
+			path:             "/attributes",
+			body:             `{"name": "age", "type": "int"}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"name":"age","type":"int"`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.expectedContains)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/equiv"
+	"github.com/gin-gonic/gin"
+)
+
+func CompareRules(c *gin.Context) {
+
+	type request struct {
+		FirstRule    string `json:"first_rule"`
+		SecondRule   string `json:"second_rule"`
+		FirstRuleId  uint   `json:"first_rule_id"`
+		SecondRuleId uint   `json:"second_rule_id"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	first, ok := resolveRule(c, req.FirstRuleId, req.FirstRule)
+	if !ok {
+		return
+	}
+	second, ok := resolveRule(c, req.SecondRuleId, req.SecondRule)
+	if !ok {
+		return
+	}
+
+	result, err := equiv.Compare(first.ast, second.ast)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot compare rules. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/coverage"
+	"github.com/gin-gonic/gin"
+)
+
+func RuleCoverage(c *gin.Context) {
+
+	type request struct {
+		Rule    string              `json:"rule"`
+		RuleId  uint                `json:"rule_id"`
+		Records []map[string]string `json:"records"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Records) == 0 {
+		c.JSON(http.StatusBadRequest, "records must not be empty")
+		return
+	}
+
+	rule, ok := resolveEnabledRule(c, req.RuleId, req.Rule)
+	if !ok {
+		return
+	}
+
+	report, err := coverage.Measure(rule.ast, req.Records)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot measure coverage. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
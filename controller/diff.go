@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/diff"
+	"github.com/gin-gonic/gin"
+)
+
+func DiffRules(c *gin.Context) {
+
+	type request struct {
+		FirstRule    string `json:"first_rule"`
+		SecondRule   string `json:"second_rule"`
+		FirstRuleId  uint   `json:"first_rule_id"`
+		SecondRuleId uint   `json:"second_rule_id"`
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid format '%s', expected json or text", format))
+		return
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	first, ok := resolveRule(c, req.FirstRuleId, req.FirstRule)
+	if !ok {
+		return
+	}
+	second, ok := resolveRule(c, req.SecondRuleId, req.SecondRule)
+	if !ok {
+		return
+	}
+
+	changes, err := diff.Diff(first.ast, second.ast)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot diff rules. err : %s", err.Error()))
+		return
+	}
+
+	if format == "text" {
+		c.String(http.StatusOK, diff.Unified(changes))
+		return
+	}
+	if changes == nil {
+		changes = []diff.Change{}
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/codegen"
+	"RuleEngineAST/ast/elastic"
+	"RuleEngineAST/ast/jsonlogic"
+	"RuleEngineAST/ast/mongo"
+	"RuleEngineAST/ast/parse"
+	"github.com/gin-gonic/gin"
+)
+
+// exporters translates a parsed rule for each of the formats supported by ExportRule.
+var exporters = map[string]func(parse.AST) (any, error){
+	"mongo": func(ast parse.AST) (any, error) {
+		return mongo.Filter(ast)
+	},
+	"elasticsearch": func(ast parse.AST) (any, error) {
+		return elastic.Search(ast)
+	},
+	"jsonlogic": jsonlogic.Encode,
+	"javascript": func(ast parse.AST) (any, error) {
+		return codegen.JavaScript(ast, "ruleMatch")
+	},
+	"python": func(ast parse.AST) (any, error) {
+		return codegen.Python(ast, "rule_match")
+	},
+}
+
+func ExportRule(c *gin.Context) {
+
+	format := c.Query("format")
+	exporter, ok := exporters[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid format '%s'", format))
+		return
+	}
+
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+
+	doc, err := exporter(rule.ast)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot export rule. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":     rule.rule.Id,
+		"format": format,
+		"export": doc,
+	})
+}
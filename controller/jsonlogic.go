@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/jsonlogic"
+	"github.com/gin-gonic/gin"
+)
+
+func ImportJSONLogic(c *gin.Context) {
+
+	type request struct {
+		JSONLogic json.RawMessage `json:"jsonlogic"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	ast, err := jsonlogic.Parse(req.JSONLogic)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("cannot import jsonlogic. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"rule": fmt.Sprint(ast),
+	})
+}
+
+func ExportJSONLogic(c *gin.Context) {
+
+	type request struct {
+		Rule string `json:"rule"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	ast, err := ruleEngine.parseTree(req.Rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		return
+	}
+
+	doc, err := jsonlogic.Encode(ast)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("cannot export jsonlogic. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jsonlogic": doc,
+	})
+}
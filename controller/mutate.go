@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/mutate"
+	"github.com/gin-gonic/gin"
+)
+
+func MutateRule(c *gin.Context) {
+
+	type request struct {
+		Rule   string        `json:"rule"`
+		RuleId uint          `json:"rule_id"`
+		Tests  []mutate.Case `json:"tests"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	rule, ok := resolveEnabledRule(c, req.RuleId, req.Rule)
+	if !ok {
+		return
+	}
+
+	// a stored rule is judged by its stored tests unless others are given
+	if len(req.Tests) == 0 && req.RuleId != 0 {
+		for _, test := range ruleTestManager.FindRuleTests(req.RuleId) {
+			req.Tests = append(req.Tests, mutate.Case{Data: test.Data, Expected: test.Expected})
+		}
+	}
+	if len(req.Tests) == 0 {
+		c.JSON(http.StatusBadRequest, "tests must not be empty")
+		return
+	}
+
+	report, err := mutate.Run(rule.ast, req.Tests)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot mutate rule. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/normal"
+	"RuleEngineAST/ast/tree"
+	"github.com/gin-gonic/gin"
+)
+
+func NormalizeRule(c *gin.Context) {
+
+	type request struct {
+		Rule string `json:"rule"`
+	}
+
+	form := normal.Form(c.DefaultQuery("form", string(normal.DNF)))
+	if form != normal.CNF && form != normal.DNF {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid form '%s', expected cnf or dnf", form))
+		return
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	rule, err := ruleEngine.prepare(req.Rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		return
+	}
+
+	normalized, err := normal.Convert(rule.ast, form)
+	if errors.Is(err, normal.ErrTooLarge) {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("rule is too large to normalize. err : %s", err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot normalize rule. err : %s", err.Error()))
+		return
+	}
+
+	node, err := tree.Encode(normalized)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot normalize rule. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule":       req.Rule,
+		"form":       form,
+		"normalized": fmt.Sprint(normalized),
+		"ast":        node,
+	})
+}
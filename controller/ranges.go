@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/ranges"
+	"github.com/gin-gonic/gin"
+)
+
+func RuleRanges(c *gin.Context) {
+
+	type request struct {
+		Rule   string `json:"rule"`
+		RuleId uint   `json:"rule_id"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	rule, ok := resolveRule(c, req.RuleId, req.Rule)
+	if !ok {
+		return
+	}
+
+	branches, err := ranges.Analyze(rule.ast)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot analyze rule. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"branches": branches})
+}
@@ -1,26 +1,282 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"strconv"
 
+	"RuleEngineAST/ast/analyze"
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/merge"
+	"RuleEngineAST/ast/parse"
+	bools "RuleEngineAST/ast/parse/bool"
+	"RuleEngineAST/ast/schema"
+	"RuleEngineAST/ast/simplify"
+	"RuleEngineAST/ast/tree"
+	"RuleEngineAST/dao"
+	"RuleEngineAST/models"
 	"RuleEngineAST/service"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 var ruleManager service.RuleInterface = &service.RuleManagerV1{}
 
-var ruleEngine = &RuleEngine{}
+var ruleEngine = NewRuleEngine(WithCacheSize(cacheSize()))
 
+// cacheSize returns the rule cache size set by the RULE_CACHE_SIZE environment variable, or DefaultCacheSize.
+func cacheSize() int {
+	size, err := strconv.Atoi(os.Getenv("RULE_CACHE_SIZE"))
+	if err != nil {
+		return DefaultCacheSize
+	}
+	return size
+}
+
+// DefaultPageSize and MaxPageSize bound the number of rules FindRules returns per page.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// FindRules lists stored rules a page at a time. The query parameters select the page size (limit), the order (sort
+// by id or rule, order asc or desc), a text to search for in rules (q), filters on the metadata (name, owner, tag,
+// priority and enabled) and the page to return (cursor, as given by next_cursor in the previous page).
 func FindRules(c *gin.Context) {
-	var rules = ruleManager.FindRules()
-	c.JSON(http.StatusOK, gin.H{"rules": rules})
+
+	query := dao.RuleQuery{
+		Search: c.Query("q"),
+		Name:   c.Query("name"),
+		Owner:  c.Query("owner"),
+		Tag:    c.Query("tag"),
+		Sort:   c.DefaultQuery("sort", "id"),
+		Limit:  DefaultPageSize,
+	}
+
+	if priority := c.Query("priority"); priority != "" {
+		n, err := strconv.Atoi(priority)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "priority must be a number")
+			return
+		}
+		query.Priority = &n
+	}
+	if enabled := c.Query("enabled"); enabled != "" {
+		b, err := strconv.ParseBool(enabled)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "enabled must be true or false")
+			return
+		}
+		query.Enabled = &b
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxPageSize {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("limit must be a number between 1 and %d", MaxPageSize))
+			return
+		}
+		query.Limit = n
+	}
+	if query.Sort != "id" && query.Sort != "rule" {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid sort '%s'", query.Sort))
+		return
+	}
+	switch order := c.DefaultQuery("order", "asc"); order {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid order '%s'", order))
+		return
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil || (query.Sort == "rule") != (after.Rule != "") {
+			c.JSON(http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		query.After = after
+	}
+
+	rules, next, err := ruleManager.FindRules(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := gin.H{"rules": rules}
+	if next != nil {
+		response["next_cursor"] = encodeCursor(next)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// encodeCursor and decodeCursor convert page cursors to and from the opaque strings handed out to clients.
+func encodeCursor(cursor *dao.RuleCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*dao.RuleCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &dao.RuleCursor{}
+	if err := json.Unmarshal(raw, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// findRule loads the stored rule named by the id path parameter, using the rule engine's cache. If the id is not valid
+// or the rule cannot be loaded the error response is written and false is returned.
+func findRule(c *gin.Context) (*storedRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule id '%s'", c.Param("id")))
+		return nil, false
+	}
+	return findRuleById(c, uint(id))
+}
+
+func findRuleById(c *gin.Context, id uint) (*storedRule, bool) {
+	rule, err := ruleEngine.findStoredRule(id, ruleManager.FindRuleById)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, fmt.Sprintf("rule %d not found", id))
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return rule, true
+}
+
+// findEnabledRule is findRule for handlers which evaluate the rule on records from the request: a disabled rule is
+// reported as a conflict.
+func findEnabledRule(c *gin.Context) (*storedRule, bool) {
+	rule, ok := findRule(c)
+	return rule, ok && checkEnabled(c, rule)
+}
+
+// findEnabledRuleById is findRuleById for handlers which evaluate the rule on records from the request: a disabled rule
+// is reported as a conflict.
+func findEnabledRuleById(c *gin.Context, id uint) (*storedRule, bool) {
+	rule, ok := findRuleById(c, id)
+	return rule, ok && checkEnabled(c, rule)
+}
+
+func checkEnabled(c *gin.Context, rule *storedRule) bool {
+	if !rule.rule.IsEnabled() {
+		c.JSON(http.StatusConflict, fmt.Sprintf("rule %d is disabled", rule.rule.Id))
+		return false
+	}
+	return true
+}
+
+// resolveRule prepares a rule given either by the id of a stored rule or by its text, the id taking precedence. If
+// the rule cannot be loaded or parsed the error response is written and false is returned.
+func resolveRule(c *gin.Context, id uint, text string) (*preparedRule, bool) {
+	return resolveRuleWith(c, id, text, findRuleById)
+}
+
+// resolveEnabledRule is resolveRule for handlers which evaluate the rule on records from the request: a disabled
+// stored rule is reported as a conflict.
+func resolveEnabledRule(c *gin.Context, id uint, text string) (*preparedRule, bool) {
+	return resolveRuleWith(c, id, text, findEnabledRuleById)
+}
+
+func resolveRuleWith(c *gin.Context, id uint, text string, find func(*gin.Context, uint) (*storedRule, bool)) (*preparedRule, bool) {
+	if id != 0 {
+		rule, ok := find(c, id)
+		if !ok {
+			return nil, false
+		}
+		return rule.preparedRule, true
+	}
+
+	rule, err := ruleEngine.prepare(text)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		return nil, false
+	}
+	return rule, true
+}
+
+// checkedRule is a rule which passed the checks made before storing it.
+type checkedRule struct {
+	ast      parse.AST
+	bytecode []byte
+	warnings []analyze.Finding
+}
+
+// checkRule parses a rule about to be stored, type-checks it against the attribute catalog, looks for clauses which
+// never or always match, rejecting them in strict mode, and compiles it to bytecode. If the rule is rejected the error response is written and false is returned.
+func checkRule(c *gin.Context, text string, strict bool) (*checkedRule, bool) {
+	ast, err := ruleEngine.parseTree(text)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		return nil, false
+	}
+
+	// rules are only type-checked once attributes have been declared, so that the catalog can be adopted gradually
+	if catalog := loadCatalog(); len(catalog) > 0 {
+		problems, err := catalog.Check(ast)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return nil, false
+		}
+		if len(problems) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    "rule does not match the attribute catalog",
+				"problems": problems,
+			})
+			return nil, false
+		}
+	}
+
+	// clauses which never or always match are usually mistakes; they are reported, and rejected in strict mode
+	warnings, err := analyze.Analyze(ast)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if strict && len(warnings) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "rule has clauses which never or always match",
+			"warnings": warnings,
+		})
+		return nil, false
+	}
+
+	// store the compiled form next to the rule so it can be evaluated without parsing again
+	bytecode, err := ruleEngine.compileBytecode(ast)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("cannot compile rule. err : %s", err.Error()))
+		return nil, false
+	}
+
+	return &checkedRule{ast: ast, bytecode: bytecode, warnings: warnings}, true
 }
 
 func CreateRule(c *gin.Context) {
 
 	type request struct {
-		Rule string `json:"rule"`
+		Rule   string `json:"rule"`
+		Strict bool   `json:"strict"`
+		ruleMetadata
+	}
+
+	type response struct {
+		models.Rule
+		Warnings []analyze.Finding `json:"warnings,omitempty"`
 	}
 
 	req := &request{}
@@ -30,23 +286,284 @@ func CreateRule(c *gin.Context) {
 		return
 	}
 
-	_, err = ruleEngine.parseTree(req.Rule)
+	rule := models.Rule{Rule: req.Rule}
+	req.apply(&rule)
+	if !checkRuleName(c, rule) {
+		return
+	}
+
+	checked, ok := checkRule(c, req.Rule, req.Strict)
+	if !ok {
+		return
+	}
+	rule.Bytecode = checked.bytecode
+
+	rule, err = ruleManager.CreateRule(rule)
+	if errors.Is(err, dao.ErrRuleNameTaken) {
+		c.JSON(http.StatusConflict, fmt.Sprintf("rule '%s' already exists", *rule.Name))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	rule := ruleManager.CreateRule(req.Rule)
+	c.JSON(http.StatusOK, response{Rule: rule, Warnings: checked.warnings})
+}
+
+func FindRule(c *gin.Context) {
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rule.rule)
+}
 
-	c.JSON(http.StatusOK, rule)
+// ruleMetadata holds the descriptive fields of a rule given in a request. Fields left nil are not set.
+type ruleMetadata struct {
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+	Owner       *string  `json:"owner"`
+	Priority    *int     `json:"priority"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// apply sets the provided fields on the rule. An empty name unsets the rule's name.
+func (m ruleMetadata) apply(rule *models.Rule) {
+	if m.Name != nil {
+		rule.Name = m.Name
+		if *m.Name == "" {
+			rule.Name = nil
+		}
+	}
+	if m.Description != nil {
+		rule.Description = *m.Description
+	}
+	if m.Tags != nil {
+		rule.Tags = m.Tags
+	}
+	if m.Owner != nil {
+		rule.Owner = *m.Owner
+	}
+	if m.Priority != nil {
+		rule.Priority = *m.Priority
+	}
+	if m.Enabled != nil {
+		rule.Enabled = m.Enabled
+	}
+}
+
+// complete sets the fields left nil to their defaults, for requests replacing every field of a rule.
+func (m *ruleMetadata) complete() {
+	empty, zero, enabled := "", 0, true
+	if m.Name == nil {
+		m.Name = &empty
+	}
+	if m.Description == nil {
+		m.Description = &empty
+	}
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	if m.Owner == nil {
+		m.Owner = &empty
+	}
+	if m.Priority == nil {
+		m.Priority = &zero
+	}
+	if m.Enabled == nil {
+		m.Enabled = &enabled
+	}
+}
+
+// checkRuleName checks that no other rule has the rule's name. If one has, the error response is written and false
+// is returned.
+func checkRuleName(c *gin.Context, rule models.Rule) bool {
+	if rule.Name == nil {
+		return true
+	}
+	existing, err := ruleManager.FindRuleByName(*rule.Name)
+	if err == nil && existing.Id != rule.Id {
+		c.JSON(http.StatusConflict, fmt.Sprintf("rule '%s' already exists", *rule.Name))
+		return false
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return true
+}
+
+// ruleUpdate holds the changes requested to a stored rule. Fields left nil are not changed.
+type ruleUpdate struct {
+	Rule   *string `json:"rule"`
+	Strict bool    `json:"strict"`
+	Force  bool    `json:"force"`
+	ruleMetadata
+}
+
+// UpdateRule replaces the text and the metadata of a stored rule; metadata which is not provided is reset.
+func UpdateRule(c *gin.Context) {
+	update := &ruleUpdate{}
+	if err := c.BindJSON(update); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if update.Rule == nil {
+		c.JSON(http.StatusBadRequest, "rule must be provided")
+		return
+	}
+	update.complete()
+	applyRuleUpdate(c, update)
+}
+
+// PatchRule changes the provided fields of a stored rule.
+func PatchRule(c *gin.Context) {
+	update := &ruleUpdate{}
+	if err := c.BindJSON(update); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	applyRuleUpdate(c, update)
+}
+
+// applyRuleUpdate changes the stored rule named by the id path parameter. A new text goes through the same checks as
+// new rules, and the rule's tests are run against it; the update is rejected if any of them fails unless it is forced.
+func applyRuleUpdate(c *gin.Context, update *ruleUpdate) {
+
+	type response struct {
+		models.Rule
+		Warnings []analyze.Finding `json:"warnings,omitempty"`
+		Tests    *testRun          `json:"tests,omitempty"`
+	}
+
+	stored, ok := findRule(c)
+	if !ok {
+		return
+	}
+	rule := stored.rule
+	update.apply(&rule)
+	if !checkRuleName(c, rule) {
+		return
+	}
+	resp := response{}
+
+	if update.Rule != nil {
+		checked, ok := checkRule(c, *update.Rule, update.Strict)
+		if !ok {
+			return
+		}
+		match, err := compile.Compile(checked.ast)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("cannot compile rule. err : %s", err.Error()))
+			return
+		}
+
+		run := runRuleTests(match, ruleTestManager.FindRuleTests(rule.Id))
+		if !run.Passed && !update.Force {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "rule fails its tests; set force to update it anyway",
+				"tests": run,
+			})
+			return
+		}
+
+		if rule.Rule != *update.Rule {
+			rule.Version++
+		}
+		rule.Rule = *update.Rule
+		rule.Bytecode = checked.bytecode
+		resp.Warnings, resp.Tests = checked.warnings, run
+	}
+
+	rule, err := ruleManager.UpdateRule(rule, stored.rule.Version)
+	ruleEngine.forgetStoredRule(rule.Id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, fmt.Sprintf("rule %d not found", rule.Id))
+		return
+	case errors.Is(err, dao.ErrRuleNameTaken):
+		c.JSON(http.StatusConflict, fmt.Sprintf("rule '%s' already exists", *rule.Name))
+		return
+	case errors.Is(err, dao.ErrRuleChanged):
+		c.JSON(http.StatusConflict, fmt.Sprintf("rule %d was changed by another update", rule.Id))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp.Rule = rule
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteRule deletes a stored rule together with its tests.
+func DeleteRule(c *gin.Context) {
+
+	stored, ok := findRule(c)
+	if !ok {
+		return
+	}
+
+	err := ruleManager.DeleteRule(stored.rule.Id)
+	ruleEngine.forgetStoredRule(stored.rule.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, stored.rule)
+}
+
+// mergeStrategies maps the strategies accepted by MergeRules to the function combining the rules. k is only used by
+// ATLEAST and EXACTLY.
+var mergeStrategies = map[string]func(k int, rules []parse.AST) (parse.AST, error){
+	"AND": func(_ int, rules []parse.AST) (parse.AST, error) {
+		return merge.Merge(bools.OpAnd, rules...)
+	},
+	"OR": func(_ int, rules []parse.AST) (parse.AST, error) {
+		return merge.Merge(bools.OpOr, rules...)
+	},
+	"ATLEAST": func(k int, rules []parse.AST) (parse.AST, error) {
+		if err := checkCount(k, rules); err != nil {
+			return nil, err
+		}
+		return simplify.Simplify(&bools.AtLeastExpr{K: k, Children: rules})
+	},
+	"EXACTLY": func(k int, rules []parse.AST) (parse.AST, error) {
+		if err := checkCount(k, rules); err != nil {
+			return nil, err
+		}
+		return simplify.Simplify(&bools.ExactlyExpr{K: k, Children: rules})
+	},
+	"MAJORITY": func(_ int, rules []parse.AST) (parse.AST, error) {
+		return simplify.Simplify(bools.MajorityOf(rules...))
+	},
+	"XOR": func(_ int, rules []parse.AST) (parse.AST, error) {
+		return simplify.Simplify(&bools.ExactlyExpr{K: 1, Children: rules})
+	},
+	"PRIORITY": func(_ int, rules []parse.AST) (parse.AST, error) {
+		return &bools.PriorityExpr{Children: rules}, nil
+	},
+}
+
+func checkCount(k int, rules []parse.AST) error {
+	if !bools.ValidCount(k, len(rules)) {
+		return fmt.Errorf("%w: k must be between 1 and the number of rules, %d", parse.ErrConfig, len(rules))
+	}
+	return nil
 }
 
 func MergeRules(c *gin.Context) {
 
 	type request struct {
-		FirstRule  string `json:"first_rule"`
-		SecondRule string `json:"second_rule"`
-		Strategy   string `json:"merge_strategy"`
+		FirstRule  string   `json:"first_rule"`
+		SecondRule string   `json:"second_rule"`
+		Rules      []string `json:"rules"`
+		RuleIds    []uint   `json:"rule_ids"`
+		Strategy   string   `json:"merge_strategy"`
+		K          int      `json:"k"`
 	}
 
 	req := &request{}
@@ -56,29 +573,86 @@ func MergeRules(c *gin.Context) {
 		return
 	}
 
-	if req.Strategy != "AND" && req.Strategy != "OR" {
+	combine, ok := mergeStrategies[req.Strategy]
+	if !ok {
 		c.JSON(http.StatusBadRequest, "invalid strategy")
 		return
 	}
 
-	// combine rule has onl 2 strategy for now  i.e AND & OR
-	mergedRule := ruleEngine.combineRule(req.FirstRule, req.SecondRule, req.Strategy)
-	_, err = ruleEngine.parseTree(mergedRule)
+	var texts []string
+	for _, text := range append([]string{req.FirstRule, req.SecondRule}, req.Rules...) {
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts)+len(req.RuleIds) == 0 {
+		c.JSON(http.StatusBadRequest, "no rules to merge")
+		return
+	}
+	// the texts come before the stored rules, so their relative order is lost when both are given
+	if req.Strategy == "PRIORITY" && len(texts) > 0 && len(req.RuleIds) > 0 {
+		c.JSON(http.StatusBadRequest, "PRIORITY depends on the order of the rules; merge either rule texts or rule_ids")
+		return
+	}
+
+	// every rule is parsed on its own, so an invalid one is reported as it was given
+	var rules []parse.AST
+	for _, text := range texts {
+		rule, err := ruleEngine.prepare(text)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule '%s'. err : %s", text, err.Error()))
+			return
+		}
+		rules = append(rules, rule.ast)
+	}
+	versions := map[uint]uint{}
+	for _, id := range req.RuleIds {
+		rule, ok := findRuleById(c, id)
+		if !ok {
+			return
+		}
+		rules = append(rules, rule.ast)
+		versions[id] = rule.rule.Version
+	}
+
+	merged, err := combine(req.K, rules)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot merge rules. err : %s", err.Error()))
+		return
+	}
+
+	node, err := tree.Encode(merged)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, fmt.Sprintf("cannot merge rules. err : %s", err.Error()))
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot merge rules. err : %s", err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]string{
-		"merged_rule": mergedRule,
-	})
+	response := gin.H{
+		"merged_rule": fmt.Sprint(merged),
+		"ast":         node,
+	}
+	if len(versions) > 0 {
+		response["versions"] = versions
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func DisassembleRule(c *gin.Context) {
+
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+
+	c.String(http.StatusOK, rule.program.Disassemble())
 }
 
 func EvaluateRule(c *gin.Context) {
 
 	type payloadStruct struct {
-		Rule string            `json:"rule"`
-		Data map[string]string `json:"data"`
+		Rule   string          `json:"rule"`
+		Data   json.RawMessage `json:"data"`
+		Strict bool            `json:"strict"`
 	}
 
 	payload := &payloadStruct{}
@@ -89,15 +663,135 @@ func EvaluateRule(c *gin.Context) {
 		return
 	}
 
-	ast, err := ruleEngine.parseTree(payload.Rule)
+	data, catalog, ok := bindData(c, payload.Data, payload.Strict)
+	if !ok {
+		return
+	}
+
+	rule, err := ruleEngine.prepare(payload.Rule)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	evalNode := ruleEngine.evaluateRule(ast, payload.Data)
+	if payload.Strict {
+		match, ok := strictMatch(c, catalog, rule)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"rule_match": match(data),
+			"data":       data,
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, map[string]bool{
-		"rule_match": evalNode.MatchValue,
+		"rule_match": rule.match(data),
 	})
 }
+
+// EvaluateStoredRule evaluates a stored rule with data, using the rule as parsed when it was loaded. The response names
+// the version of the rule which was evaluated.
+func EvaluateStoredRule(c *gin.Context) {
+
+	type payloadStruct struct {
+		Data   json.RawMessage `json:"data"`
+		Strict bool            `json:"strict"`
+	}
+
+	rule, ok := findEnabledRule(c)
+	if !ok {
+		return
+	}
+
+	payload := &payloadStruct{}
+	err := c.BindJSON(payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid request. err : %s", err.Error()))
+		return
+	}
+
+	data, catalog, ok := bindData(c, payload.Data, payload.Strict)
+	if !ok {
+		return
+	}
+
+	match := rule.match
+	if payload.Strict {
+		if match, ok = strictMatch(c, catalog, rule.preparedRule); !ok {
+			return
+		}
+	}
+
+	response := gin.H{
+		"rule_match": match(data),
+		"rule_id":    rule.rule.Id,
+		"version":    rule.rule.Version,
+	}
+	if payload.Strict {
+		response["data"] = data
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// strictMatch returns the match of a rule evaluated in strict mode. The data is coerced to the canonical form of its
+// declared type, so the rule's numeric literals are canonicalized the same way; the rule itself, and how it is
+// evaluated without strict mode, is left as written. If the canonical rule cannot be prepared the error response is
+// written and false is returned.
+func strictMatch(c *gin.Context, catalog schema.Catalog, rule *preparedRule) (compile.Rule, bool) {
+	canonical, changed := catalog.Canonicalize(rule.ast)
+	if !changed {
+		return rule.match, true
+	}
+	prepared, err := ruleEngine.prepare(fmt.Sprint(canonical))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return prepared.match, true
+}
+
+// bindData decodes the data of an evaluate request. By default the data must map attribute names to strings. In
+// strict mode values may also be numbers or booleans, and are validated against the attribute catalog, coerced to the
+// declared types and completed with declared defaults. If the data is not valid the error response, listing any
+// problems per attribute, is written and false is returned. In strict mode the catalog the data was validated against
+// is returned with it.
+func bindData(c *gin.Context, raw json.RawMessage, strict bool) (map[string]string, schema.Catalog, bool) {
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+
+	if !strict {
+		var data map[string]string
+		if err := json.Unmarshal(raw, &data); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid data. err : %s", err.Error()))
+			return nil, nil, false
+		}
+		return data, nil, true
+	}
+
+	var data map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid data. err : %s", err.Error()))
+		return nil, nil, false
+	}
+
+	catalog := loadCatalog()
+	if len(catalog) == 0 {
+		c.JSON(http.StatusBadRequest, "strict mode needs declared attributes; see /attributes")
+		return nil, nil, false
+	}
+
+	coerced, problems := catalog.Validate(data)
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "data does not match the attribute catalog",
+			"problems": problems,
+		})
+		return nil, nil, false
+	}
+	return coerced, catalog, true
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errLoadPanicked is returned to the callers waiting for a load which panicked.
+var errLoadPanicked = errors.New("loading the cache entry panicked")
+
+// CacheStats reports the state of a cache and how it has been used since it was created.
+type CacheStats struct {
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// lruCache is a bounded, least recently used cache which is safe for concurrent use. Concurrent misses for the same
+// key are collapsed into a single load, whose result is shared. Failed loads are not cached.
+type lruCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used entry
+	entries  map[K]*list.Element
+	loading  map[K]*load[V]
+	stats    CacheStats
+}
+
+type entry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// load is a load in progress; done is closed once val and err are set.
+type load[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// newLRUCache returns a cache holding at most capacity entries. A cache with a capacity of zero or less stores nothing,
+// but still collapses concurrent loads.
+func newLRUCache[K comparable, V any](capacity int) *lruCache[K, V] {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &lruCache[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[K]*list.Element{},
+		loading:  map[K]*load[V]{},
+		stats:    CacheStats{Capacity: capacity},
+	}
+}
+
+// get returns the cached value for key, calling fn to produce it on a miss.
+func (c *lruCache[K, V]) get(key K, fn func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return elem.Value.(*entry[K, V]).val, nil
+	}
+	c.stats.Misses++
+	if l, ok := c.loading[key]; ok {
+		c.mu.Unlock()
+		<-l.done
+		return l.val, l.err
+	}
+	l := &load[V]{done: make(chan struct{})}
+	c.loading[key] = l
+	c.mu.Unlock()
+
+	// the load is finished even if fn panics, so that callers waiting for it, and later callers, are not blocked
+	defer func() {
+		r := recover()
+		if r != nil {
+			l.err = fmt.Errorf("%w: %v", errLoadPanicked, r)
+		}
+		c.mu.Lock()
+		// a key removed while it was loading may be stale, so its result is returned but not stored
+		if c.loading[key] == l {
+			delete(c.loading, key)
+			if l.err == nil {
+				c.add(key, l.val)
+			}
+		}
+		c.mu.Unlock()
+		close(l.done)
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	l.val, l.err = fn()
+	return l.val, l.err
+}
+
+// add stores a value, evicting the least recently used entries to make room. The caller must hold c.mu.
+func (c *lruCache[K, V]) add(key K, val V) {
+	if c.capacity == 0 {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, val: val})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[K, V]).key)
+		c.stats.Evictions++
+	}
+}
+
+// remove drops key from the cache, including the result of any load in progress.
+func (c *lruCache[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	delete(c.loading, key)
+}
+
+func (c *lruCache[K, V]) snapshot() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"RuleEngineAST/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLRUCache(t *testing.T) {
+
+	cache := newLRUCache[string, int](2)
+	loads := 0
+	get := func(key string, val int) int {
+		got, err := cache.get(key, func() (int, error) {
+			loads++
+			return val, nil
+		})
+		assert.Nil(t, err)
+		return got
+	}
+
+	assert.Equal(t, 1, get("a", 1))
+	assert.Equal(t, 2, get("b", 2))
+	assert.Equal(t, 1, get("a", 10)) // hit; b is now the least recently used
+	assert.Equal(t, 3, get("c", 3))  // evicts b
+	assert.Equal(t, 1, get("a", 10))
+	assert.Equal(t, 20, get("b", 20))
+	assert.Equal(t, 4, loads)
+
+	_, err := cache.get("d", func() (int, error) { return 0, errors.New("failed") })
+	assert.NotNil(t, err)
+	assert.Equal(t, 5, get("d", 5)) // failed loads are not cached
+
+	cache.remove("d")
+	assert.Equal(t, 6, get("d", 6))
+
+	assert.Equal(t, CacheStats{Size: 2, Capacity: 2, Hits: 2, Misses: 7, Evictions: 3}, cache.snapshot())
+}
+
+func TestLRUCacheDisabled(t *testing.T) {
+
+	cache := newLRUCache[string, int](0)
+	for i := 0; i < 3; i++ {
+		val, err := cache.get("a", func() (int, error) { return i, nil })
+		assert.Nil(t, err)
+		assert.Equal(t, i, val)
+	}
+	assert.Equal(t, CacheStats{Misses: 3}, cache.snapshot())
+}
+
+func TestLRUCacheCollapsesLoads(t *testing.T) {
+
+	cache := newLRUCache[string, int](10)
+	release := make(chan struct{})
+	var loads atomic.Int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := cache.get("a", func() (int, error) {
+				loads.Add(1)
+				<-release
+				return 42, nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 42, val)
+		}()
+	}
+
+	// wait for every caller to miss before letting the load finish
+	for cache.snapshot().Misses < 8 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	assert.Equal(t, 1, cache.snapshot().Size)
+}
+
+func TestLRUCacheLoadPanics(t *testing.T) {
+
+	cache := newLRUCache[string, int](10)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan any)
+
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		_, _ = cache.get("a", func() (int, error) {
+			close(started)
+			<-release
+			panic("parser bug")
+		})
+	}()
+
+	// a caller waiting for the load gets an error instead of blocking forever
+	<-started
+	waited := make(chan error)
+	go func() {
+		_, err := cache.get("a", func() (int, error) { return 0, nil })
+		waited <- err
+	}()
+	for cache.snapshot().Misses < 2 {
+		runtime.Gosched()
+	}
+	close(release)
+	assert.Equal(t, "parser bug", <-panicked)
+	assert.True(t, errors.Is(<-waited, errLoadPanicked))
+
+	// the failed load is not cached, and the key can be loaded again
+	val, err := cache.get("a", func() (int, error) { return 42, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, 42, val)
+	assert.Equal(t, 1, cache.snapshot().Size)
+}
+
+func TestRuleEngineCache(t *testing.T) {
+
+	re := NewRuleEngine(WithCacheSize(8))
+
+	first, err := re.prepare("age > 30 AND department == 'Sales'")
+	assert.Nil(t, err)
+	second, err := re.prepare("age > 30 AND department == 'Sales'")
+	assert.Nil(t, err)
+	assert.Same(t, first, second)
+	assert.True(t, first.match(map[string]string{"age": "31", "department": "Sales"}))
+
+	_, err = re.prepare("age > AND")
+	assert.NotNil(t, err)
+
+	stored := map[uint]models.Rule{1: {Id: 1, Rule: "age > 30"}}
+	finds := 0
+	find := func(id uint) (models.Rule, error) {
+		finds++
+		rule, ok := stored[id]
+		if !ok {
+			return rule, gorm.ErrRecordNotFound
+		}
+		return rule, nil
+	}
+
+	rule, err := re.findStoredRule(1, find)
+	assert.Nil(t, err)
+	assert.Equal(t, "age > 30", rule.rule.Rule)
+	_, err = re.findStoredRule(1, find)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, finds)
+
+	stored[1] = models.Rule{Id: 1, Rule: "age > 40"}
+	re.forgetStoredRule(1)
+	rule, err = re.findStoredRule(1, find)
+	assert.Nil(t, err)
+	assert.Equal(t, "age > 40", rule.rule.Rule)
+	assert.False(t, rule.match(map[string]string{"age": "35"}))
+
+	_, err = re.findStoredRule(2, find)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	stats := re.cacheStats()
+	assert.Equal(t, CacheStats{Size: 3, Capacity: 8, Hits: 1, Misses: 4}, stats["rules"])
+	assert.Equal(t, CacheStats{Size: 1, Capacity: 8, Hits: 1, Misses: 3}, stats["stored"])
+}
@@ -1,20 +1,86 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"RuleEngineAST/ast/compile"
 	"RuleEngineAST/ast/parse"
 	bools "RuleEngineAST/ast/parse/bool"
 	"RuleEngineAST/ast/parse/comp"
+	"RuleEngineAST/ast/parse/grammar"
+	"RuleEngineAST/ast/vm"
+	"RuleEngineAST/models"
 )
 
+// RuleEngine parses and evaluates rules. Its parsers are created once and shared by all requests, and parsed rules
+// are kept in bounded caches keyed by rule text and by stored rule ID.
 type RuleEngine struct {
+	bParser *bools.Parser
+	cParser *comp.Parser
+	dialect string
+
+	cacheSize int
+	rules     *lruCache[ruleKey, *preparedRule]
+	stored    *lruCache[uint, *storedRule]
+}
+
+// DefaultCacheSize is the number of rules each of the rule engine's caches holds unless configured otherwise.
+const DefaultCacheSize = 1024
+
+// defaultDialect names the rule syntax accepted by the default parsers.
+const defaultDialect = "default"
+
+type RuleEngineOpt func(*RuleEngine)
+
+// WithCacheSize sets the number of rules held by each of the rule engine's caches. A size of zero disables caching.
+func WithCacheSize(size int) RuleEngineOpt {
+	return func(re *RuleEngine) {
+		re.cacheSize = size
+	}
+}
+
+// NewRuleEngine returns a rule engine using the default rule syntax. It panics if the parsers cannot be configured,
+// which can only happen if the default tokens are broken.
+func NewRuleEngine(opts ...RuleEngineOpt) *RuleEngine {
+	bParser, err := bools.NewParser()
+	if err != nil {
+		panic(err)
+	}
+	cParser, err := comp.NewParser()
+	if err != nil {
+		panic(err)
+	}
+	re := &RuleEngine{bParser: bParser, cParser: cParser, dialect: defaultDialect, cacheSize: DefaultCacheSize}
+	for _, opt := range opts {
+		opt(re)
+	}
+	re.rules = newLRUCache[ruleKey, *preparedRule](re.cacheSize)
+	re.stored = newLRUCache[uint, *storedRule](re.cacheSize)
+	return re
+}
+
+// ruleKey identifies a rule by its text and the syntax it is written in.
+type ruleKey struct {
+	dialect string
+	rule    string
+}
+
+// preparedRule is a parsed rule together with its compiled form. Prepared rules are shared between requests, so
+// their ASTs must never be modified.
+type preparedRule struct {
+	ast   parse.AST
+	match compile.Rule
 }
 
-func NewRuleEngine() *RuleEngine {
-	return &RuleEngine{}
+// storedRule is a rule loaded from the database, prepared for evaluation. Its match runs the bytecode stored with the
+// rule, while its AST serves the endpoints which analyse the rule.
+type storedRule struct {
+	rule    models.Rule
+	program *vm.Program
+	*preparedRule
 }
 
 type EvaluateNode struct {
@@ -23,16 +89,12 @@ type EvaluateNode struct {
 }
 
 func (re *RuleEngine) parseTree(ruleString string) (parse.AST, error) {
-	bParser, _ := bools.NewParser()
-	cParser, _ := comp.NewParser()
-
-	ast, err := bParser.ParseStr(ruleString)
+	ast, err := re.bParser.ParseStr(ruleString)
 	if err != nil {
 		return nil, err
 	}
 
-	// parse comparisons
-	err = ast.Parse(cParser)
+	ast, err = grammar.ParseComparisons(ast, re.cParser)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing comparison: %v\n", err)
 	}
@@ -40,9 +102,76 @@ func (re *RuleEngine) parseTree(ruleString string) (parse.AST, error) {
 	return ast, nil
 }
 
-// TODO: optimise combine rule method using AST if time available
-func (re *RuleEngine) combineRule(r1, r2, strategy string) string {
-	return fmt.Sprintf("(%s) %s (%s)", r1, strategy, r2)
+// prepare parses and compiles a rule, reusing the result for rules seen recently.
+func (re *RuleEngine) prepare(ruleString string) (*preparedRule, error) {
+	return re.rules.get(ruleKey{dialect: re.dialect, rule: ruleString}, func() (*preparedRule, error) {
+		ast, err := re.parseTree(ruleString)
+		if err != nil {
+			return nil, err
+		}
+		match, err := compile.Compile(ast)
+		if err != nil {
+			return nil, err
+		}
+		return &preparedRule{ast: ast, match: match}, nil
+	})
+}
+
+// findStoredRule returns the stored rule with the provided ID, calling find to load it if it is not cached. Errors
+// from find are returned unchanged. Callers which modify or delete a stored rule must call forgetStoredRule.
+func (re *RuleEngine) findStoredRule(id uint, find func(uint) (models.Rule, error)) (*storedRule, error) {
+	return re.stored.get(id, func() (*storedRule, error) {
+		rule, err := find(id)
+		if err != nil {
+			return nil, err
+		}
+		prepared, err := re.prepare(rule.Rule)
+		if err != nil {
+			return nil, err
+		}
+		program, err := re.loadProgram(rule, prepared.ast)
+		if err != nil {
+			return nil, err
+		}
+		return &storedRule{
+			rule:         rule,
+			program:      program,
+			preparedRule: &preparedRule{ast: prepared.ast, match: program.Run},
+		}, nil
+	})
+}
+
+// forgetStoredRule drops a stored rule from the cache, so that it is loaded again on next use.
+func (re *RuleEngine) forgetStoredRule(id uint) {
+	re.stored.remove(id)
+}
+
+// cacheStats reports the state of the rule engine's caches.
+func (re *RuleEngine) cacheStats() map[string]CacheStats {
+	return map[string]CacheStats{
+		"rules":  re.rules.snapshot(),
+		"stored": re.stored.snapshot(),
+	}
+}
+
+func (re *RuleEngine) compileBytecode(ast parse.AST) ([]byte, error) {
+	program, err := vm.Compile(ast)
+	if err != nil {
+		return nil, err
+	}
+	return program.MarshalBinary()
+}
+
+// loadProgram loads the bytecode stored with a rule, falling back to compiling the rule's AST for rules stored without
+// bytecode or with bytecode in an older format.
+func (re *RuleEngine) loadProgram(rule models.Rule, ast parse.AST) (*vm.Program, error) {
+	if len(rule.Bytecode) > 0 {
+		program, err := vm.Load(rule.Bytecode)
+		if !errors.Is(err, vm.ErrVersion) {
+			return program, err
+		}
+	}
+	return vm.Compile(ast)
 }
 
 func (re *RuleEngine) evaluateRule(ast parse.AST, dataMap map[string]string) *EvaluateNode {
@@ -60,6 +189,37 @@ func (re *RuleEngine) evaluateRule(ast parse.AST, dataMap map[string]string) *Ev
 			return &EvaluateNode{MatchValue: false}
 		}
 
+	case *bools.UnaryExpr:
+		eval := re.evaluateRule(ast.Expr, dataMap)
+
+		switch ast.Op {
+		case bools.OpNot:
+			return &EvaluateNode{MatchValue: !eval.MatchValue}
+		default:
+			return &EvaluateNode{MatchValue: false}
+		}
+
+	case *bools.AtLeastExpr:
+		return &EvaluateNode{MatchValue: re.countMatches(ast.Children, dataMap) >= ast.K}
+
+	case *bools.ExactlyExpr:
+		return &EvaluateNode{MatchValue: re.countMatches(ast.Children, dataMap) == ast.K}
+
+	case *bools.PriorityExpr:
+		// the first rule whose attributes are all present in the data decides
+		for _, child := range ast.Children {
+			opinion := true
+			for _, attr := range comp.Attributes(child) {
+				if _, ok := dataMap[attr]; !ok {
+					opinion = false
+				}
+			}
+			if opinion {
+				return re.evaluateRule(child, dataMap)
+			}
+		}
+		return &EvaluateNode{MatchValue: false}
+
 	case *comp.EqualExpr:
 		leftEval := re.evaluateRule(ast.LHS, dataMap)
 		rightEval := re.evaluateRule(ast.RHS, dataMap)
@@ -119,3 +279,13 @@ func (re *RuleEngine) evaluateRule(ast parse.AST, dataMap map[string]string) *Ev
 
 	return nil
 }
+
+func (re *RuleEngine) countMatches(asts []parse.AST, dataMap map[string]string) int {
+	count := 0
+	for _, ast := range asts {
+		if re.evaluateRule(ast, dataMap).MatchValue {
+			count++
+		}
+	}
+	return count
+}
@@ -2,8 +2,14 @@ package controller
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/ast/testgen"
+	"RuleEngineAST/ast/vm"
+	"RuleEngineAST/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -89,6 +95,84 @@ func TestEvaluateRule(t *testing.T) {
 			},
 			expectedMatch: false,
 		},
+		{
+			desc:       "rule is match for NOT operator",
+			ruleString: "age > 30 AND NOT (department == 'ENGINEERING')",
+			dataMap: map[string]string{
+				"age":        "31",
+				"department": "SALES",
+			},
+			expectedMatch: true,
+		},
+		{
+			desc:       "rule is match for ATLEAST operator",
+			ruleString: "ATLEAST(2, age > 30, department == 'ENGINEERING', salary > 20000)",
+			dataMap: map[string]string{
+				"age":    "31",
+				"salary": "25000",
+			},
+			expectedMatch: true,
+		},
+		{
+			desc:       "rule is not match for MAJORITY operator",
+			ruleString: "MAJORITY(age > 30, department == 'ENGINEERING', salary > 20000)",
+			dataMap: map[string]string{
+				"age":        "31",
+				"department": "SALES",
+			},
+			expectedMatch: false,
+		},
+		{
+			desc:       "rule is not match for XOR operator",
+			ruleString: "XOR(age > 30, department == 'ENGINEERING')",
+			dataMap: map[string]string{
+				"age":        "31",
+				"department": "ENGINEERING",
+			},
+			expectedMatch: false,
+		},
+		{
+			desc:       "rule is match for PRIORITY operator with an overriding rule",
+			ruleString: "PRIORITY(vip == 'true', age > 30)",
+			dataMap: map[string]string{
+				"vip": "true",
+				"age": "20",
+			},
+			expectedMatch: true,
+		},
+		{
+			desc:       "rule is not match for PRIORITY operator when the overriding rule says no",
+			ruleString: "PRIORITY(vip == 'true', age > 30)",
+			dataMap: map[string]string{
+				"vip": "false",
+				"age": "31",
+			},
+			expectedMatch: false,
+		},
+		{
+			desc:       "rule is match for PRIORITY operator when the overriding rule has no opinion",
+			ruleString: "PRIORITY(vip == 'true', age > 30)",
+			dataMap: map[string]string{
+				"age": "31",
+			},
+			expectedMatch: true,
+		},
+		{
+			desc:       "rule is match for literal with a comma in a list",
+			ruleString: "ATLEAST(1, name == 'Smith, John', age > 30)",
+			dataMap: map[string]string{
+				"name": "Smith, John",
+			},
+			expectedMatch: true,
+		},
+		{
+			desc:       "rule is not match for literal with a comma in a list",
+			ruleString: "ATLEAST(1, name == 'Smith, John', age > 30)",
+			dataMap: map[string]string{
+				"name": "Smith",
+			},
+			expectedMatch: false,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -118,6 +202,10 @@ func TestParseTree(t *testing.T) {
 			desc:       "successfully parse tree",
 			ruleString: "age > 30 AND department == 'ENGINEERING'",
 		},
+		{
+			desc:       "successfully parse single comparison",
+			ruleString: "age > 30",
+		},
 		{
 			desc:          "invalid rule string",
 			ruleString:    "age > AND department == 'ENGINEERING'",
@@ -133,30 +221,250 @@ func TestParseTree(t *testing.T) {
 	}
 }
 
-func TestCombineRule(t *testing.T) {
+// TestParseTreeConcurrent shares one rule engine between goroutines; run it with -race to check that parsing does not
+// modify shared state.
+func TestParseTreeConcurrent(t *testing.T) {
+
+	re := NewRuleEngine()
+
+	rules := append([]string{"age > AND department == 'ENGINEERING'", "NOT age", "(age > 30"}, evaluatorRules...)
+	expected := make([]string, len(rules))
+	for idx, rule := range rules {
+		ast, err := re.parseTree(rule)
+		expected[idx] = fmt.Sprint(ast, err)
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				for idx, rule := range rules {
+					ast, err := re.parseTree(rule)
+					assert.Equal(t, expected[idx], fmt.Sprint(ast, err), "rule %q", rule)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// evaluatorRules and evaluatorRecords form the corpus on which every way of evaluating a rule must agree with the
+// tree-walking evaluator.
+var evaluatorRules = []string{
+	"age > 30",
+	"age >= 30.5 AND age <= 40",
+	"age < 18 OR age > 65",
+	"department == 'Marketing'",
+	"department != 'Sales'",
+	"department == 'Human Resources' OR department == 'Sales'",
+	"NOT (department == 'Sales')",
+	"NOT (age > 30 AND salary >= 20000)",
+	"((age > 30 AND department == 'Marketing')) AND (salary > 20000 OR experience > 5)",
+	"age > 'thirty'",
+	"age > inf OR age < -Infinity",
+	"score > 30.0000001",
+	"active AND age > 1",
+	"ATLEAST(2, age > 30, department == 'Marketing', salary > 20000)",
+	"MAJORITY(age > 30, department == 'Sales', experience > 5) AND NOT (salary > 50000)",
+	"XOR(department == 'Sales', age < 18, age > 65)",
+	"EXACTLY(2, age > 30, department == 'Sales') OR ATLEAST(2, age > 1, salary > 1)",
+	"PRIORITY(department == 'Sales' AND salary > 20000, experience > 5, age > 30)",
+	"PRIORITY(score > 30, active, age > 30)",
+}
+
+var evaluatorRecords = []map[string]string{
+	{},
+	{"age": "31", "department": "Marketing", "salary": "51000", "experience": "6"},
+	{"age": "30", "department": "Sales", "salary": "20000", "experience": "5"},
+	{"age": "17", "department": "Human Resources"},
+	{"age": "66", "department": "sales"},
+	{"age": "30.5", "salary": "1e5"},
+	{"age": "+31", "department": ""},
+	{"age": ".5e2", "department": "Marketing", "salary": "20_001", "experience": "5."},
+	{"age": "thirty", "salary": "NaN"},
+	{"age": " 31", "salary": "Inf", "experience": "-infinity"},
+	{"age": "1e39", "salary": "3.4028235e38"},
+	{"age": "-Inf", "score": "30.0000001"},
+	{"age": "1_0", "score": "30.000002"},
+	{"age": "1e-50", "score": "30"},
+	{"age": "40", "department": "Marketing", "salary": "19999.99", "experience": "6"},
+}
+
+func TestCompiledRule(t *testing.T) {
+
+	re := NewRuleEngine()
+
+	for _, rule := range evaluatorRules {
+		ast, err := re.parseTree(rule)
+		assert.Nil(t, err)
+
+		compiled, err := compile.Compile(ast)
+		assert.Nil(t, err)
+
+		for _, record := range evaluatorRecords {
+			assert.Equal(t, re.evaluateRule(ast, record).MatchValue, compiled(record), "rule %q, record %v", rule, record)
+		}
+	}
+}
+
+func TestBytecodeRule(t *testing.T) {
 
 	re := NewRuleEngine()
 
+	for _, rule := range evaluatorRules {
+		ast, err := re.parseTree(rule)
+		assert.Nil(t, err)
+
+		bytecode, err := re.compileBytecode(ast)
+		assert.Nil(t, err)
+		program, err := vm.Load(bytecode)
+		assert.Nil(t, err)
+
+		for _, record := range evaluatorRecords {
+			assert.Equal(t, re.evaluateRule(ast, record).MatchValue, program.Run(record), "rule %q, record %v", rule, record)
+		}
+	}
+}
+
+func TestLoadProgram(t *testing.T) {
+
+	re := NewRuleEngine()
+	rule := "age > 30 AND ATLEAST(1, department == 'Sales', salary > 50000)"
+	ast, err := re.parseTree(rule)
+	assert.Nil(t, err)
+	bytecode, err := re.compileBytecode(ast)
+	assert.Nil(t, err)
+
 	testCases := []struct {
-		desc              string
-		firstRule         string
-		SecondRule        string
-		Strategy          string
-		ExpectedMergeRule string
+		desc     string
+		bytecode []byte
+		valid    bool
 	}{
-		{
-			desc:              "successfully merge rules",
-			firstRule:         "age > 30",
-			SecondRule:        "department == 'ENGINEERING'",
-			Strategy:          "AND",
-			ExpectedMergeRule: "(age > 30) AND (department == 'ENGINEERING')",
-		},
+		{desc: "stored bytecode", bytecode: bytecode, valid: true},
+		{desc: "no bytecode", bytecode: nil, valid: true},
+		{desc: "older format", bytecode: []byte("RVM\x01\x00\x00\x01\x01"), valid: true},
+		{desc: "corrupt bytecode", bytecode: []byte("RVM\x02\x00"), valid: false},
 	}
 
 	for _, tt := range testCases {
 		t.Run(tt.desc, func(t *testing.T) {
-			mergeRule := re.combineRule(tt.firstRule, tt.SecondRule, tt.Strategy)
-			assert.Equal(t, tt.ExpectedMergeRule, mergeRule)
+			program, err := re.loadProgram(models.Rule{Rule: rule, Bytecode: tt.bytecode}, ast)
+			if !tt.valid {
+				assert.True(t, errors.Is(err, vm.ErrBytecode), "unexpected error: %v", err)
+				return
+			}
+			assert.Nil(t, err)
+			for _, record := range evaluatorRecords {
+				assert.Equal(t, re.evaluateRule(ast, record).MatchValue, program.Run(record), "record %v", record)
+			}
 		})
 	}
 }
+
+func TestFindStoredRuleRunsBytecode(t *testing.T) {
+
+	re := NewRuleEngine()
+	// the stored bytecode disagrees with the text, to tell which one is evaluated
+	ast, err := re.parseTree("age > 40")
+	assert.Nil(t, err)
+	bytecode, err := re.compileBytecode(ast)
+	assert.Nil(t, err)
+
+	rule, err := re.findStoredRule(1, func(id uint) (models.Rule, error) {
+		return models.Rule{Id: id, Rule: "age > 30", Bytecode: bytecode}, nil
+	})
+	assert.Nil(t, err)
+	assert.False(t, rule.match(map[string]string{"age": "35"}))
+	assert.True(t, rule.match(map[string]string{"age": "45"}))
+	assert.Equal(t, "age > 30", fmt.Sprint(rule.ast))
+}
+
+func TestGeneratedRecords(t *testing.T) {
+
+	re := NewRuleEngine()
+
+	for _, rule := range evaluatorRules {
+		ast, err := re.parseTree(rule)
+		assert.Nil(t, err)
+
+		result, err := testgen.Generate(ast)
+		assert.Nil(t, err)
+		for _, record := range result.Matching {
+			assert.True(t, re.evaluateRule(ast, record).MatchValue, "rule %q must match %v", rule, record)
+		}
+		for _, record := range result.Failing {
+			assert.False(t, re.evaluateRule(ast, record).MatchValue, "rule %q must not match %v", rule, record)
+		}
+	}
+}
+
+var benchmarkRule = "((age > 30 AND department == 'Marketing')) AND (salary > 20000 OR experience > 5)"
+
+var benchmarkData = map[string]string{
+	"age":        "31",
+	"department": "Marketing",
+	"salary":     "51000",
+	"experience": "6",
+}
+
+func BenchmarkEvaluateRule(b *testing.B) {
+	re := NewRuleEngine()
+	ast, err := re.parseTree(benchmarkRule)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		re.evaluateRule(ast, benchmarkData)
+	}
+}
+
+func BenchmarkCompiledRule(b *testing.B) {
+	re := NewRuleEngine()
+	ast, err := re.parseTree(benchmarkRule)
+	if err != nil {
+		b.Fatal(err)
+	}
+	compiled, err := compile.Compile(ast)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compiled(benchmarkData)
+	}
+}
+
+func BenchmarkBytecodeRule(b *testing.B) {
+	re := NewRuleEngine()
+	ast, err := re.parseTree(benchmarkRule)
+	if err != nil {
+		b.Fatal(err)
+	}
+	program, err := vm.Compile(ast)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		program.Run(benchmarkData)
+	}
+}
+
+func BenchmarkParseAndEvaluateRule(b *testing.B) {
+	re := NewRuleEngine()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ast, _ := re.parseTree(benchmarkRule)
+		re.evaluateRule(ast, benchmarkData)
+	}
+}
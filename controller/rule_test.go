@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"RuleEngineAST/dao"
+	"RuleEngineAST/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupDatabase connects the dao package to an empty database for the duration of the test, and gives the handlers
+// a fresh rule engine so that no rule is served from the cache of an earlier test.
+func setupDatabase(t *testing.T) {
+	dao.ConnectDatabase(t.TempDir() + "/")
+	assert.NotNil(t, dao.DB)
+	ruleEngine = NewRuleEngine()
+}
+
+func createRule(t *testing.T, text string, enabled bool) models.Rule {
+	rule, err := ruleManager.CreateRule(models.Rule{Rule: text, Enabled: &enabled})
+	assert.Nil(t, err)
+	return rule
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDisabledRulesAreNotEvaluated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+
+	enabled := createRule(t, "age > 30", true)
+	disabled := createRule(t, "department == 'Sales'", false)
+	assert.Equal(t, uint(1), enabled.Id)
+	assert.Equal(t, uint(2), disabled.Id)
+
+	router := gin.New()
+	router.POST("/rules/:id/evaluate", EvaluateStoredRule)
+	router.POST("/rules/:id/tests/run", RunRuleTests)
+	router.GET("/rules/:id/export", ExportRule)
+	router.POST("/rules/merge", MergeRules)
+	router.POST("/rules/coverage", RuleCoverage)
+	router.POST("/rules/mutate", MutateRule)
+	router.POST("/rules/testdata", GenerateTestData)
+	router.POST("/rules/ranges", RuleRanges)
+	router.POST("/rules/compare", CompareRules)
+	router.POST("/rules/diff", DiffRules)
+
+	testCases := []struct {
+		desc   string
+		method string
+		path   string
+		body   string // {id} in path and body is replaced with the id of the rule
+		// evaluates is set for endpoints which evaluate the rule on records from the request; the others accept
+		// disabled rules
+		evaluates bool
+	}{
+		{desc: "evaluate", method: http.MethodPost, path: "/rules/{id}/evaluate", body: `{"data": {"age": "31"}}`, evaluates: true},
+		{desc: "run tests", method: http.MethodPost, path: "/rules/{id}/tests/run"},
+		{desc: "export", method: http.MethodGet, path: "/rules/{id}/export?format=mongo"},
+		{desc: "merge", method: http.MethodPost, path: "/rules/merge", body: `{"rule_ids": [{id}], "rules": ["a > 1"], "merge_strategy": "AND"}`},
+		{desc: "coverage", method: http.MethodPost, path: "/rules/coverage", body: `{"rule_id": {id}, "records": [{"age": "31"}]}`, evaluates: true},
+		{desc: "mutate", method: http.MethodPost, path: "/rules/mutate", body: `{"rule_id": {id}, "tests": [{"data": {"age": "31"}, "expected": true}]}`, evaluates: true},
+		{desc: "testdata", method: http.MethodPost, path: "/rules/testdata", body: `{"rule_id": {id}}`},
+		{desc: "ranges", method: http.MethodPost, path: "/rules/ranges", body: `{"rule_id": {id}}`},
+		{desc: "compare", method: http.MethodPost, path: "/rules/compare", body: `{"first_rule_id": {id}, "second_rule": "a > 1"}`},
+		{desc: "diff", method: http.MethodPost, path: "/rules/diff", body: `{"first_rule": "a > 1", "second_rule_id": {id}}`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			format := func(s string, id uint) string {
+				return strings.ReplaceAll(s, "{id}", strconv.FormatUint(uint64(id), 10))
+			}
+			w := serve(router, tt.method, format(tt.path, enabled.Id), format(tt.body, enabled.Id))
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			w = serve(router, tt.method, format(tt.path, disabled.Id), format(tt.body, disabled.Id))
+			if !tt.evaluates {
+				assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				return
+			}
+			assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+			assert.Equal(t, `"rule 2 is disabled"`, w.Body.String())
+		})
+	}
+}
+
+func TestCreateRuleWithManyChildren(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+
+	router := gin.New()
+	router.POST("/rules", CreateRule)
+	router.POST("/rules/:id/evaluate", EvaluateStoredRule)
+
+	// counting rules with more children than the bytecode stack is deep must still be accepted
+	children := make([]string, 300)
+	for idx := range children {
+		children[idx] = fmt.Sprintf("a%d == 'x'", idx)
+	}
+	body, err := json.Marshal(map[string]string{"rule": fmt.Sprintf("ATLEAST(2, %s)", strings.Join(children, ", "))})
+	assert.Nil(t, err)
+
+	w := serve(router, http.MethodPost, "/rules", string(body))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serve(router, http.MethodPost, "/rules/1/evaluate", `{"data": {"a1": "x", "a299": "x"}}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"rule_match":true`)
+}
+
+func TestStrictEvaluationCanonicalizesLiterals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+
+	// a rule stored before the attributes were declared
+	createRule(t, "(salary == 20000.50) AND (age != 031)", true)
+	for _, attribute := range []models.Attribute{{Name: "salary", Type: "decimal"}, {Name: "age", Type: "int"}} {
+		_, err := attributeManager.CreateAttribute(attribute)
+		assert.Nil(t, err)
+	}
+
+	router := gin.New()
+	router.POST("/rules", CreateRule)
+	router.POST("/rules/evaluate", EvaluateRule)
+	router.POST("/rules/:id/evaluate", EvaluateStoredRule)
+
+	// the rule is stored as written; only strict evaluation compares canonical literals
+	w := serve(router, http.MethodPost, "/rules", `{"rule": "(salary == 20000.50) AND (age != 031)"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"rule":"(salary == 20000.50) AND (age != 031)"`)
+
+	data := `{"salary": "20000.50", "age": 30}`
+	testCases := []struct {
+		desc string
+		path string
+		body string
+	}{
+		{desc: "rule text", path: "/rules/evaluate", body: `{"rule": "salary == 20000.50 AND age != 031", "data": ` + data + `, "strict": true}`},
+		{desc: "rule stored before the catalog", path: "/rules/1/evaluate", body: `{"data": ` + data + `, "strict": true}`},
+		{desc: "rule stored after the catalog", path: "/rules/2/evaluate", body: `{"data": ` + data + `, "strict": true}`},
+		{desc: "not strict", path: "/rules/2/evaluate", body: `{"data": {"salary": "20000.50", "age": "30"}}`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := serve(router, http.MethodPost, tt.path, tt.body)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), `"rule_match":true`)
+		})
+	}
+}
+
+func TestUpdateRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+	createRule(t, "age > 30", true)
+	createRule(t, "age > 40", true)
+
+	router := gin.New()
+	router.GET("/rules/:id", FindRule)
+	router.PUT("/rules/:id", UpdateRule)
+
+	w := serve(router, http.MethodPut, "/rules/1", `{"rule": "age > 31", "description": "adults", "priority": 2}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"version":2,"description":"adults"`)
+
+	// fields which are not provided are reset, zero values included
+	w = serve(router, http.MethodPut, "/rules/1", `{"rule": "age > 31"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	stored, err := dao.FindRuleById(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), stored.Version)
+	assert.Equal(t, "", stored.Description)
+	assert.Equal(t, 0, stored.Priority)
+
+	// the handler still holds version 2 in its cache when another update has changed the text
+	w = serve(router, http.MethodGet, "/rules/1", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, dao.DB.Model(&models.Rule{}).Where("id = ?", 1).Updates(map[string]any{"rule": "age > 32", "version": 3}).Error)
+	w = serve(router, http.MethodPut, "/rules/1", `{"rule": "age > 33"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	stored, err = dao.FindRuleById(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "age > 32", stored.Rule)
+
+	// a rule deleted since it was read is not stored again
+	w = serve(router, http.MethodGet, "/rules/2", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, dao.DeleteRule(2))
+	w = serve(router, http.MethodPut, "/rules/2", `{"rule": "age > 41"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	_, err = dao.FindRuleById(2)
+	assert.NotNil(t, err)
+}
+
+func TestMergeRulesByPriority(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+	createRule(t, "vip == 'true'", true)
+	createRule(t, "age > 30", true)
+
+	router := gin.New()
+	router.POST("/rules/merge", MergeRules)
+
+	testCases := []struct {
+		desc             string
+		body             string
+		expectedCode     int
+		expectedContains string
+	}{
+		{
+			desc:             "rule texts",
+			body:             `{"rules": ["age > 30", "vip == 'true'"], "merge_strategy": "PRIORITY"}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"merged_rule":"PRIORITY(age \u003e 30, vip == 'true')"`,
+		},
+		{
+			desc:             "rule ids",
+			body:             `{"rule_ids": [2, 1], "merge_strategy": "PRIORITY"}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"merged_rule":"PRIORITY(age \u003e 30, vip == 'true')"`,
+		},
+		{
+			desc:         "rule texts and ids",
+			body:         `{"rules": ["dept == 'A'"], "rule_ids": [1], "merge_strategy": "PRIORITY"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "rule texts and ids with another strategy",
+			body:         `{"rules": ["dept == 'A'"], "rule_ids": [1], "merge_strategy": "OR"}`,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := serve(router, http.MethodPost, "/rules/merge", tt.body)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.expectedContains)
+		})
+	}
+}
+
+func TestRuleNameTakenByConcurrentRequest(t *testing.T) {
+	setupDatabase(t)
+
+	// the handlers check names first, so storing a taken name only happens when another request stores it in between
+	name := "adults"
+	_, err := ruleManager.CreateRule(models.Rule{Rule: "age > 18", Name: &name})
+	assert.Nil(t, err)
+	_, err = ruleManager.CreateRule(models.Rule{Rule: "age > 21", Name: &name})
+	assert.True(t, errors.Is(err, dao.ErrRuleNameTaken), "unexpected error: %v", err)
+
+	other := createRule(t, "age > 21", true)
+	other.Name = &name
+	_, err = ruleManager.UpdateRule(other, other.Version)
+	assert.True(t, errors.Is(err, dao.ErrRuleNameTaken), "unexpected error: %v", err)
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"RuleEngineAST/ast/compile"
+	"RuleEngineAST/models"
+	"RuleEngineAST/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var ruleTestManager service.RuleTestInterface = &service.RuleTestManagerV1{}
+
+type ruleTestRequest struct {
+	Name     string            `json:"name"`
+	Data     map[string]string `json:"data"`
+	Expected bool              `json:"expected"`
+}
+
+// testResult is the outcome of one test of a rule.
+type testResult struct {
+	models.RuleTest
+	Actual bool `json:"actual"`
+	Passed bool `json:"passed"`
+}
+
+// testRun is the outcome of all the tests of a rule.
+type testRun struct {
+	Passed  bool         `json:"passed"`
+	Total   int          `json:"total"`
+	Failed  int          `json:"failed"`
+	Results []testResult `json:"results"`
+}
+
+// runRuleTests evaluates each test with the provided rule.
+func runRuleTests(match compile.Rule, tests []models.RuleTest) *testRun {
+	run := &testRun{Passed: true, Total: len(tests), Results: []testResult{}}
+	for _, test := range tests {
+		actual := match(test.Data)
+		result := testResult{RuleTest: test, Actual: actual, Passed: actual == test.Expected}
+		if !result.Passed {
+			run.Passed = false
+			run.Failed++
+		}
+		run.Results = append(run.Results, result)
+	}
+	return run
+}
+
+// findRuleTest loads the test named by the testId path parameter, which must belong to the provided rule. If the test
+// cannot be loaded the error response is written and false is returned.
+func findRuleTest(c *gin.Context, rule *storedRule) (models.RuleTest, bool) {
+	id, err := strconv.ParseUint(c.Param("testId"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid test id '%s'", c.Param("testId")))
+		return models.RuleTest{}, false
+	}
+	test, err := ruleTestManager.FindRuleTestById(rule.rule.Id, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, "test not found")
+		return test, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return test, false
+	}
+	return test, true
+}
+
+// bindRuleTest reads a test from the request body into test. If the test is not valid the error response is written
+// and false is returned.
+func bindRuleTest(c *gin.Context, test *models.RuleTest) bool {
+	req := &ruleTestRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return false
+	}
+	if req.Data == nil {
+		c.JSON(http.StatusBadRequest, "data must be provided")
+		return false
+	}
+	test.Name = req.Name
+	test.Data = req.Data
+	test.Expected = req.Expected
+	return true
+}
+
+func FindRuleTests(c *gin.Context) {
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tests": ruleTestManager.FindRuleTests(rule.rule.Id)})
+}
+
+func FindRuleTest(c *gin.Context) {
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	test, ok := findRuleTest(c, rule)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, test)
+}
+
+func CreateRuleTest(c *gin.Context) {
+
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	test := models.RuleTest{RuleId: rule.rule.Id}
+	if !bindRuleTest(c, &test) {
+		return
+	}
+
+	test, err := ruleTestManager.CreateRuleTest(test)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
+func UpdateRuleTest(c *gin.Context) {
+
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	test, ok := findRuleTest(c, rule)
+	if !ok {
+		return
+	}
+	if !bindRuleTest(c, &test) {
+		return
+	}
+
+	test, err := ruleTestManager.UpdateRuleTest(test)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
+func DeleteRuleTest(c *gin.Context) {
+
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	test, ok := findRuleTest(c, rule)
+	if !ok {
+		return
+	}
+
+	if err := ruleTestManager.DeleteRuleTest(test.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
+// RunRuleTests runs the tests of a stored rule against its current text. Disabled rules can be tested too, so that
+// they can be fixed before they are enabled again.
+func RunRuleTests(c *gin.Context) {
+	rule, ok := findRule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, runRuleTests(rule.match, ruleTestManager.FindRuleTests(rule.rule.Id)))
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"RuleEngineAST/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRuleTests(t *testing.T) {
+
+	tests := []models.RuleTest{
+		{Id: 1, Data: map[string]string{"age": "31", "department": "Sales"}, Expected: true},
+		{Id: 2, Data: map[string]string{"age": "30", "department": "Sales"}, Expected: false},
+		{Id: 3, Data: map[string]string{"department": "Sales"}, Expected: false},
+	}
+
+	testCases := []struct {
+		desc           string
+		rule           string
+		expectedFailed []uint
+	}{
+		{
+			desc:           "all pass",
+			rule:           "age > 30 AND department == 'Sales'",
+			expectedFailed: []uint{},
+		},
+		{
+			desc:           "boundary moved",
+			rule:           "age >= 30 AND department == 'Sales'",
+			expectedFailed: []uint{2},
+		},
+		{
+			desc:           "comparison dropped",
+			rule:           "department == 'Sales'",
+			expectedFailed: []uint{2, 3},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			rule, err := NewRuleEngine().prepare(tt.rule)
+			assert.Nil(t, err)
+
+			run := runRuleTests(rule.match, tests)
+			failed := []uint{}
+			for _, result := range run.Results {
+				if !result.Passed {
+					failed = append(failed, result.Id)
+				}
+			}
+			assert.Equal(t, tt.expectedFailed, failed)
+			assert.Equal(t, len(tests), run.Total)
+			assert.Equal(t, len(tt.expectedFailed), run.Failed)
+			assert.Equal(t, len(tt.expectedFailed) == 0, run.Passed)
+		})
+	}
+}
+
+func TestRuleTestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupDatabase(t)
+	createRule(t, "age > 30", true)
+	createRule(t, "age > 40", true)
+
+	router := gin.New()
+	router.POST("/rules/:id/tests", CreateRuleTest)
+	router.GET("/rules/:id/tests/:testId", FindRuleTest)
+	router.PUT("/rules/:id/tests/:testId", UpdateRuleTest)
+	router.DELETE("/rules/:id/tests/:testId", DeleteRuleTest)
+
+	// the steps run in order, each one seeing the tests left by the previous ones
+	testCases := []struct {
+		desc             string
+		method           string
+		path             string
+		body             string
+		expectedCode     int
+		expectedContains string
+	}{
+		{
+			desc:             "create",
+			method:           http.MethodPost,
+			path:             "/rules/1/tests",
+			body:             `{"name": "adult", "data": {"age": "31"}, "expected": true}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"id":1,"ruleId":1,"name":"adult"`,
+		},
+		{
+			desc:         "create without data",
+			method:       http.MethodPost,
+			path:         "/rules/1/tests",
+			body:         `{"name": "empty"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:             "get",
+			method:           http.MethodGet,
+			path:             "/rules/1/tests/1",
+			expectedCode:     http.StatusOK,
+			expectedContains: `"name":"adult"`,
+		},
+		{
+			desc:             "get with an invalid id",
+			method:           http.MethodGet,
+			path:             "/rules/1/tests/abc",
+			expectedCode:     http.StatusBadRequest,
+			expectedContains: `"invalid test id 'abc'"`,
+		},
+		{
+			desc:         "get with id zero",
+			method:       http.MethodGet,
+			path:         "/rules/1/tests/0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "get with a negative id",
+			method:       http.MethodGet,
+			path:         "/rules/1/tests/-1",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:             "get unknown",
+			method:           http.MethodGet,
+			path:             "/rules/1/tests/99",
+			expectedCode:     http.StatusNotFound,
+			expectedContains: `"test not found"`,
+		},
+		{
+			desc:         "get through another rule",
+			method:       http.MethodGet,
+			path:         "/rules/2/tests/1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:             "update",
+			method:           http.MethodPut,
+			path:             "/rules/1/tests/1",
+			body:             `{"name": "young", "data": {"age": "20"}, "expected": false}`,
+			expectedCode:     http.StatusOK,
+			expectedContains: `"name":"young"`,
+		},
+		{
+			desc:             "update with an invalid id",
+			method:           http.MethodPut,
+			path:             "/rules/1/tests/abc",
+			body:             `{"data": {"age": "20"}}`,
+			expectedCode:     http.StatusBadRequest,
+			expectedContains: `"invalid test id 'abc'"`,
+		},
+		{
+			desc:             "delete with an invalid id",
+			method:           http.MethodDelete,
+			path:             "/rules/1/tests/abc",
+			expectedCode:     http.StatusBadRequest,
+			expectedContains: `"invalid test id 'abc'"`,
+		},
+		{
+			desc:         "delete",
+			method:       http.MethodDelete,
+			path:         "/rules/1/tests/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "get deleted",
+			method:       http.MethodGet,
+			path:         "/rules/1/tests/1",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.expectedContains)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/simplify"
+	"github.com/gin-gonic/gin"
+)
+
+func SimplifyRule(c *gin.Context) {
+
+	type request struct {
+		Rule string `json:"rule"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	rule, err := ruleEngine.prepare(req.Rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		return
+	}
+
+	simplified, err := simplify.Simplify(rule.ast)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot simplify rule. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule":       req.Rule,
+		"simplified": fmt.Sprint(simplified),
+	})
+}
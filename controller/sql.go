@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/sqlgen"
+	"github.com/gin-gonic/gin"
+)
+
+func CompileSQL(c *gin.Context) {
+
+	type request struct {
+		Rule    string            `json:"rule"`
+		Dialect string            `json:"dialect"`
+		Columns map[string]string `json:"columns"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Dialect == "" {
+		req.Dialect = "sqlite"
+	}
+	dialector, err := sqlgen.Dialector(req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ast, err := ruleEngine.parseTree(req.Rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid rule. err : %s", err.Error()))
+		return
+	}
+
+	where, err := sqlgen.Compile(ast, dialector, req.Columns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("cannot compile rule to sql. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"where": where.SQL,
+		"args":  where.Args,
+	})
+}
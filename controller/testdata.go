@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RuleEngineAST/ast/testgen"
+	"github.com/gin-gonic/gin"
+)
+
+func GenerateTestData(c *gin.Context) {
+
+	type request struct {
+		Rule   string `json:"rule"`
+		RuleId uint   `json:"rule_id"`
+		Limit  int    `json:"limit"`
+	}
+
+	req := &request{}
+	err := c.BindJSON(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if req.Limit < 0 {
+		c.JSON(http.StatusBadRequest, "limit must not be negative")
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = testgen.DefaultLimit
+	}
+
+	rule, ok := resolveRule(c, req.RuleId, req.Rule)
+	if !ok {
+		return
+	}
+
+	result, err := testgen.GenerateLimit(rule.ast, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, fmt.Sprintf("cannot generate test data. err : %s", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,33 @@
+package dao
+
+import "RuleEngineAST/models"
+
+func CreateAttribute(a *models.Attribute) error {
+	return DB.Create(a).Error
+}
+
+func FindAttributes() []models.Attribute {
+	var attributes []models.Attribute
+	DB.Order("name").Find(&attributes)
+	return attributes
+}
+
+func FindAttributeById(id uint) (models.Attribute, error) {
+	var attribute models.Attribute
+	err := DB.First(&attribute, id).Error
+	return attribute, err
+}
+
+func FindAttributeByName(name string) (models.Attribute, error) {
+	var attribute models.Attribute
+	err := DB.Where("name = ?", name).First(&attribute).Error
+	return attribute, err
+}
+
+func UpdateAttribute(a *models.Attribute) error {
+	return DB.Save(a).Error
+}
+
+func DeleteAttribute(id uint) error {
+	return DB.Delete(&models.Attribute{}, id).Error
+}
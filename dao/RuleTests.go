@@ -0,0 +1,27 @@
+package dao
+
+import "RuleEngineAST/models"
+
+func CreateRuleTest(t *models.RuleTest) error {
+	return DB.Create(t).Error
+}
+
+func FindRuleTests(ruleId uint) []models.RuleTest {
+	var tests []models.RuleTest
+	DB.Where("rule_id = ?", ruleId).Order("id").Find(&tests)
+	return tests
+}
+
+func FindRuleTestById(ruleId, id uint) (models.RuleTest, error) {
+	var test models.RuleTest
+	err := DB.Where("rule_id = ?", ruleId).First(&test, id).Error
+	return test, err
+}
+
+func UpdateRuleTest(t *models.RuleTest) error {
+	return DB.Save(t).Error
+}
+
+func DeleteRuleTest(id uint) error {
+	return DB.Delete(&models.RuleTest{}, id).Error
+}
@@ -1,13 +1,133 @@
 package dao
 
-import "RuleEngineAST/models"
+import (
+	"errors"
+	"strings"
 
-func CreateRule(r models.Rule) {
-	DB.Create(&r)
+	"RuleEngineAST/models"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// ErrRuleChanged is returned when a rule is updated after another update has changed its text.
+var ErrRuleChanged = errors.New("rule was changed by another update")
+
+// ErrRuleNameTaken is returned when a rule is stored with the name of another rule.
+var ErrRuleNameTaken = errors.New("rule name already taken")
+
+// RuleCursor marks the last rule of a page: the next page starts after it in the order of the query.
+type RuleCursor struct {
+	Id   uint   `json:"id"`
+	Rule string `json:"rule,omitempty"` // Rule is only set when sorting by rule text.
+}
+
+// RuleQuery selects a page of rules. Sort is either "id" or "rule", ties in rule text being broken by id. Search
+// keeps the rules whose text contains it, ignoring case, and the other filters keep the rules with the provided name,
+// owner, tag, priority and enabled flag. At most Limit rules are returned, starting after After when it is set.
+type RuleQuery struct {
+	Search   string
+	Name     string
+	Owner    string
+	Tag      string
+	Priority *int
+	Enabled  *bool
+	Sort     string
+	Desc     bool
+	Limit    int
+	After    *RuleCursor
+}
+
+func CreateRule(r *models.Rule) error {
+	return checkNameTaken(DB.Create(r).Error)
+}
+
+// checkNameTaken reports a violation of the unique index on rule names as ErrRuleNameTaken. Handlers check names
+// before storing a rule, but another request may store the same name in between.
+func checkNameTaken(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), "rules.name") {
+		return ErrRuleNameTaken
+	}
+	return err
+}
+
+func FindRules(q RuleQuery) ([]models.Rule, error) {
+	db := DB.Model(&models.Rule{})
+	if q.Search != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Search)
+		db = db.Where(`rule LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+	}
+	if q.Name != "" {
+		db = db.Where("name = ?", q.Name)
+	}
+	if q.Owner != "" {
+		db = db.Where("owner = ?", q.Owner)
+	}
+	if q.Tag != "" {
+		db = db.Where("EXISTS (SELECT 1 FROM json_each(rules.tags) WHERE json_each.value = ?)", q.Tag)
+	}
+	if q.Priority != nil {
+		db = db.Where("priority = ?", *q.Priority)
+	}
+	if q.Enabled != nil {
+		db = db.Where("enabled = ?", *q.Enabled)
+	}
+
+	cmp, dir := ">", "asc"
+	if q.Desc {
+		cmp, dir = "<", "desc"
+	}
+	if q.Sort == "rule" {
+		if q.After != nil {
+			db = db.Where("rule "+cmp+" ? OR (rule = ? AND id "+cmp+" ?)", q.After.Rule, q.After.Rule, q.After.Id)
+		}
+		db = db.Order("rule " + dir)
+	} else if q.After != nil {
+		db = db.Where("id "+cmp+" ?", q.After.Id)
+	}
+	db = db.Order("id " + dir)
+
+	var rules []models.Rule
+	err := db.Limit(q.Limit).Find(&rules).Error
+	return rules, err
+}
+
+func FindRuleById(id uint) (models.Rule, error) {
+	var rule models.Rule
+	err := DB.First(&rule, id).Error
+	return rule, err
+}
+
+func FindRuleByName(name string) (models.Rule, error) {
+	var rule models.Rule
+	err := DB.Where("name = ?", name).First(&rule).Error
+	return rule, err
+}
+
+// UpdateRule stores every field of a rule which was read at the provided version. It fails with ErrRuleChanged if the
+// stored rule has moved past that version since, and with gorm.ErrRecordNotFound if it has been deleted.
+func UpdateRule(r *models.Rule, version uint) error {
+	result := DB.Model(&models.Rule{}).Where("id = ? AND version = ?", r.Id, version).
+		Select("*").Omit("id", "created_at").Updates(r)
+	if result.Error != nil {
+		return checkNameTaken(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := FindRuleById(r.Id); err != nil {
+			return err
+		}
+		return ErrRuleChanged
+	}
+	return nil
 }
 
-func FindRule() []models.Rule {
-	var rule []models.Rule
-	DB.Find(&rule)
-	return rule
+// DeleteRule deletes a rule together with its tests, in one transaction so that neither is deleted without the other.
+func DeleteRule(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", id).Delete(&models.RuleTest{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Rule{}, id).Error
+	})
 }
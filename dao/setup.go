@@ -17,7 +17,14 @@ func ConnectDatabase(dbName string) {
 		panic("Failed to connect to database!")
 	}
 
-	err = database.AutoMigrate(&models.Rule{})
+	err = database.AutoMigrate(&models.Rule{}, &models.Attribute{}, &models.RuleTest{})
+	if err != nil {
+		return
+	}
+
+	// rule names are unique, but sqlite cannot add a unique column to the rules stored before names existed, so the
+	// index is created separately
+	err = database.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_rules_name ON rules(name)").Error
 	if err != nil {
 		return
 	}
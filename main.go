@@ -20,17 +20,83 @@ func main() {
 		})
 	})
 
-	//get all rules
+	//list rules a page at a time, optionally searching their text
 	router.GET("/rules", controller.FindRules)
 
 	//create a new rule
 	router.POST("/rules", controller.CreateRule)
 
+	//get, update or delete a stored rule; updates are rejected if the rule's tests fail
+	router.GET("/rules/:id", controller.FindRule)
+	router.PUT("/rules/:id", controller.UpdateRule)
+	router.PATCH("/rules/:id", controller.PatchRule)
+	router.DELETE("/rules/:id", controller.DeleteRule)
+
+	//manage and run the tests stored with a rule
+	router.GET("/rules/:id/tests", controller.FindRuleTests)
+	router.POST("/rules/:id/tests", controller.CreateRuleTest)
+	router.GET("/rules/:id/tests/:testId", controller.FindRuleTest)
+	router.PUT("/rules/:id/tests/:testId", controller.UpdateRuleTest)
+	router.DELETE("/rules/:id/tests/:testId", controller.DeleteRuleTest)
+	router.POST("/rules/:id/tests/run", controller.RunRuleTests)
+
+	//show the bytecode compiled for a stored rule
+	router.GET("/rules/:id/bytecode", controller.DisassembleRule)
+
+	//export a stored rule as a mongo filter, an elasticsearch query, jsonlogic or a javascript or python function
+	router.GET("/rules/:id/export", controller.ExportRule)
+
 	//evaluate a rule with data
 	router.POST("/rules/evaluate", controller.EvaluateRule)
 
+	//evaluate a stored rule with data
+	router.POST("/rules/:id/evaluate", controller.EvaluateStoredRule)
+
+	//simplify a rule
+	router.POST("/rules/simplify", controller.SimplifyRule)
+
+	//convert a rule to conjunctive or disjunctive normal form
+	router.POST("/rules/normalize", controller.NormalizeRule)
+
+	//values of each attribute for which a rule matches
+	router.POST("/rules/ranges", controller.RuleRanges)
+
+	//sample records which a rule matches and records it does not match
+	router.POST("/rules/testdata", controller.GenerateTestData)
+
+	//which parts of a rule a batch of records exercises
+	router.POST("/rules/coverage", controller.RuleCoverage)
+
+	//how many faulty copies of a rule its test cases catch
+	router.POST("/rules/mutate", controller.MutateRule)
+
+	//compare two rules, given by text or id
+	router.POST("/rules/compare", controller.CompareRules)
+
+	//diff two rules, given by text or id
+	router.POST("/rules/diff", controller.DiffRules)
+
 	//merge rules
 	router.POST("/rules/merge", controller.MergeRules)
 
+	//convert a JSONLogic document into a rule
+	router.POST("/rules/jsonlogic/import", controller.ImportJSONLogic)
+
+	//convert a rule into a JSONLogic document
+	router.POST("/rules/jsonlogic/export", controller.ExportJSONLogic)
+
+	//compile a rule into a parameterized sql where clause
+	router.POST("/rules/sql", controller.CompileSQL)
+
+	//manage the catalog of attributes rules are checked against
+	router.GET("/attributes", controller.FindAttributes)
+	router.POST("/attributes", controller.CreateAttribute)
+	router.GET("/attributes/:id", controller.FindAttribute)
+	router.PUT("/attributes/:id", controller.UpdateAttribute)
+	router.DELETE("/attributes/:id", controller.DeleteAttribute)
+
+	//show rule cache statistics
+	router.GET("/admin/cache", controller.ShowCacheStats)
+
 	router.Run() // listen and serve on 0.0.0.0:8080 (for windows "localhost:8080")
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type Attribute struct {
+	Id          uint      `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name" gorm:"uniqueIndex"`
+	Type        string    `json:"type"`
+	Values      []string  `json:"values,omitempty" gorm:"serializer:json"`
+	Default     *string   `json:"default,omitempty"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -3,7 +3,21 @@ package models
 import "time"
 
 type Rule struct {
-	Id        uint      `json:"id" gorm:"primary_key"`
-	Rule      string    `json:"rule"`
-	CreatedAt time.Time `json:"createdAt"`
+	Id          uint      `json:"id" gorm:"primary_key"`
+	Name        *string   `json:"name,omitempty"` // Name is unique among rules, but may be left unset.
+	Rule        string    `json:"rule"`
+	Version     uint      `json:"version" gorm:"default:1"` // Version counts the texts the rule has had, starting at 1.
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags,omitempty" gorm:"serializer:json"`
+	Owner       string    `json:"owner"`
+	Priority    int       `json:"priority"`
+	Enabled     *bool     `json:"enabled" gorm:"default:true"` // Enabled is never nil once the rule is stored.
+	Bytecode    []byte    `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// IsEnabled reports whether the rule may be evaluated. Rules are enabled unless disabled explicitly.
+func (r Rule) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RuleTest is an example record stored with a rule, together with whether the rule is expected to match it.
+type RuleTest struct {
+	Id        uint              `json:"id" gorm:"primary_key"`
+	RuleId    uint              `json:"ruleId" gorm:"index"`
+	Name      string            `json:"name"`
+	Data      map[string]string `json:"data" gorm:"serializer:json"`
+	Expected  bool              `json:"expected"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
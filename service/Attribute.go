@@ -0,0 +1,48 @@
+package service
+
+import (
+	"time"
+
+	"RuleEngineAST/dao"
+	"RuleEngineAST/models"
+)
+
+type AttributeInterface interface {
+	FindAttributes() []models.Attribute
+	FindAttributeById(id uint) (models.Attribute, error)
+	FindAttributeByName(name string) (models.Attribute, error)
+	CreateAttribute(attribute models.Attribute) (models.Attribute, error)
+	UpdateAttribute(attribute models.Attribute) (models.Attribute, error)
+	DeleteAttribute(id uint) error
+}
+
+type AttributeManagerV1 struct {
+}
+
+func (attributeManager *AttributeManagerV1) FindAttributes() []models.Attribute {
+	return dao.FindAttributes()
+}
+
+func (attributeManager *AttributeManagerV1) FindAttributeById(id uint) (models.Attribute, error) {
+	return dao.FindAttributeById(id)
+}
+
+func (attributeManager *AttributeManagerV1) FindAttributeByName(name string) (models.Attribute, error) {
+	return dao.FindAttributeByName(name)
+}
+
+func (attributeManager *AttributeManagerV1) CreateAttribute(attribute models.Attribute) (models.Attribute, error) {
+	attribute.Id = 0
+	attribute.CreatedAt = time.Now()
+	err := dao.CreateAttribute(&attribute)
+	return attribute, err
+}
+
+func (attributeManager *AttributeManagerV1) UpdateAttribute(attribute models.Attribute) (models.Attribute, error) {
+	err := dao.UpdateAttribute(&attribute)
+	return attribute, err
+}
+
+func (attributeManager *AttributeManagerV1) DeleteAttribute(id uint) error {
+	return dao.DeleteAttribute(id)
+}
@@ -8,24 +8,63 @@ import (
 )
 
 type RuleInterface interface {
-	FindRules() []models.Rule
-	CreateRule(ruleStr string) models.Rule
+	FindRules(query dao.RuleQuery) ([]models.Rule, *dao.RuleCursor, error)
+	FindRuleById(id uint) (models.Rule, error)
+	FindRuleByName(name string) (models.Rule, error)
+	CreateRule(rule models.Rule) (models.Rule, error)
+	UpdateRule(rule models.Rule, version uint) (models.Rule, error)
+	DeleteRule(id uint) error
 }
 
 type RuleManagerV1 struct {
 }
 
-func (ruleManager *RuleManagerV1) FindRules() []models.Rule {
-	return dao.FindRule()
+// FindRules returns a page of rules, and the cursor of the next page or nil if this page is the last.
+func (ruleManager *RuleManagerV1) FindRules(query dao.RuleQuery) ([]models.Rule, *dao.RuleCursor, error) {
+	limit := query.Limit
+	query.Limit++
+	rules, err := dao.FindRules(query)
+	if err != nil || len(rules) <= limit {
+		return rules, nil, err
+	}
+
+	rules = rules[:limit]
+	last := rules[limit-1]
+	next := &dao.RuleCursor{Id: last.Id}
+	if query.Sort == "rule" {
+		next.Rule = last.Rule
+	}
+	return rules, next, nil
 }
 
-func (ruleManager *RuleManagerV1) CreateRule(ruleStr string) models.Rule {
-	rule := models.Rule{
-		Rule:      ruleStr,
-		CreatedAt: time.Now(),
+func (ruleManager *RuleManagerV1) FindRuleById(id uint) (models.Rule, error) {
+	return dao.FindRuleById(id)
+}
+
+func (ruleManager *RuleManagerV1) FindRuleByName(name string) (models.Rule, error) {
+	return dao.FindRuleByName(name)
+}
+
+func (ruleManager *RuleManagerV1) CreateRule(rule models.Rule) (models.Rule, error) {
+	rule.Id = 0
+	rule.Version = 1
+	rule.CreatedAt = time.Now()
+	if rule.Enabled == nil {
+		enabled := true
+		rule.Enabled = &enabled
 	}
 
-	dao.CreateRule(rule)
+	err := dao.CreateRule(&rule)
+
+	return rule, err
+}
+
+// UpdateRule stores a rule which was read at the provided version, failing if another update changed its text since.
+func (ruleManager *RuleManagerV1) UpdateRule(rule models.Rule, version uint) (models.Rule, error) {
+	err := dao.UpdateRule(&rule, version)
+	return rule, err
+}
 
-	return rule
+func (ruleManager *RuleManagerV1) DeleteRule(id uint) error {
+	return dao.DeleteRule(id)
 }
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"time"
+
+	"RuleEngineAST/dao"
+	"RuleEngineAST/models"
+)
+
+type RuleTestInterface interface {
+	FindRuleTests(ruleId uint) []models.RuleTest
+	FindRuleTestById(ruleId, id uint) (models.RuleTest, error)
+	CreateRuleTest(test models.RuleTest) (models.RuleTest, error)
+	UpdateRuleTest(test models.RuleTest) (models.RuleTest, error)
+	DeleteRuleTest(id uint) error
+}
+
+type RuleTestManagerV1 struct {
+}
+
+func (ruleTestManager *RuleTestManagerV1) FindRuleTests(ruleId uint) []models.RuleTest {
+	return dao.FindRuleTests(ruleId)
+}
+
+func (ruleTestManager *RuleTestManagerV1) FindRuleTestById(ruleId, id uint) (models.RuleTest, error) {
+	return dao.FindRuleTestById(ruleId, id)
+}
+
+func (ruleTestManager *RuleTestManagerV1) CreateRuleTest(test models.RuleTest) (models.RuleTest, error) {
+	test.Id = 0
+	test.CreatedAt = time.Now()
+	err := dao.CreateRuleTest(&test)
+	return test, err
+}
+
+func (ruleTestManager *RuleTestManagerV1) UpdateRuleTest(test models.RuleTest) (models.RuleTest, error) {
+	err := dao.UpdateRuleTest(&test)
+	return test, err
+}
+
+func (ruleTestManager *RuleTestManagerV1) DeleteRuleTest(id uint) error {
+	return dao.DeleteRuleTest(id)
+}